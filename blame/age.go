@@ -0,0 +1,99 @@
+package blame
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AgeMode selects the commit that lines are attributed to, which sets their date.
+//   - LastTouched: the last commit that changed the line, as git blame does by default
+//   - Introduced: the commit that first added the line, ignoring changes in whitespace
+//     and following lines moved within the file or copied from other files, so
+//     reformatting or moving code doesn't reset the age of comments
+type AgeMode int
+
+const (
+	LastTouched AgeMode = iota
+	Introduced
+)
+
+// AgeModes lists the accepted names of age modes.
+var AgeModes = []string{"last-touched", "introduced"}
+
+func (m AgeMode) String() string {
+	if m < 0 || int(m) >= len(AgeModes) {
+		return "unknown"
+	}
+	return AgeModes[m]
+}
+
+// ParseAgeMode returns the AgeMode with the provided name.
+func ParseAgeMode(name string) (AgeMode, error) {
+	for i, m := range AgeModes {
+		if strings.EqualFold(name, m) {
+			return AgeMode(i), nil
+		}
+	}
+	return LastTouched, fmt.Errorf("unknown age mode %q, expected one of %s", name, strings.Join(AgeModes, ", "))
+}
+
+// blameArgs returns the git blame options of the age mode.
+func (m AgeMode) blameArgs() []string {
+	if m == Introduced {
+		return []string{"-w", "-M", "-C"}
+	}
+	return nil
+}
+
+// Options lists the git blame options accepted by ParseOptions.
+//   - -w: ignore changes in whitespace
+//   - -M: follow lines moved or copied within the file
+//   - -C: also follow lines moved or copied from other files changed in the same commit
+var Options = []string{"-w", "-M", "-C"}
+
+// Settings configure how files are blamed. They're passed to every call instead of
+// being global, so concurrent searches can blame files differently.
+//   - AgeMode: the commit that lines are attributed to. Following the history of lines
+//     is slower, and is always done by git blame, even if listme was built with an
+//     alternative backend
+//   - Options: git blame options, as returned by ParseOptions. As with the Introduced
+//     age mode, files are always blamed by git when options are set
+//   - LazyFetch: let git blame fetch the objects missing from a partial clone. Fetching
+//     every blob touched by the history of a file is slow. Requires git 2.44 or later
+type Settings struct {
+	AgeMode   AgeMode
+	Options   []string
+	LazyFetch bool
+}
+
+// ParseOptions returns the git blame options, one of Options each, so reformatting or
+// moving code doesn't attribute its comments to whoever did it. The leading dash may be
+// left out, e.g. w for -w. Repeated options are dropped.
+func ParseOptions(opts []string) ([]string, error) {
+	var parsed []string
+	for _, opt := range opts {
+		if !strings.HasPrefix(opt, "-") {
+			opt = "-" + opt
+		}
+		if !slices.Contains(Options, opt) {
+			return nil, fmt.Errorf("unsupported git blame option %q, expected one of %s", opt, strings.Join(Options, ", "))
+		}
+		if !slices.Contains(parsed, opt) {
+			parsed = append(parsed, opt)
+		}
+	}
+	return parsed, nil
+}
+
+// gitArgs returns the git blame options of the age mode and of the options, in the order of Options.
+func (s Settings) gitArgs() []string {
+	set := append(s.AgeMode.blameArgs(), s.Options...)
+	var args []string
+	for _, opt := range Options {
+		if slices.Contains(set, opt) {
+			args = append(args, opt)
+		}
+	}
+	return args
+}
@@ -3,121 +3,208 @@ package blame
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/op/go-logging"
+	"github.com/mattn/go-runewidth"
 )
 
-var log = logging.MustGetLogger("listme")
-
 // Maximum length for the Git author string
 const MaxAuthorLength = 20
 
+// Length of the abbreviated commit hash
+const ShortHashLength = 7
+
 // LineBlame contains Git blame information for a specific file line.
 //   - Time: date and time of commit
-//   - Author: author name
+//   - Author: author name, shortened to MaxAuthorLength
+//   - FullAuthor: author name as committed
+//   - Hash: full commit hash, empty if the line is not committed yet
 type LineBlame struct {
-	Time   time.Time
-	Author string
+	Time       time.Time
+	Author     string
+	FullAuthor string
+	Hash       string
+}
+
+// ShortHash returns the abbreviated commit hash, or an empty string if the line is not committed yet.
+func (b *LineBlame) ShortHash() string {
+	if len(b.Hash) < ShortHashLength {
+		return b.Hash
+	}
+	return b.Hash[:ShortHashLength]
 }
 
+// GitBlame holds the blame of the lines of a file, by line number.
 type GitBlame struct {
-	blames []*LineBlame
+	blames map[int]*LineBlame
 }
 
 // BlameLine returns a LineBlame for the specified line if possible.
-// If the line is out of range, an error is returned.
+// If the line was not blamed, an error is returned.
 func (b *GitBlame) BlameLine(line int) (*LineBlame, error) {
-	line = line - 1
-	if line < 0 || line >= len(b.blames) {
+	blame, ok := b.blames[line]
+	if !ok {
 		err := fmt.Errorf("line %d out of range", line)
-		log.Info(err)
+		slog.Info("git blame lookup failed", "error", err)
 		return nil, err
 	}
-	return b.blames[line], nil
+	return blame, nil
 }
 
-func parseGitBlame(out io.Reader) []*LineBlame {
-	var blames []*LineBlame
-	lr := bufio.NewReader(out)
-	s := bufio.NewScanner(lr)
+// parseGitBlame parses the output of git blame --line-porcelain, returning the blame of
+// each line. maxLine is the maximum length of the lines of the output, longer lines are an error.
+func parseGitBlame(out io.Reader, maxLine int) (map[int]*LineBlame, error) {
+	blames := make(map[int]*LineBlame)
+	s := bufio.NewScanner(out)
+	s.Buffer(nil, maxLine)
 
 	var currentBlame *LineBlame
 	for s.Scan() {
 		buf := s.Text()
-		if strings.HasPrefix(buf, "author ") {
-			if currentBlame != nil {
-				blames = append(blames, currentBlame)
-			}
-			currentBlame = &LineBlame{
-				Author: truncateName(strings.TrimPrefix(buf, "author "), MaxAuthorLength),
-			}
+		if hash, line, ok := parseCommitHeader(buf); ok {
+			currentBlame = &LineBlame{Hash: hash}
+			blames[line] = currentBlame
+		} else if currentBlame == nil {
+			continue
+		} else if strings.HasPrefix(buf, "author ") {
+			currentBlame.FullAuthor = strings.TrimPrefix(buf, "author ")
+			currentBlame.Author = truncateName(currentBlame.FullAuthor, MaxAuthorLength)
 		} else if strings.HasPrefix(buf, "author-time ") {
-			if currentBlame != nil {
-				tsStr := strings.TrimPrefix(buf, "author-time ")
-				ts, err := strconv.ParseInt(tsStr, 10, 64)
-				time := time.Unix(ts, 0)
-				if err == nil {
-					currentBlame.Time = time
-				}
+			ts, err := strconv.ParseInt(strings.TrimPrefix(buf, "author-time "), 10, 64)
+			if err == nil {
+				currentBlame.Time = time.Unix(ts, 0)
 			}
 		}
 	}
+	return blames, s.Err()
+}
 
-	// Append the last entry
-	if currentBlame != nil {
-		blames = append(blames, currentBlame)
+// parseCommitHeader parses the header line that starts each entry of the porcelain
+// output, returning the commit hash and the line number in the final file.
+// Uncommitted lines have an all-zero hash, which is returned as an empty string.
+func parseCommitHeader(line string) (string, int, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return "", 0, false
+	}
+	hash := fields[0]
+	if len(hash) != 40 && len(hash) != 64 {
+		return "", 0, false
 	}
-	return blames
+	for _, c := range hash {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return "", 0, false
+		}
+	}
+	final, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", 0, false
+	}
+	if strings.Trim(hash, "0") == "" {
+		return "", final, true
+	}
+	return hash, final, true
 }
 
-func truncateName(name string, maxLength int) string {
-	totalLen := len(name)
-	words := strings.Fields(name) // Split the name into words
-
-	truncated := []string{}
-	for i := len(words) - 1; i >= 0; i-- {
-		if totalLen > maxLength {
-			if i == 0 {
-				remLen := maxLength - 2*len(truncated)
-				truncated = append(truncated, words[i][0:remLen]) // Truncate if first name
-			} else {
-				truncated = append(truncated, string(words[i][0])) // First letter
-			}
-			totalLen -= len(words[i]) - 2
+// TruncateAuthor shortens an author name to MaxAuthorLength, as in the blame of lines.
+func TruncateAuthor(name string) string {
+	return truncateName(name, MaxAuthorLength)
+}
+
+// truncateName shortens the name to fit in maxWidth terminal columns. Words are
+// abbreviated to their initials from last to first, then the first word is cut.
+// Widths are measured in columns, so wide characters such as CJK count as two.
+func truncateName(name string, maxWidth int) string {
+	words := strings.Fields(name)
+	width := runewidth.StringWidth(strings.Join(words, " "))
+
+	for i := len(words) - 1; i > 0 && width > maxWidth; i-- {
+		initial, _ := utf8.DecodeRuneInString(words[i])
+		width -= runewidth.StringWidth(words[i]) - runewidth.RuneWidth(initial)
+		words[i] = string(initial)
+	}
+
+	if width > maxWidth && len(words) > 0 {
+		// cut the first word, keeping the initials if there's room for them
+		rest := width - runewidth.StringWidth(words[0])
+		if rest < maxWidth {
+			words[0] = runewidth.Truncate(words[0], maxWidth-rest, "")
 		} else {
-			truncated = append(truncated, words[i])
+			return runewidth.Truncate(strings.Join(words, " "), maxWidth, "")
 		}
 	}
+	return strings.Join(words, " ")
+}
 
-	for i, j := 0, len(truncated)-1; i < j; i, j = i+1, j-1 {
-		truncated[i], truncated[j] = truncated[j], truncated[i]
+// maxRanges is the maximum number of line ranges passed to git blame,
+// files with more scattered lines are blamed whole.
+const maxRanges = 64
+
+// lineRanges merges the sorted line numbers into git blame -L arguments.
+func lineRanges(lines []int) []string {
+	var args []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] <= lines[j]+1 {
+			j++
+		}
+		args = append(args, "-L", fmt.Sprintf("%d,%d", lines[i], lines[j]))
+		i = j + 1
 	}
-
-	return strings.Join(truncated, " ")
+	return args
 }
 
+// backend blames files without running git. It's only set when listme is built
+// with an alternative backend (see libgit2.go), and BlameFile falls back to git
+// blame whenever it fails.
+var backend func(path string, lines []int) (*GitBlame, error)
+
 // BlameFile runs git blame for the provided path using the OS interface,
-// parses the output and returns a *GitBlame or error.
-func BlameFile(path string) (*GitBlame, error) {
+// parses the output and returns a *GitBlame or error. Only the provided
+// line numbers, in increasing order, are blamed, or every line if none are.
+// The git process is killed if ctx is done before it finishes.
+func BlameFile(ctx context.Context, path string, lines []int, settings Settings) (*GitBlame, error) {
+	if err := ctx.Err(); err != nil {
+		// the backend can't be stopped once started
+		return nil, err
+	}
 	absolutePath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, err
 	}
-
-	err = os.Chdir(filepath.Dir(absolutePath))
+	info, err := os.Stat(absolutePath)
 	if err != nil {
 		return nil, err
 	}
 
-	cmd := exec.Command("git", "blame", absolutePath, "--line-porcelain")
+	gitArgs := settings.gitArgs()
+	if backend != nil && len(gitArgs) == 0 {
+		gb, err := backend(absolutePath, lines)
+		if err == nil {
+			return gb, nil
+		}
+		slog.Debug("blame backend failed, falling back to git blame", "path", path, "error", err)
+	}
+
+	args := append([]string{"blame", "--line-porcelain"}, gitArgs...)
+	if ranges := lineRanges(lines); len(ranges) <= 2*maxRanges {
+		args = append(args, ranges...)
+	}
+	cmd := exec.CommandContext(ctx, "git", append(args, "--", absolutePath)...)
+	cmd.Dir = filepath.Dir(absolutePath)
+	if !settings.LazyFetch {
+		cmd.Env = append(os.Environ(), "GIT_NO_LAZY_FETCH=1")
+	}
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -131,10 +218,19 @@ func BlameFile(path string) (*GitBlame, error) {
 		return nil, err
 	}
 
-	blames := parseGitBlame(stdout)
+	// the output has a line with the content of each blamed line, besides the headers
+	blames, err := parseGitBlame(stdout, int(info.Size())+bufio.MaxScanTokenSize)
+	if err != nil {
+		// git would block writing the rest of the output
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		err = fmt.Errorf("failed to read git blame output: %s", err)
+		slog.Debug("git blame failed", "path", path, "error", err)
+		return nil, err
+	}
 	if err := cmd.Wait(); err != nil {
-		err = fmt.Errorf("git blame failed: %v - %s", err, stderr.String())
-		log.Debug(err)
+		err = fmt.Errorf("git blame failed: %v - %s", err, strings.TrimSpace(stderr.String()))
+		slog.Debug("git blame failed", "path", path, "error", err)
 		return nil, err
 	}
 
@@ -0,0 +1,127 @@
+package blame
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestTruncateName(t *testing.T) {
+	cases := []struct {
+		name     string
+		maxWidth int
+		want     string
+	}{
+		{"John Doe", 20, "John Doe"},
+		{"José Álvarez", 20, "José Álvarez"},
+		{"陈伟", 20, "陈伟"},
+		{"Maximilian Alexander Featherstonehaugh", 20, "Maximilian A F"},
+		{"José María Rodríguez Álvarez", 20, "José María R Á"},
+		{"Ñandú Ñoño Çağlayan Ölçer", 12, "Ñandú Ñ Ç Ö"},
+		{"Bartholomew Featherstonehaugh", 10, "Bartholo F"},
+		{"Aleksandrovich", 6, "Aleksa"},
+		{"陈伟明 欧阳", 6, "陈 欧"},
+		{"欧阳陈伟明", 5, "欧阳"},
+		{"A B C D E F G H I J K L", 5, "A B C"},
+		{"", 20, ""},
+	}
+	for _, c := range cases {
+		got := truncateName(c.name, c.maxWidth)
+		if got != c.want {
+			t.Errorf("truncateName(%q, %d) = %q, want %q", c.name, c.maxWidth, got, c.want)
+		}
+		if w := runewidth.StringWidth(got); w > c.maxWidth {
+			t.Errorf("truncateName(%q, %d) has width %d", c.name, c.maxWidth, w)
+		}
+	}
+}
+
+func TestParseGitBlame(t *testing.T) {
+	// output of git blame --line-porcelain -L 2,2 -L 5,5
+	out := `345fcef0b3cedfeed0f27c6975857773f85d17b0 2 2 1
+author Ana Silva
+author-mail <ana@example.com>
+author-time 1700000000
+summary add parser
+filename a.py
+	# FIXME: later
+0000000000000000000000000000000000000000 5 5 1
+author Not Committed Yet
+author-time 1800000000
+filename a.py
+	# TODO: new
+`
+	blames, err := parseGitBlame(strings.NewReader(out), len(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blames) != 2 {
+		t.Fatalf("got %d lines, want 2", len(blames))
+	}
+	if b := blames[2]; b.Author != "Ana Silva" || b.ShortHash() != "345fcef" || b.Time.Unix() != 1700000000 {
+		t.Errorf("line 2: got %+v", b)
+	}
+	if b := blames[5]; b.Hash != "" || b.Author != "Not Committed Yet" {
+		t.Errorf("line 5: got %+v", b)
+	}
+
+	// lines longer than the default limit of bufio.Scanner
+	long := strings.Replace(out, "# FIXME: later", "# FIXME: "+strings.Repeat("x", 100000), 1)
+	blames, err = parseGitBlame(strings.NewReader(long), len(long))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blames) != 2 {
+		t.Errorf("long line: got %d lines, want 2", len(blames))
+	}
+	if _, err := parseGitBlame(strings.NewReader(long), 1000); err == nil {
+		t.Error("expected error for a line longer than the limit")
+	}
+}
+
+func TestLineRanges(t *testing.T) {
+	got := strings.Join(lineRanges([]int{1, 2, 3, 7, 9, 10}), " ")
+	if want := "-L 1,3 -L 7,7 -L 9,10"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseAgeMode(t *testing.T) {
+	for _, name := range AgeModes {
+		mode, err := ParseAgeMode(name)
+		if err != nil || mode.String() != name {
+			t.Errorf("%s: got %v, %v", name, mode, err)
+		}
+	}
+	if got := strings.Join(Introduced.blameArgs(), " "); got != "-w -M -C" {
+		t.Errorf("introduced: got %q", got)
+	}
+	if _, err := ParseAgeMode("oldest"); err == nil {
+		t.Error("expected an error for an unknown age mode")
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions([]string{"C", "-w", "w"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(Settings{Options: opts}.gitArgs(), " "); got != "-w -C" {
+		t.Errorf("got %q, want %q", got, "-w -C")
+	}
+	if got := strings.Join(Settings{AgeMode: Introduced, Options: opts}.gitArgs(), " "); got != "-w -M -C" {
+		t.Errorf("introduced: got %q, want %q", got, "-w -M -C")
+	}
+	if _, err := ParseOptions([]string{"-L"}); err == nil {
+		t.Error("expected an error for an unsupported option")
+	}
+}
+
+func TestLocalBlame(t *testing.T) {
+	b := LocalBlame("Maria Fernanda Souza", time.Time{})
+	if b.Author != "Maria F S (local)" || b.FullAuthor != "Maria Fernanda Souza (local)" {
+		t.Errorf("got %q, %q", b.Author, b.FullAuthor)
+	}
+}
@@ -0,0 +1,73 @@
+package blame
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mathpn/listme/cache"
+	"github.com/mathpn/listme/matcher"
+)
+
+// Cache reuses the blame of files whose content didn't change since they were
+// last blamed at the same HEAD commit. A nil *Cache blames every file.
+type Cache struct {
+	store *cache.Cache
+	head  string
+}
+
+// NewCache returns the blame cache of the git repository that contains path.
+// It fails if path is not inside a repository or if HEAD has no commits yet.
+func NewCache(path string) (*Cache, error) {
+	root, err := matcher.RepoRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := exec.Command("git", "-C", root, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git rev-parse failed: %v", err)
+	}
+	store, err := cache.Open(root)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{store: store, head: strings.TrimSpace(string(out))}, nil
+}
+
+// BlameFile returns the cached blame of the lines of the file with the settings if
+// available, otherwise it calls BlameFile and stores the result.
+func (c *Cache) BlameFile(ctx context.Context, path string, lines []int, settings Settings) (*GitBlame, error) {
+	if c == nil {
+		return BlameFile(ctx, path, lines, settings)
+	}
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(absolutePath)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	// the version changes with the fields of LineBlame
+	key := strings.Join([]string{"blame-v2", strings.Join(settings.gitArgs(), " "), c.head, absolutePath, hex.EncodeToString(sum[:]), fmt.Sprint(lines)}, "\x00")
+
+	var blames map[int]*LineBlame
+	if c.store.Get(key, &blames) {
+		return &GitBlame{blames: blames}, nil
+	}
+	gb, err := BlameFile(ctx, absolutePath, lines, settings)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.Put(key, gb.blames); err != nil {
+		slog.Debug("failed to cache git blame", "path", path, "error", err)
+	}
+	return gb, nil
+}
@@ -0,0 +1,48 @@
+package blame
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// UnknownAuthor is the author of lines that couldn't be blamed in shallow or partial clones.
+const UnknownAuthor = "unknown"
+
+// CloneState describes a repository cloned with a limited history.
+//   - Shallow: commits older than the clone depth are missing
+//   - Partial: objects are fetched on demand from a promisor remote
+type CloneState struct {
+	Shallow bool
+	Partial bool
+}
+
+// Incomplete reports whether the repository lacks part of its history.
+func (s CloneState) Incomplete() bool {
+	return s.Shallow || s.Partial
+}
+
+// DetectCloneState returns the state of the repository that contains path.
+func DetectCloneState(path string) CloneState {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	var state CloneState
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--is-shallow-repository").Output()
+	if err == nil {
+		state.Shallow = strings.TrimSpace(string(out)) == "true"
+	}
+	// partial clones have a promisor remote, older versions of git also set extensions.partialClone
+	out, err = exec.Command("git", "-C", dir, "config", "--get-regexp", `^(extensions\.partialclone|remote\..*\.promisor)$`).Output()
+	if err == nil {
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			_, value, _ := strings.Cut(line, " ")
+			if value != "" && value != "false" {
+				state.Partial = true
+			}
+		}
+	}
+	return state
+}
@@ -0,0 +1,183 @@
+//go:build libgit2
+
+package blame
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	git "github.com/libgit2/git2go/v34"
+)
+
+func init() {
+	backend = libgit2Blame
+}
+
+// idleTimeout is how long a libgit2 worker waits for another file before it stops.
+const idleTimeout = 5 * time.Second
+
+// blameRequest is a file to blame by a libgit2 worker, which sends the result back.
+type blameRequest struct {
+	path   string
+	lines  []int
+	result chan<- blameResult
+}
+
+type blameResult struct {
+	blame *GitBlame
+	err   error
+}
+
+// Files are blamed by a pool of long-lived workers instead of one git process per
+// file. A libgit2 repository must not be used by several threads at once, so each
+// worker opens its own repositories and reuses them for every file it blames, which
+// amortizes opening them and loading their index. Idle workers stop after idleTimeout,
+// freeing their repositories.
+var (
+	requests = make(chan blameRequest)
+	// workers limits the number of running workers
+	workers = make(chan struct{}, runtime.GOMAXPROCS(0))
+)
+
+// libgit2Blame blames the file in-process with libgit2. libgit2 only blames
+// committed content, so files with uncommitted changes are left to git blame.
+func libgit2Blame(path string, lines []int) (*GitBlame, error) {
+	result := make(chan blameResult, 1)
+	req := blameRequest{path: path, lines: lines, result: result}
+	// prefer an idle worker, then start a new one if the pool isn't full
+	select {
+	case requests <- req:
+	default:
+		select {
+		case requests <- req:
+		case workers <- struct{}{}:
+			go newWorker().run(req)
+		}
+	}
+	r := <-result
+	return r.blame, r.err
+}
+
+// worker blames files with the repositories it owns.
+//   - repos: open repositories, by repository root
+//   - roots: repository root of the directories of the blamed files
+type worker struct {
+	repos map[string]*git.Repository
+	roots map[string]string
+}
+
+func newWorker() *worker {
+	return &worker{repos: make(map[string]*git.Repository), roots: make(map[string]string)}
+}
+
+// run blames the file of req and of the following requests, until none arrives for
+// idleTimeout. The repositories of the worker are freed when it stops.
+func (w *worker) run(req blameRequest) {
+	defer func() { <-workers }()
+	defer w.free()
+	for {
+		gb, err := w.blame(req.path, req.lines)
+		req.result <- blameResult{blame: gb, err: err}
+		select {
+		case req = <-requests:
+		case <-time.After(idleTimeout):
+			return
+		}
+	}
+}
+
+func (w *worker) free() {
+	for _, repo := range w.repos {
+		repo.Free()
+	}
+}
+
+// repository returns the repository of the directory, opening it the first time a
+// file of the repository is blamed.
+func (w *worker) repository(dir string) (*git.Repository, error) {
+	if root, ok := w.roots[dir]; ok {
+		return w.repos[root], nil
+	}
+	repo, err := git.OpenRepositoryExtended(dir, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	root := repo.Workdir()
+	if open, ok := w.repos[root]; ok {
+		repo.Free()
+		repo = open
+	} else {
+		w.repos[root] = repo
+	}
+	w.roots[dir] = root
+	return repo, nil
+}
+
+func (w *worker) blame(path string, lines []int) (*GitBlame, error) {
+	repo, err := w.repository(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(repo.Workdir(), path)
+	if err != nil {
+		return nil, err
+	}
+	rel = filepath.ToSlash(rel)
+
+	status, err := repo.StatusFile(rel)
+	if err != nil {
+		return nil, err
+	}
+	if status != git.StatusCurrent {
+		return nil, fmt.Errorf("%s has uncommitted changes", rel)
+	}
+
+	opts, err := git.DefaultBlameOptions()
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) > 0 {
+		opts.MinLine = uint32(lines[0])
+		opts.MaxLine = uint32(lines[len(lines)-1])
+	}
+	b, err := repo.BlameFile(rel, &opts)
+	if err != nil {
+		return nil, err
+	}
+	defer b.Free()
+
+	blames := make(map[int]*LineBlame)
+	if len(lines) > 0 {
+		for _, n := range lines {
+			if hunk, err := b.HunkByLine(n); err == nil {
+				blames[n] = hunkBlame(hunk)
+			}
+		}
+		return &GitBlame{blames: blames}, nil
+	}
+	for i := 0; i < b.HunkCount(); i++ {
+		hunk, err := b.HunkByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < int(hunk.LinesInHunk); j++ {
+			blames[int(hunk.FinalStartLineNumber)+j] = hunkBlame(hunk)
+		}
+	}
+	return &GitBlame{blames: blames}, nil
+}
+
+func hunkBlame(hunk git.BlameHunk) *LineBlame {
+	blame := &LineBlame{}
+	if hunk.FinalCommitId != nil && !hunk.FinalCommitId.IsZero() {
+		blame.Hash = hunk.FinalCommitId.String()
+	}
+	if sig := hunk.FinalSignature; sig != nil {
+		blame.Time = sig.When
+		blame.FullAuthor = sig.Name
+		blame.Author = truncateName(sig.Name, MaxAuthorLength)
+	}
+	return blame
+}
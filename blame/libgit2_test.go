@@ -0,0 +1,74 @@
+//go:build libgit2
+
+package blame
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// gitRepo creates a repository with a committed file of the provided author.
+func gitRepo(t *testing.T, author string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "code.py"), []byte("x = 1\n# TODO: fix\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "code.py"},
+		{"-c", "user.name=" + author, "-c", "user.email=dev@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestLibgit2Blame(t *testing.T) {
+	authors := []string{"Ada Lovelace", "Alan Turing"}
+	dirs := make([]string, len(authors))
+	for i, author := range authors {
+		dirs[i] = gitRepo(t, author)
+	}
+
+	// the workers are shared by concurrent searches of several repositories
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			gb, err := libgit2Blame(filepath.Join(dirs[i%2], "code.py"), []int{2})
+			if err != nil {
+				errs <- err
+				return
+			}
+			b, err := gb.BlameLine(2)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if b.FullAuthor != authors[i%2] || b.Hash == "" {
+				errs <- fmt.Errorf("got author %q and hash %q, expected %q", b.FullAuthor, b.Hash, authors[i%2])
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dirs[0], "code.py"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := libgit2Blame(filepath.Join(dirs[0], "code.py"), nil); err == nil {
+		t.Error("expected error for a file with uncommitted changes")
+	}
+}
@@ -0,0 +1,49 @@
+package blame
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalSuffix marks the authors of lines attributed to the local user, see LocalBlame.
+const LocalSuffix = " (local)"
+
+// LocalUser returns the name of the current user: the user.name setting of git for
+// the directory of path, unless useGit is false, or else the name of the operating
+// system account. It returns an empty string if neither is known.
+func LocalUser(path string, useGit bool) string {
+	if useGit {
+		dir := path
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			dir = filepath.Dir(path)
+		}
+		out, err := exec.Command("git", "-C", dir, "config", "user.name").Output()
+		if name := strings.TrimSpace(string(out)); err == nil && name != "" {
+			return name
+		}
+	}
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	// the full name is the first field of GECOS on Unix, e.g. "John Doe,,,"
+	if name, _, _ := strings.Cut(u.Name, ","); strings.TrimSpace(name) != "" {
+		return strings.TrimSpace(name)
+	}
+	return u.Username
+}
+
+// LocalBlame returns the blame of a line attributed to the local user name, for lines
+// that couldn't be blamed, e.g. outside of repositories or not committed yet. The
+// author is marked with LocalSuffix, which is kept when the name is shortened.
+func LocalBlame(name string, t time.Time) *LineBlame {
+	return &LineBlame{
+		Time:       t,
+		Author:     truncateName(name, MaxAuthorLength-len(LocalSuffix)) + LocalSuffix,
+		FullAuthor: name + LocalSuffix,
+	}
+}
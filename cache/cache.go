@@ -0,0 +1,227 @@
+// Package cache stores data computed for a git repository, such as the blame of
+// its files, on disk so that later searches can reuse it.
+//
+// Each repository has its own directory under Dir, named after a hash of the
+// repository root. Entries are JSON files named after a hash of their key, and
+// their modification time records the last time they were used.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// repoFile holds the root of the repository of a cache directory.
+const repoFile = "repo"
+
+const entrySuffix = ".json"
+
+// Cache is the cache of a single repository.
+type Cache struct {
+	dir string
+}
+
+// Dir returns the directory where all caches are stored, listme inside the user
+// cache directory, e.g. ~/.cache/listme on Linux.
+func Dir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "listme"), nil
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// Open returns the cache of the repository rooted at repoRoot, creating it if needed.
+func Open(repoRoot string) (*Cache, error) {
+	base, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(base, hash(repoRoot)[:16])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, repoFile), []byte(repoRoot+"\n"), 0o644); err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir}, nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, hash(key)+entrySuffix)
+}
+
+// Get decodes the entry stored with the key into v and reports whether it was found.
+// Entries that can't be decoded are treated as missing.
+func (c *Cache) Get(key string, v any) bool {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return true
+}
+
+// Put stores v with the key, replacing any previous entry.
+func (c *Cache) Put(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, so concurrent searches never read partial entries
+	f, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// Repo summarizes the cache of a repository.
+//   - Path: root of the repository, empty if unknown
+//   - Dir: directory of the cache
+//   - Size: total size of the entries, in bytes
+//   - LastUsed: last time an entry was stored or read
+type Repo struct {
+	Path     string
+	Dir      string
+	Entries  int
+	Size     int64
+	LastUsed time.Time
+}
+
+// Repos returns the cached repositories sorted by path.
+func Repos() ([]Repo, error) {
+	base, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	dirs, err := os.ReadDir(base)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []Repo
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		repo := Repo{Dir: filepath.Join(base, d.Name())}
+		if data, err := os.ReadFile(filepath.Join(repo.Dir, repoFile)); err == nil {
+			repo.Path = strings.TrimSpace(string(data))
+		}
+		err := forEachEntry(repo.Dir, func(_ string, info fs.FileInfo) {
+			repo.Entries++
+			repo.Size += info.Size()
+			if info.ModTime().After(repo.LastUsed) {
+				repo.LastUsed = info.ModTime()
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Path < repos[j].Path })
+	return repos, nil
+}
+
+func forEachEntry(dir string, fn func(path string, info fs.FileInfo)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), entrySuffix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		fn(filepath.Join(dir, e.Name()), info)
+	}
+	return nil
+}
+
+// Clear removes the cache of the repository rooted at repoRoot, or every cache if
+// repoRoot is empty. It returns the number of bytes freed.
+func Clear(repoRoot string) (int64, error) {
+	repos, err := Repos()
+	if err != nil {
+		return 0, err
+	}
+	var freed int64
+	for _, repo := range repos {
+		if repoRoot != "" && repo.Path != repoRoot {
+			continue
+		}
+		if err := os.RemoveAll(repo.Dir); err != nil {
+			return freed, err
+		}
+		freed += repo.Size
+	}
+	return freed, nil
+}
+
+// GC removes the entries not used within maxAge and the caches of repositories
+// that no longer exist. It returns the number of entries removed and bytes freed.
+func GC(maxAge time.Duration) (int, int64, error) {
+	repos, err := Repos()
+	if err != nil {
+		return 0, 0, err
+	}
+	var removed int
+	var freed int64
+	limit := time.Now().Add(-maxAge)
+	for _, repo := range repos {
+		if _, err := os.Stat(repo.Path); repo.Path == "" || errors.Is(err, fs.ErrNotExist) {
+			if err := os.RemoveAll(repo.Dir); err != nil {
+				return removed, freed, err
+			}
+			removed += repo.Entries
+			freed += repo.Size
+			continue
+		}
+		err := forEachEntry(repo.Dir, func(path string, info fs.FileInfo) {
+			if info.ModTime().Before(limit) && os.Remove(path) == nil {
+				removed++
+				freed += info.Size()
+			}
+		})
+		if err != nil {
+			return removed, freed, err
+		}
+	}
+	return removed, freed, nil
+}
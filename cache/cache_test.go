@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetPut(t *testing.T) {
+	c := &Cache{dir: t.TempDir()}
+	var got []int
+	if c.Get("key", &got) {
+		t.Fatal("found an entry in an empty cache")
+	}
+	if err := c.Put("key", []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Get("key", &got) || len(got) != 2 || got[1] != 2 {
+		t.Fatalf("got %v, want [1 2]", got)
+	}
+
+	// corrupt entries are treated as missing
+	if err := os.WriteFile(c.path("key"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c.Get("key", &got) {
+		t.Error("found a corrupt entry")
+	}
+}
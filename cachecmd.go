@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/cache"
+	"github.com/mathpn/listme/matcher"
+)
+
+// cacheModes lists the accepted modes of the cache command.
+var cacheModes = []string{"status", "clear", "gc"}
+
+func runCache(args []string) {
+	parser := argparse.NewParser("listme cache", "Inspect and prune the git blame cache reused by searches.")
+	mode := parser.SelectorPositional(cacheModes, &argparse.Options{Help: "Mode: status shows the cache size per repository, clear removes the cache of the current repository and gc removes stale entries"})
+	all := parser.Flag("", "all", &argparse.Options{Help: "With clear, remove the cache of every repository"})
+	maxAge := parser.Int("", "max-age", &argparse.Options{Default: 30, Help: "With gc, remove entries not used within this number of days"})
+	parseArgs(parser, args)
+
+	if err := setupLogging(false, false, "text", ""); err != nil {
+		fatal(err)
+	}
+
+	switch *mode {
+	case "status", "":
+		cacheStatus()
+	case "clear":
+		repo := ""
+		if !*all {
+			root, err := matcher.RepoRoot(".")
+			if err != nil {
+				fatal(fmt.Errorf("not inside a git repository, use --all to clear every cache"))
+			}
+			repo = root
+		}
+		freed, err := cache.Clear(repo)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("freed %s\n", formatSize(freed))
+	case "gc":
+		if *maxAge < 0 {
+			fatal(fmt.Errorf("max-age must be a non-negative integer"))
+		}
+		removed, freed, err := cache.GC(time.Duration(*maxAge) * 24 * time.Hour)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Printf("removed %d entries, freed %s\n", removed, formatSize(freed))
+	}
+}
+
+func cacheStatus() {
+	dir, err := cache.Dir()
+	if err != nil {
+		fatal(err)
+	}
+	repos, err := cache.Repos()
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Printf("cache directory: %s\n", dir)
+
+	var total int64
+	for _, repo := range repos {
+		path := repo.Path
+		if path == "" {
+			path = repo.Dir
+		} else if _, err := os.Stat(path); err != nil {
+			path += " (missing)"
+		}
+		lastUsed := "never"
+		if !repo.LastUsed.IsZero() {
+			lastUsed = repo.LastUsed.Format(time.DateOnly)
+		}
+		fmt.Printf("%10s %7d entries  last used %s  %s\n", formatSize(repo.Size), repo.Entries, lastUsed, path)
+		total += repo.Size
+	}
+	fmt.Printf("%10s total in %d repositories\n", formatSize(total), len(repos))
+}
+
+// formatSize formats a number of bytes with a binary unit, e.g. 1.5 MiB.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
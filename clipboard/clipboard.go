@@ -0,0 +1,67 @@
+// Package clipboard places text on the system clipboard.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Write places the text on the system clipboard using the platform clipboard tools.
+// If none of them is available, e.g. over SSH, the text is sent to the terminal
+// with an OSC 52 escape sequence, which most modern terminals support.
+func Write(text string) error {
+	for _, args := range commands() {
+		path, err := exec.LookPath(args[0])
+		if err != nil {
+			continue
+		}
+		cmd := exec.Command(path, args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if err := cmd.Run(); err != nil {
+			slog.Debug("clipboard tool failed", "command", args[0], "error", err)
+			continue
+		}
+		return nil
+	}
+	return writeOSC52(text)
+}
+
+// commands returns the clipboard tools of the platform, in order of preference.
+func commands() [][]string {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbcopy"}}
+	case "windows":
+		return [][]string{{"clip.exe"}}
+	}
+	var cmds [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmds = append(cmds, []string{"wl-copy"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		cmds = append(cmds, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+	}
+	return cmds
+}
+
+// writeOSC52 writes the OSC 52 sequence to the controlling terminal, so stdout is left untouched.
+// Inside tmux, the sequence is wrapped to be passed through to the outer terminal.
+func writeOSC52(text string) error {
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("no clipboard tool found and no terminal to send OSC 52 to")
+	}
+	defer tty.Close()
+
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, err = tty.WriteString(seq)
+	return err
+}
@@ -0,0 +1,113 @@
+// Package codeowners parses CODEOWNERS files, which assign owners, such as teams
+// (@org/team), users (@user) or email addresses, to the paths of a repository.
+package codeowners
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	gitignore "github.com/sabhiram/go-gitignore"
+)
+
+// Locations are the paths of the CODEOWNERS file, relative to the repository root,
+// in the order they're looked up, as on GitHub and GitLab.
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// rule assigns owners to the paths matching a pattern. A rule without owners leaves
+// the paths without owners.
+type rule struct {
+	pattern *gitignore.GitIgnore
+	owners  []string
+}
+
+// Owners assigns owners to the paths of a repository. A nil *Owners has no owners.
+type Owners struct {
+	root  string
+	rules []rule
+}
+
+// Load parses the CODEOWNERS file of the repository rooted at repoRoot. It fails with
+// fs.ErrNotExist if there's no CODEOWNERS file in any of the Locations.
+func Load(repoRoot string) (*Owners, error) {
+	for _, location := range Locations {
+		f, err := os.Open(filepath.Join(repoRoot, filepath.FromSlash(location)))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		owners, err := Parse(repoRoot, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", location, err)
+		}
+		return owners, nil
+	}
+	return nil, fmt.Errorf("no CODEOWNERS file found in %s: %w", repoRoot, fs.ErrNotExist)
+}
+
+// Parse reads the rules of a CODEOWNERS file of the repository rooted at repoRoot.
+// Each line is a gitignore-style pattern followed by its owners; comments start with #.
+// GitLab sections, e.g. [Docs], are ignored, so their rules apply as any other.
+func Parse(repoRoot string, r io.Reader) (*Owners, error) {
+	o := &Owners{root: repoRoot}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		o.rules = append(o.rules, rule{pattern: gitignore.CompileIgnoreLines(fields[0]), owners: fields[1:]})
+	}
+	return o, scanner.Err()
+}
+
+// Of returns the owners of a file, given by its absolute path or relative to the
+// repository root. The last matching rule takes precedence.
+func (o *Owners) Of(path string) []string {
+	if o == nil {
+		return nil
+	}
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(o.root, path)
+		if err != nil {
+			return nil
+		}
+		path = rel
+	}
+	path = filepath.ToSlash(path)
+	for i := len(o.rules) - 1; i >= 0; i-- {
+		if o.rules[i].pattern.MatchesPath(path) {
+			return o.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// Owns reports whether owner is one of the owners. Owners are compared case-insensitively,
+// with or without the leading @, and a team also matches without its organization,
+// so payments-team matches @org/payments-team.
+func Owns(owners []string, owner string) bool {
+	owner = strings.ToLower(strings.TrimPrefix(owner, "@"))
+	for _, o := range owners {
+		o = strings.ToLower(strings.TrimPrefix(o, "@"))
+		if o == owner {
+			return true
+		}
+		if _, team, ok := strings.Cut(o, "/"); ok && team == owner {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package codeowners
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	file := `# default owners
+*            @org/platform
+*.go         @org/backend dev@example.com
+
+[Payments]
+/payments/   @org/payments-team # the whole directory
+docs/        @org/docs
+/payments/README.md
+`
+	owners, err := Parse("/repo", strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path   string
+		owners []string
+	}{
+		{"main.py", []string{"@org/platform"}},
+		{"cmd/main.go", []string{"@org/backend", "dev@example.com"}},
+		{"/repo/payments/api.go", []string{"@org/payments-team"}},
+		{"payments/README.md", nil},
+		{"src/docs/intro.md", []string{"@org/docs"}},
+	}
+	for _, c := range cases {
+		if got := owners.Of(c.path); !slices.Equal(got, c.owners) {
+			t.Errorf("%s: expected owners %v, got %v", c.path, c.owners, got)
+		}
+	}
+}
+
+func TestOwns(t *testing.T) {
+	owners := []string{"@org/payments-team", "dev@example.com"}
+	for _, owner := range []string{"@org/payments-team", "@payments-team", "Payments-Team", "dev@example.com"} {
+		if !Owns(owners, owner) {
+			t.Errorf("expected %s to be an owner", owner)
+		}
+	}
+	for _, owner := range []string{"@org", "@other/payments", "@dev"} {
+		if Owns(owners, owner) {
+			t.Errorf("expected %s not to be an owner", owner)
+		}
+	}
+}
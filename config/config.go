@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames lists the accepted names of the configuration file, in order of precedence.
+var FileNames = []string{".listme.yaml", ".listme.yml"}
+
+// Config holds the settings read from a configuration file.
+//   - CommentPrefixes: comment markers per file extension, e.g. ".lisp": ";;"
+//   - ExtensionTags: additional tags per file extension, matched literally, e.g. ".py": ["type: ignore"]
+//   - DocumentationRules: how tags are found per file extension, either "prose" or "comment"
+//   - Locale: language of the output labels, e.g. pt-BR. Defaults to the LANG environment variable
+//   - AgeTiers: badges marking lines by commit age
+//   - ForbiddenTags: tags that block a commit when found in added lines, e.g. BUG or FIXME!
+//   - MaxFileSizes: maximum file size to scan per file extension (in MB), overriding --max-file-size
+//   - FailOn: tags that make a --ci run exit with a non-zero status when found
+//   - IncludeUntracked: whether files not tracked by git are scanned, true if not provided.
+//     It only applies inside git repositories
+//   - Authors: rules that rename or exclude commit authors, applied in order, see AuthorRule
+//   - Tags: tags searched by default instead of the built-in ones, overridden by --tags
+//   - Glob: glob pattern of the files searched by default, overridden by --glob
+//   - Style: default style, one of Styles, overridden by --bw and --plain
+//   - Exclude: names of directories that are never searched, in addition to the dependency directories
+//   - OldCommitLimit: age in days of the lines marked as old, overridden by --old-commit-mark-limit
+//   - Workers: number of search workers, overridden by --workers
+//   - Lint: rules that flag low-quality comments when searching with --lint, see LintRules
+type Config struct {
+	CommentPrefixes    map[string]Prefixes `yaml:"comment_prefixes"`
+	ExtensionTags      map[string][]string `yaml:"extension_tags"`
+	DocumentationRules map[string]string   `yaml:"documentation_rules"`
+	Locale             string              `yaml:"locale"`
+	AgeTiers           []AgeTier           `yaml:"age_tiers"`
+	ForbiddenTags      []string            `yaml:"forbidden_tags"`
+	FailOn             []string            `yaml:"fail_on"`
+	MaxFileSizes       map[string]int64    `yaml:"max_file_sizes"`
+	IncludeUntracked   *bool               `yaml:"include_untracked"`
+	Authors            []AuthorRule        `yaml:"authors"`
+	Tags               []string            `yaml:"tags"`
+	Glob               string              `yaml:"glob"`
+	Style              string              `yaml:"style"`
+	Exclude            []string            `yaml:"exclude"`
+	OldCommitLimit     *int                `yaml:"old_commit_limit"`
+	Workers            int                 `yaml:"workers"`
+	Lint               LintRules           `yaml:"lint"`
+}
+
+// LintRules are the policy of --lint. Comments without text are always flagged.
+//   - MinLength: minimum length of the comment text, in characters, overridden by --lint-min-length
+//   - RequireAssignee: comments must name an assignee, as in TODO(alice) or @alice
+//   - RequireIssue: comments must reference an issue, as in #123, PROJ-123 or a link
+type LintRules struct {
+	MinLength       int  `yaml:"min_length"`
+	RequireAssignee bool `yaml:"require_assignee"`
+	RequireIssue    bool `yaml:"require_issue"`
+}
+
+// Styles lists the accepted values of the style setting.
+var Styles = []string{"full", "bw", "plain"}
+
+// AuthorRule renames the commit authors whose name matches the regular expression
+// Match to Name or, with Exclude, drops their lines, e.g. to merge the identities of
+// a contractor or to hide bot accounts.
+type AuthorRule struct {
+	Match   string `yaml:"match"`
+	Name    string `yaml:"name"`
+	Exclude bool   `yaml:"exclude"`
+}
+
+// AgeTier marks lines committed more than Days days ago with Label.
+// Color is the optional background color of the badge.
+type AgeTier struct {
+	Label string `yaml:"label"`
+	Days  int    `yaml:"days"`
+	Color string `yaml:"color"`
+}
+
+// Prefixes is a list of comment markers. In the configuration file,
+// it can be written either as a single string or as a list of strings.
+type Prefixes []string
+
+func (p *Prefixes) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = Prefixes{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// Load finds and parses the configuration file for the provided path, see Find.
+// If no file is found, an empty Config is returned.
+func Load(path string) (*Config, error) {
+	configPath, ok := Find(path)
+	if !ok {
+		return &Config{}, nil
+	}
+	slog.Debug("loading configuration file", "path", configPath)
+	return Parse(configPath)
+}
+
+// Parse reads the configuration file at path.
+func Parse(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %s", path, err)
+	}
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", path, err)
+	}
+	cfg.normalize()
+	return cfg, nil
+}
+
+// normalize ensures all extensions start with a dot.
+func (c *Config) normalize() {
+	prefixes := make(map[string]Prefixes, len(c.CommentPrefixes))
+	for ext, p := range c.CommentPrefixes {
+		prefixes[normalizeExt(ext)] = p
+	}
+	c.CommentPrefixes = prefixes
+
+	extTags := make(map[string][]string, len(c.ExtensionTags))
+	for ext, tags := range c.ExtensionTags {
+		extTags[normalizeExt(ext)] = tags
+	}
+	c.ExtensionTags = extTags
+
+	sizes := make(map[string]int64, len(c.MaxFileSizes))
+	for ext, size := range c.MaxFileSizes {
+		sizes[normalizeExt(ext)] = size
+	}
+	c.MaxFileSizes = sizes
+
+	rules := make(map[string]string, len(c.DocumentationRules))
+	for ext, rule := range c.DocumentationRules {
+		rules[normalizeExt(ext)] = rule
+	}
+	c.DocumentationRules = rules
+}
+
+func normalizeExt(ext string) string {
+	if !strings.HasPrefix(ext, ".") {
+		return "." + ext
+	}
+	return ext
+}
+
+// Find returns the path of the configuration file for the provided path, as used by Load.
+// The file is searched for in the path (or its directory, if path is a file) and in its
+// parents, up to the root of the git repository. If there's none, the user configuration
+// file is used, see UserFile. Settings of the two files are not merged.
+func Find(path string) (string, bool) {
+	if configPath, ok := findInRepo(path); ok {
+		return configPath, true
+	}
+	userPath := UserFile()
+	if _, err := os.Stat(userPath); userPath == "" || err != nil {
+		return "", false
+	}
+	return userPath, true
+}
+
+// UserFile returns the path of the user configuration file, config.yaml in the listme
+// directory of $XDG_CONFIG_HOME, or of ~/.config if it's not set. It returns an empty
+// string if the home directory is unknown.
+func UserFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "listme", "config.yaml")
+}
+
+func findInRepo(path string) (string, bool) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	for {
+		for _, name := range FileNames {
+			candidate := filepath.Join(dir, name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, true
+			}
+		}
+		// the repository root is the last place to look for a configuration file
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil || dir == os.Getenv("GIT_WORK_TREE") {
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
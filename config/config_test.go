@@ -0,0 +1,28 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMaxFileSizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".listme.yaml")
+	content := "max_file_sizes:\n  log: 20\n  .csv: 50\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int64{".log": 20, ".csv": 50}
+	if len(cfg.MaxFileSizes) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, cfg.MaxFileSizes)
+	}
+	for ext, size := range expected {
+		if cfg.MaxFileSizes[ext] != size {
+			t.Errorf("max file size of %s: expected %d, got %d", ext, size, cfg.MaxFileSizes[ext])
+		}
+	}
+}
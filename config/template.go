@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Template returns a commented configuration file with the settings of c.
+// Empty settings are written as commented-out examples.
+func Template(c *Config) string {
+	var b strings.Builder
+	b.WriteString("# listme configuration file, see https://github.com/mathpn/listme#configuration-file\n")
+
+	b.WriteString("\n# Language of the output labels: en, pt-BR or es.\n")
+	b.WriteString("# Defaults to the LC_ALL, LC_MESSAGES or LANG environment variables.\n")
+	if c.Locale != "" {
+		fmt.Fprintf(&b, "locale: %s\n", c.Locale)
+	} else {
+		b.WriteString("# locale: en\n")
+	}
+
+	b.WriteString("\n# Defaults of the search, overridden by the command line flags. The style is full, bw or plain.\n")
+	writeList(&b, "tags", c.Tags, "BUG, FIXME, TODO")
+	if c.Glob != "" {
+		fmt.Fprintf(&b, "glob: %s\n", strconv.Quote(c.Glob))
+	} else {
+		b.WriteString("# glob: \"*.go\"\n")
+	}
+	if c.Style != "" {
+		fmt.Fprintf(&b, "style: %s\n", c.Style)
+	} else {
+		b.WriteString("# style: plain\n")
+	}
+	writeList(&b, "exclude", c.Exclude, "generated, third_party")
+	if c.OldCommitLimit != nil {
+		fmt.Fprintf(&b, "old_commit_limit: %d\n", *c.OldCommitLimit)
+	} else {
+		b.WriteString("# old_commit_limit: 60\n")
+	}
+	if c.Workers > 0 {
+		fmt.Fprintf(&b, "workers: %d\n", c.Workers)
+	} else {
+		b.WriteString("# workers: 128\n")
+	}
+
+	b.WriteString("\n# Rules that flag low-quality comments when searching with --lint. Comments without text are always flagged.\n")
+	if c.Lint != (LintRules{}) {
+		b.WriteString("lint:\n")
+		if c.Lint.MinLength > 0 {
+			fmt.Fprintf(&b, "  min_length: %d\n", c.Lint.MinLength)
+		}
+		if c.Lint.RequireAssignee {
+			b.WriteString("  require_assignee: true\n")
+		}
+		if c.Lint.RequireIssue {
+			b.WriteString("  require_issue: true\n")
+		}
+	} else {
+		b.WriteString("# lint:\n#   min_length: 10\n#   require_assignee: true\n#   require_issue: true\n")
+	}
+
+	b.WriteString("\n# Badges marking lines by commit age, with a minimum age in days and an optional color.\n")
+	if len(c.AgeTiers) > 0 {
+		b.WriteString("age_tiers:\n")
+		for _, tier := range c.AgeTiers {
+			fmt.Fprintf(&b, "  - label: %s\n    days: %d\n", strconv.Quote(tier.Label), tier.Days)
+			if tier.Color != "" {
+				fmt.Fprintf(&b, "    color: %s\n", strconv.Quote(tier.Color))
+			}
+		}
+	} else {
+		b.WriteString("# age_tiers:\n#   - label: OLD\n#     days: 60\n")
+	}
+
+	b.WriteString("\n# Tags that make a --ci run fail when found.\n")
+	writeList(&b, "fail_on", c.FailOn, "BUG, FIXME")
+
+	b.WriteString("\n# Tags that block a commit when found in added lines, see listme hook check-staged.\n")
+	writeList(&b, "forbidden_tags", c.ForbiddenTags, "FIXME!")
+
+	b.WriteString("\n# Comment markers per file extension, for languages with unusual comment syntax.\n")
+	if len(c.CommentPrefixes) > 0 {
+		b.WriteString("comment_prefixes:\n")
+		for _, ext := range sortedKeys(c.CommentPrefixes) {
+			fmt.Fprintf(&b, "  %s: %s\n", ext, quoteList([]string(c.CommentPrefixes[ext])))
+		}
+	} else {
+		b.WriteString("# comment_prefixes:\n#   .lisp: \";;\"\n#   .sql: \"--\"\n")
+	}
+
+	b.WriteString("\n# Additional tags per file extension, matched literally.\n")
+	if len(c.ExtensionTags) > 0 {
+		b.WriteString("extension_tags:\n")
+		for _, ext := range sortedKeys(c.ExtensionTags) {
+			fmt.Fprintf(&b, "  %s: %s\n", ext, quoteList(c.ExtensionTags[ext]))
+		}
+	} else {
+		b.WriteString("# extension_tags:\n#   .py: [\"type: ignore\"]\n#   .java: [\"@deprecated\"]\n")
+	}
+
+	b.WriteString("\n# Maximum file size to scan per file extension, in MB, overriding --max-file-size.\n")
+	if len(c.MaxFileSizes) > 0 {
+		b.WriteString("max_file_sizes:\n")
+		for _, ext := range sortedKeys(c.MaxFileSizes) {
+			fmt.Fprintf(&b, "  %s: %d\n", ext, c.MaxFileSizes[ext])
+		}
+	} else {
+		b.WriteString("# max_file_sizes:\n#   .sql: 1\n#   .go: 20\n")
+	}
+
+	b.WriteString("\n# How tags are found per file extension: prose (anywhere in paragraphs) or comment.\n")
+	if len(c.DocumentationRules) > 0 {
+		b.WriteString("documentation_rules:\n")
+		for _, ext := range sortedKeys(c.DocumentationRules) {
+			fmt.Fprintf(&b, "  %s: %s\n", ext, c.DocumentationRules[ext])
+		}
+	} else {
+		b.WriteString("# documentation_rules:\n#   .txt: prose\n")
+	}
+
+	b.WriteString("\n# Rules that rename or exclude commit authors by regular expression, applied in order.\n")
+	if len(c.Authors) > 0 {
+		b.WriteString("authors:\n")
+		for _, rule := range c.Authors {
+			fmt.Fprintf(&b, "  - match: %s\n", strconv.Quote(rule.Match))
+			if rule.Exclude {
+				b.WriteString("    exclude: true\n")
+			} else {
+				fmt.Fprintf(&b, "    name: %s\n", strconv.Quote(rule.Name))
+			}
+		}
+	} else {
+		b.WriteString("# authors:\n#   - match: \"^(jdoe|John Doe \\\\(contractor\\\\))$\"\n#     name: John Doe\n#   - match: \"\\\\[bot\\\\]$\"\n#     exclude: true\n")
+	}
+	return b.String()
+}
+
+func writeList(b *strings.Builder, key string, values []string, example string) {
+	if len(values) > 0 {
+		fmt.Fprintf(b, "%s: %s\n", key, quoteList(values))
+	} else {
+		fmt.Fprintf(b, "# %s: [%s]\n", key, example)
+	}
+}
+
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/digest"
+	"github.com/mathpn/listme/search"
+)
+
+func runDigest(args []string) {
+	parser := argparse.NewParser("listme digest", "Render the HTML report of tagged comments as an email, printed as an .eml file or sent through an SMTP server. Meant for a weekly cron job.")
+	flags := addSearchFlags(parser)
+	mailTo := parser.StringList("", "mail-to", &argparse.Options{Help: "Recipient of the digest. Can be repeated"})
+	from := parser.String("", "from", &argparse.Options{Help: "Sender of the digest. Required with --smtp"})
+	subject := parser.String("", "subject", &argparse.Options{Help: "Subject of the digest. By default, the number of tagged comments and the searched path"})
+	smtpAddr := parser.String("", "smtp", &argparse.Options{Help: "Send the digest through the SMTP server at this host:port instead of printing it. The LISTME_SMTP_USERNAME and LISTME_SMTP_PASSWORD environment variables set the credentials"})
+	parseArgs(parser, args)
+
+	if *smtpAddr != "" {
+		if *from == "" || len(*mailTo) == 0 {
+			fatal(fmt.Errorf("--smtp requires --from and --mail-to"))
+		}
+		if _, _, err := net.SplitHostPort(*smtpAddr); err != nil {
+			fatal(fmt.Errorf("invalid SMTP server address %q: %s", *smtpAddr, err))
+		}
+	}
+
+	var html bytes.Buffer
+	var matches []search.JSONMatch
+	opts := flags.options()
+	opts.Format = search.HTMLFormat
+	opts.Output = &html
+	opts.Collect = func(m search.JSONMatch) {
+		matches = append(matches, m)
+	}
+	params, err := search.NewSearchParams(opts)
+	if err != nil {
+		fatal(err)
+	}
+	requireComplete(search.Search(params))
+
+	path, err := filepath.Abs(opts.Path)
+	if err != nil {
+		path = opts.Path
+	}
+
+	msg := digest.Message{
+		From:    *from,
+		To:      *mailTo,
+		Subject: *subject,
+		Date:    time.Now(),
+		Text:    digest.Summary(path, matches),
+		HTML:    html.Bytes(),
+	}
+	if msg.Subject == "" {
+		msg.Subject = fmt.Sprintf("listme: %d tagged comments in %s", len(matches), path)
+	}
+	if *smtpAddr == "" {
+		if err := digest.Write(os.Stdout, msg); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	var auth smtp.Auth
+	if user := os.Getenv("LISTME_SMTP_USERNAME"); user != "" {
+		host, _, _ := net.SplitHostPort(*smtpAddr)
+		auth = smtp.PlainAuth("", user, os.Getenv("LISTME_SMTP_PASSWORD"), host)
+	}
+	if err := digest.Send(*smtpAddr, auth, msg); err != nil {
+		fatal(err)
+	}
+}
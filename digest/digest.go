@@ -0,0 +1,149 @@
+// Package digest turns the report of a search into an email, to send a periodic
+// summary of tagged comments from a cron job.
+package digest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mathpn/listme/search"
+)
+
+// maximum number of files listed in the text summary
+const maxFilesListed = 10
+
+// Message is a digest email. Text is the plain text alternative of HTML, shown by
+// mail clients that don't render HTML.
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Date    time.Time
+	Text    string
+	HTML    []byte
+}
+
+// Summary returns the plain text summary of the matches found in path: the number
+// of comments per tag, then the files with the most comments.
+func Summary(path string, matches []search.JSONMatch) string {
+	tags := make(map[string]int)
+	files := make(map[string]int)
+	for _, m := range matches {
+		tags[m.Tag]++
+		files[m.Path]++
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d tagged comments in %s\n", len(matches), path)
+	if len(matches) == 0 {
+		return b.String()
+	}
+	b.WriteString("\n")
+	for _, tag := range sortedKeys(tags) {
+		fmt.Fprintf(&b, "  %-10s %d\n", tag, tags[tag])
+	}
+	b.WriteString("\nFiles with the most comments:\n")
+	paths := sortedKeys(files)
+	if len(paths) > maxFilesListed {
+		paths = paths[:maxFilesListed]
+	}
+	for _, p := range paths {
+		fmt.Fprintf(&b, "  %4d  %s\n", files[p], p)
+	}
+	return b.String()
+}
+
+// sortedKeys returns the keys of counts from the largest count, then alphabetically.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// Write writes the message in the Internet Message Format (an .eml file), as a
+// multipart/alternative email with a text and an HTML part.
+func Write(w io.Writer, m Message) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := writePart(mw, "text/plain; charset=utf-8", []byte(m.Text)); err != nil {
+		return err
+	}
+	if err := writePart(mw, "text/html; charset=utf-8", m.HTML); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if m.From != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	}
+	if len(m.To) > 0 {
+		fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	}
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", m.Date.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@listme>\r\n", messageID())
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	b.Write(body.Bytes())
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func writePart(mw *multipart.Writer, contentType string, content []byte) error {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qw := quotedprintable.NewWriter(part)
+	if _, err := qw.Write(content); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+func messageID() string {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Send sends the message through the SMTP server at addr (host:port), which is
+// asked to upgrade the connection with STARTTLS if it supports it. Auth is optional.
+func Send(addr string, auth smtp.Auth, m Message) error {
+	if m.From == "" || len(m.To) == 0 {
+		return fmt.Errorf("the sender and recipients of the digest are required to send it")
+	}
+	var b bytes.Buffer
+	if err := Write(&b, m); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(addr, auth, m.From, m.To, b.Bytes()); err != nil {
+		return fmt.Errorf("failed to send the digest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package digest
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	m := Message{
+		From:    "listme@example.com",
+		To:      []string{"team@example.com", "lead@example.com"},
+		Subject: "listme: 3 comentários",
+		Date:    time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		Text:    "3 tagged comments in .\n",
+		HTML:    []byte(`<p class="summary">TODO: 3</p>`),
+	}
+	var b bytes.Buffer
+	if err := Write(&b, m); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := msg.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[1].Address != "lead@example.com" {
+		t.Errorf("got recipients %v (%v)", to, err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != m.Subject {
+		t.Errorf("got subject %q (%v), want %q", subject, err, m.Subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("got content type %q (%v)", mediaType, err)
+	}
+
+	// the reader decodes quoted-printable parts
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(part)
+		parts = append(parts, strings.ReplaceAll(string(content), "\r\n", "\n"))
+	}
+	if len(parts) != 2 || parts[0] != m.Text || parts[1] != string(m.HTML) {
+		t.Errorf("got parts %q", parts)
+	}
+}
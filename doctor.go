@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/akamensky/argparse"
+	tsize "github.com/kopoli/go-terminal-size"
+
+	"github.com/mathpn/listme/config"
+	"github.com/mathpn/listme/i18n"
+	"github.com/mathpn/listme/matcher"
+	"github.com/mathpn/listme/search"
+)
+
+// minTerminalWidth is the width below which the full style gets cramped.
+const minTerminalWidth = 80
+
+// check is the result of a diagnostic. Failed checks make listme doctor exit with a non-zero status.
+type check struct {
+	name   string
+	detail string
+	hint   string
+	warn   bool
+	failed bool
+}
+
+func runDoctor(args []string) {
+	parser := argparse.NewParser("listme doctor", "Diagnose the installation, git, terminal and configuration issues that affect listme.")
+	path := parser.StringPositional(&argparse.Options{Default: ".", Help: "Path to be searched, used to find the repository and configuration file"})
+	parseArgs(parser, args)
+
+	if err := setupLogging(false, false, "text", ""); err != nil {
+		fatal(err)
+	}
+
+	checks := []check{
+		checkExecutable(),
+		checkGit(),
+		checkRepository(*path),
+		checkTerminal(),
+		checkTerminalWidth(),
+		checkConfig(*path),
+	}
+
+	var failed bool
+	for _, c := range checks {
+		status := "ok"
+		switch {
+		case c.failed:
+			status = "fail"
+			failed = true
+		case c.warn:
+			status = "warn"
+		}
+		fmt.Printf("[%s] %s: %s\n", status, c.name, c.detail)
+		if c.hint != "" && (c.failed || c.warn) {
+			fmt.Printf("       hint: %s\n", c.hint)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func checkExecutable() check {
+	c := check{name: "executable"}
+	self, err := os.Executable()
+	if err != nil {
+		c.detail = err.Error()
+		c.warn = true
+		return c
+	}
+	self, _ = filepath.EvalSymlinks(self)
+	c.detail = self
+
+	found, err := exec.LookPath("listme")
+	if err != nil {
+		c.warn = true
+		c.detail += " is not in your PATH"
+		c.hint = "add the directory of the executable, e.g. $HOME/go/bin for go install, to the PATH of your shell profile (see SETUP.md)"
+		return c
+	}
+	if found, _ = filepath.EvalSymlinks(found); found != self {
+		c.warn = true
+		c.detail += ", but listme in your PATH is " + found
+		c.hint = "remove the outdated copy or reorder your PATH"
+	}
+	return c
+}
+
+func checkGit() check {
+	c := check{name: "git"}
+	out, err := exec.Command("git", "--version").Output()
+	if err != nil {
+		c.failed = true
+		c.detail = "not found"
+		c.hint = "install git (https://git-scm.com/) and add it to your PATH, or use --no-git to search without author information"
+		return c
+	}
+	c.detail = strings.TrimSpace(string(out))
+	return c
+}
+
+func checkRepository(path string) check {
+	c := check{name: "repository"}
+	dir := path
+	if info, err := os.Stat(path); err != nil {
+		c.failed = true
+		c.detail = err.Error()
+		return c
+	} else if !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	root, err := matcher.RepoRoot(dir)
+	if err != nil {
+		c.warn = true
+		c.detail = "no git repository found in " + path + " or its parents"
+		c.hint = "author and age information is only available inside git repositories"
+		return c
+	}
+	c.detail = root
+	if _, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output(); err != nil {
+		c.warn = true
+		c.detail += " has no commits"
+		c.hint = "lines are blamed as not committed yet until the first commit"
+	}
+	return c
+}
+
+func checkTerminal() check {
+	c := check{name: "terminal"}
+	info, err := os.Stdout.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		c.warn = true
+		c.detail = "stdout is not a terminal, the plain style is used"
+		c.hint = "run listme doctor directly in your terminal to check its capabilities"
+		return c
+	}
+
+	colors := "16 colors"
+	term := os.Getenv("TERM")
+	colorTerm := strings.ToLower(os.Getenv("COLORTERM"))
+	switch {
+	case os.Getenv("NO_COLOR") != "":
+		colors = "colors disabled by NO_COLOR"
+	case term == "dumb":
+		colors = "no colors (TERM=dumb)"
+		c.warn = true
+		c.hint = "set TERM to your terminal type, e.g. xterm-256color, or use the black and white style (-b)"
+	case colorTerm == "truecolor" || colorTerm == "24bit":
+		colors = "true color"
+	case strings.Contains(term, "256color"):
+		colors = "256 colors"
+	}
+	c.detail = fmt.Sprintf("TERM=%s, %s", term, colors)
+	return c
+}
+
+func checkTerminalWidth() check {
+	c := check{name: "terminal width"}
+	s, err := tsize.GetSize()
+	if err != nil {
+		c.warn = true
+		c.detail = "unknown, the output uses a default width"
+		return c
+	}
+	c.detail = fmt.Sprintf("%d columns", s.Width)
+	if s.Width < minTerminalWidth {
+		c.warn = true
+		c.hint = fmt.Sprintf("the output gets cramped in terminals narrower than %d columns, widen the window or use the plain style (-p)", minTerminalWidth)
+	}
+	return c
+}
+
+func checkConfig(path string) check {
+	c := check{name: "config"}
+	configPath, ok := config.Find(path)
+	if !ok {
+		c.detail = "no configuration file, using the defaults"
+		return c
+	}
+	c.detail = relPath(configPath)
+	cfg, err := config.Parse(configPath)
+	if err == nil {
+		err = validateConfig(cfg)
+	}
+	if err != nil {
+		c.failed = true
+		c.detail += ": " + err.Error()
+		c.hint = "fix the file or run listme init --force to write a new one"
+	}
+	return c
+}
+
+// validateConfig checks the settings that are otherwise only validated when used.
+func validateConfig(cfg *config.Config) error {
+	if cfg.Locale != "" {
+		if err := i18n.SetLocale(cfg.Locale); err != nil {
+			return err
+		}
+	}
+	for _, tier := range cfg.AgeTiers {
+		if tier.Label == "" || tier.Days < 0 {
+			return fmt.Errorf("invalid age tier: a label and a non-negative number of days are required")
+		}
+	}
+	for ext, rule := range cfg.DocumentationRules {
+		if rule != search.ProseRule && rule != search.CommentRule {
+			return fmt.Errorf("invalid documentation rule %q for %s, use %s or %s", rule, ext, search.ProseRule, search.CommentRule)
+		}
+	}
+	for ext, tags := range cfg.ExtensionTags {
+		if slices.Contains(tags, "") {
+			return fmt.Errorf("empty tag in extension_tags for %s", ext)
+		}
+	}
+	for ext, size := range cfg.MaxFileSizes {
+		if size <= 0 {
+			return fmt.Errorf("invalid max_file_sizes: the size of %s files must be a positive integer", ext)
+		}
+	}
+	if err := validateTags(cfg.FailOn); err != nil {
+		return fmt.Errorf("invalid fail_on: %s", err)
+	}
+	return validateConfigDefaults(cfg)
+}
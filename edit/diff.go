@@ -0,0 +1,216 @@
+package edit
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// number of unchanged lines shown around each change in a unified diff
+const diffContext = 3
+
+// FileEdit is a planned change to a file: Old holds the current lines and New the lines
+// that will be written. Both have line endings removed.
+type FileEdit struct {
+	Path string
+	Old  []string
+	New  []string
+}
+
+// Apply writes the new lines to the file. It fails if the file changed since the edit was planned.
+func (e *FileEdit) Apply() error {
+	lines, err := readLines(e.Path)
+	if err != nil {
+		return err
+	}
+	if !equalLines(lines, e.Old) {
+		return fmt.Errorf("%s changed since the edit was planned", e.Path)
+	}
+	return writeLines(e.Path, e.New)
+}
+
+// Changed reports whether the edit changes the file.
+func (e *FileEdit) Changed() bool {
+	return !equalLines(e.Old, e.New)
+}
+
+// Diff returns the edit as a unified diff. The name is used in the file headers, with
+// the a/ and b/ prefixes of git diff, unless it's an absolute path.
+func (e *FileEdit) Diff(name string) string {
+	ops := diffLines(e.Old, e.New)
+	hunks := groupHunks(ops)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if filepath.IsAbs(name) {
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", name, name)
+	} else {
+		fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", name, name)
+	}
+	for _, h := range hunks {
+		oldStart, newStart := h[0].oldLine, h[0].newLine
+		var oldCount, newCount int
+		for _, op := range h {
+			if op.kind != opInsert {
+				oldCount++
+			}
+			if op.kind != opDelete {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range h {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.text)
+		}
+	}
+	return b.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// an empty range refers to the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const (
+	opEqual  = ' '
+	opDelete = '-'
+	opInsert = '+'
+)
+
+// diffOp is a single line of a diff. oldLine and newLine are the 1-based
+// positions of the line in each file, or of the next line if it's absent there.
+type diffOp struct {
+	kind    byte
+	text    string
+	oldLine int
+	newLine int
+}
+
+// diffLines returns the shortest edit script between a and b using the Myers algorithm.
+// Edits made by listme touch few lines, so the number of differences is small.
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// backtrack from the end to recover the script
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: opEqual, text: a[x], oldLine: x + 1, newLine: y + 1})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, diffOp{kind: opInsert, text: b[y], oldLine: x + 1, newLine: y + 1})
+		} else {
+			x--
+			ops = append(ops, diffOp{kind: opDelete, text: a[x], oldLine: x + 1, newLine: y + 1})
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// groupHunks splits the script into hunks of changes surrounded by up to diffContext unchanged lines.
+func groupHunks(ops []diffOp) [][]diffOp {
+	var hunks [][]diffOp
+	i := 0
+	for i < len(ops) {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		// extend the hunk while the next change is close enough to share context
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == opEqual {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContext {
+				break
+			}
+			end = next
+		}
+		stop := end + diffContext
+		if stop > len(ops) {
+			stop = len(ops)
+		}
+		hunks = append(hunks, ops[start:stop])
+		i = stop
+	}
+	return hunks
+}
@@ -0,0 +1,41 @@
+package edit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := strings.Split("a b c d e f g h i j k", " ")
+	new := strings.Split("a B c d e f g h i k l", " ")
+	e := &FileEdit{Path: "f.txt", Old: old, New: new}
+
+	expected := `--- a/f.txt
++++ b/f.txt
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -7,5 +7,5 @@
+ g
+ h
+ i
+-j
+ k
++l
+`
+	if diff := e.Diff("f.txt"); diff != expected {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+
+	if diff := e.Diff("/tmp/f.txt"); !strings.HasPrefix(diff, "--- /tmp/f.txt\n+++ /tmp/f.txt\n@@") {
+		t.Errorf("expected the absolute path without prefixes, got:\n%s", diff)
+	}
+
+	if diff := (&FileEdit{Old: old, New: old}).Diff("f.txt"); diff != "" {
+		t.Errorf("expected empty diff for unchanged lines, got:\n%s", diff)
+	}
+}
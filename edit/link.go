@@ -0,0 +1,136 @@
+package edit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Link is the reference to an issue added to the tag found in a 1-based line of a file.
+// Column is the 1-based column where the tag starts, in characters, as reported by the
+// search. If it's zero, the first occurrence of the tag in the line is used.
+type Link struct {
+	Line   int
+	Column int
+	Tag    string
+	Ref    string
+}
+
+// issueRefRegex matches an issue reference: #123, owner/repo#123 or PROJ-123.
+var issueRefRegex = regexp.MustCompile(`^(?:[\w.-]+/[\w.-]+)?#\d+$|^[A-Z][A-Z0-9]+-\d+$`)
+
+// linkRegex returns a regex matching the tag as a separate word, capturing
+// the parentheses right after it, if any.
+func linkRegex(tag string) *regexp.Regexp {
+	return regexp.MustCompile(`\b(` + regexp.QuoteMeta(tag) + `)\b(\([^)]*\))?`)
+}
+
+// findTag returns the submatch indexes of linkRegex for the tag starting at the column
+// of the line, or for its first occurrence if column is zero. It returns nil if the tag
+// isn't there.
+func findTag(line string, column int, tag string) []int {
+	if column <= 0 {
+		return linkRegex(tag).FindStringSubmatchIndex(line)
+	}
+	start := columnOffset(line, column)
+	if start < 0 {
+		return nil
+	}
+	match := regexp.MustCompile(`^(` + regexp.QuoteMeta(tag) + `)(\([^)]*\))?`).FindStringSubmatchIndex(line[start:])
+	for i := range match {
+		if match[i] >= 0 {
+			match[i] += start
+		}
+	}
+	return match
+}
+
+// columnOffset returns the byte offset of the 1-based column of the line, in characters,
+// or -1 if the line is shorter.
+func columnOffset(line string, column int) int {
+	for i := range line {
+		if column--; column == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// references returns the issue references in the parentheses following a tag, e.g.
+// [#123] for TODO(alice, #123). Anything else in the parentheses, such as assignees,
+// is ignored, and so are references elsewhere in the comment.
+func references(parens string) []string {
+	var refs []string
+	for _, item := range strings.Split(strings.Trim(parens, "()"), ",") {
+		if item = strings.TrimSpace(item); issueRefRegex.MatchString(item) {
+			refs = append(refs, item)
+		}
+	}
+	return refs
+}
+
+// Linked reports whether the tag starting at the column of the provided line of the
+// file is already followed by an issue reference in parentheses, as in TODO(#123).
+// Issue numbers elsewhere in the comment, e.g. "TODO: see #123" or in a URL, don't count.
+func Linked(path string, line, column int, tag string) (bool, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return false, err
+	}
+	idx := line - 1
+	if idx < 0 || idx >= len(lines) {
+		return false, fmt.Errorf("line %d out of range, %s has %d lines", line, path, len(lines))
+	}
+	match := findTag(lines[idx], column, tag)
+	if match == nil {
+		return false, fmt.Errorf("tag %s not found in %s:%d", tag, path, line)
+	}
+	return match[4] >= 0 && len(references(lines[idx][match[4]:match[5]])) > 0, nil
+}
+
+// PlanLinks returns the edit appending the references to the tag in each line,
+// turning "TODO: text" into "TODO(#123): text" and "TODO(alice): text" into
+// "TODO(alice, #123): text". Tags that already have a reference are left unchanged.
+func PlanLinks(path string, links []Link) (*FileEdit, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	newLines := make([]string, len(lines))
+	copy(newLines, lines)
+
+	for _, l := range links {
+		idx := l.Line - 1
+		if idx < 0 || idx >= len(lines) {
+			return nil, fmt.Errorf("line %d out of range, %s has %d lines", l.Line, path, len(lines))
+		}
+		line := newLines[idx]
+		match := findTag(line, l.Column, l.Tag)
+		if match == nil {
+			return nil, fmt.Errorf("tag %s not found in %s:%d", l.Tag, path, l.Line)
+		}
+		switch {
+		case match[4] < 0:
+			newLines[idx] = line[:match[3]] + "(" + l.Ref + ")" + line[match[3]:]
+		case strings.TrimSpace(line[match[4]+1:match[5]-1]) == "":
+			newLines[idx] = line[:match[4]] + "(" + l.Ref + ")" + line[match[5]:]
+		case len(references(line[match[4]:match[5]])) == 0:
+			end := match[5] - 1
+			newLines[idx] = line[:end] + ", " + l.Ref + line[end:]
+		}
+	}
+	return &FileEdit{Path: path, Old: lines, New: newLines}, nil
+}
+
+// LinkIssue appends the reference to the tag starting at the column of the provided line
+// of the file. It returns false without changing the file if the tag already has a reference.
+func LinkIssue(path string, line, column int, tag, ref string) (bool, error) {
+	e, err := PlanLinks(path, []Link{{Line: line, Column: column, Tag: tag, Ref: ref}})
+	if err != nil {
+		return false, err
+	}
+	if !e.Changed() {
+		return false, nil
+	}
+	return true, e.Apply()
+}
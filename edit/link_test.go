@@ -0,0 +1,70 @@
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinked(t *testing.T) {
+	tests := []struct {
+		line     string
+		column   int
+		expected bool
+	}{
+		{"# TODO(#123): fix", 3, true},
+		{"# TODO(mathpn/listme#123): fix", 3, true},
+		{"# TODO(PROJ-42): fix", 3, true},
+		{"# TODO(alice, #7): fix", 3, true},
+		{"# TODO: fix", 3, false},
+		{"# TODO(alice): fix", 3, false},
+		// issue numbers outside of the parentheses of the tag aren't references
+		{"# TODO: fix #123", 3, false},
+		{"# TODO: see https://github.com/mathpn/listme/issues/123", 3, false},
+		{"# TODO: see (https://example.com/#123)", 3, false},
+		{"# TODO(https://example.com/#123): fix", 3, false},
+		{"x = 'TODO(#1)'  # TODO: fix", 19, false},
+		{"x = 'é'  # TODO(#1): fix", 12, true},
+	}
+	path := filepath.Join(t.TempDir(), "code.py")
+	for _, tt := range tests {
+		if err := os.WriteFile(path, []byte(tt.line+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		linked, err := Linked(path, 1, tt.column, "TODO")
+		if err != nil {
+			t.Errorf("Linked(%q): %s", tt.line, err)
+			continue
+		}
+		if linked != tt.expected {
+			t.Errorf("Linked(%q) = %v, expected %v", tt.line, linked, tt.expected)
+		}
+	}
+
+	if _, err := Linked(path, 1, 1, "TODO"); err == nil {
+		t.Error("expected error for a column where the tag doesn't start")
+	}
+}
+
+func TestPlanLinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "code.py")
+	content := "# TODO: fix #9\n# TODO(alice): fix\n# TODO(#1): fix\nx = 'TODO'  # TODO: fix\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	e, err := PlanLinks(path, []Link{
+		{Line: 1, Column: 3, Tag: "TODO", Ref: "#2"},
+		{Line: 2, Column: 3, Tag: "TODO", Ref: "#3"},
+		{Line: 3, Column: 3, Tag: "TODO", Ref: "#4"},
+		{Line: 4, Column: 15, Tag: "TODO", Ref: "#5"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"# TODO(#2): fix #9", "# TODO(alice, #3): fix", "# TODO(#1): fix", "x = 'TODO'  # TODO(#5): fix"}
+	for i, line := range expected {
+		if e.New[i] != line {
+			t.Errorf("line %d: got %q, expected %q", i+1, e.New[i], line)
+		}
+	}
+}
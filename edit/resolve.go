@@ -0,0 +1,288 @@
+package edit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// commentMarkerRegex captures the comment marker at the beginning of a line.
+var commentMarkerRegex = regexp.MustCompile(`^\s*(#+|//+|--|;+|\*+|/\*+|<!--|"""|''')`)
+
+// trailingMarkerRegex matches the comment marker right before a tag.
+var trailingMarkerRegex = regexp.MustCompile(`(?:#+|//+|--|;+|/\*+|<!--|"""|''')\s*$`)
+
+// blockClosers are the closers of comments that can be followed by more code in the same line.
+var blockClosers = map[string]string{"/*": "*/", "<!--": "-->"}
+
+// Resolution describes the lines removed from a file by Resolve.
+// It's stored to allow undoing the last resolution.
+//   - Path: absolute path of the file, so the resolution can be undone from any directory
+//   - Line: 1-based number of the first removed line
+//   - Lines: content of the removed lines
+//   - Keep: code preceding a trailing comment, which replaces the line instead of removing it
+//   - Before, After: lines around the removed ones, nil at the start or end of the file,
+//     recorded by Apply so Undo only restores the lines where they were removed
+type Resolution struct {
+	Path   string   `json:"path"`
+	Line   int      `json:"line"`
+	Lines  []string `json:"lines"`
+	Keep   string   `json:"keep,omitempty"`
+	Before *string  `json:"before,omitempty"`
+	After  *string  `json:"after,omitempty"`
+}
+
+// PlanResolve returns the lines that would be removed to resolve the tagged comment
+// in the provided line of the file. The line must match tagRegex.
+//
+// If block is true, the whole comment block is removed: adjacent lines starting with the
+// same comment marker, stopping at blank lines and at lines with other tags.
+//
+// If code precedes the comment, as in x = compute()  # TODO: optimize, only the comment
+// is removed and the code is kept (see Resolution.Keep). Comments followed by more code,
+// as in f(/* TODO */ x), can't be resolved.
+func PlanResolve(path string, line int, tagRegex *regexp.Regexp, block bool) (*Resolution, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	idx := line - 1
+	if idx < 0 || idx >= len(lines) {
+		return nil, fmt.Errorf("line %d out of range, %s has %d lines", line, path, len(lines))
+	}
+	match := tagRegex.FindStringSubmatchIndex(lines[idx])
+	if match == nil {
+		return nil, fmt.Errorf("no tagged comment found in %s:%d", path, line)
+	}
+
+	keep, err := trailingCode(lines[idx], match[2])
+	if err != nil {
+		return nil, fmt.Errorf("can't resolve %s:%d: %s", path, line, err)
+	}
+	if keep != "" {
+		return &Resolution{Path: path, Line: line, Lines: []string{lines[idx]}, Keep: keep}, nil
+	}
+
+	start, end := idx, idx+1
+	if block {
+		marker := commentMarker(lines[idx])
+		if marker != "" {
+			for start > 0 && inBlock(lines[start-1], marker, tagRegex) {
+				start--
+			}
+			for end < len(lines) && inBlock(lines[end], marker, tagRegex) {
+				end++
+			}
+		}
+	}
+
+	removed := make([]string, end-start)
+	copy(removed, lines[start:end])
+	return &Resolution{Path: path, Line: start + 1, Lines: removed}, nil
+}
+
+// trailingCode returns the code preceding the comment of the tag starting at tagStart,
+// without trailing spaces, or an empty string if the comment takes the whole line.
+// It fails if the comment is followed by more code.
+func trailingCode(line string, tagStart int) (string, error) {
+	loc := trailingMarkerRegex.FindStringIndex(line[:tagStart])
+	if loc == nil {
+		return "", nil
+	}
+	code := strings.TrimRight(line[:loc[0]], " \t")
+	if strings.TrimSpace(code) == "" {
+		return "", nil
+	}
+	marker := strings.TrimRight(line[loc[0]:loc[1]], " \t")
+	for opener, closer := range blockClosers {
+		if !strings.HasPrefix(marker, opener) {
+			continue
+		}
+		if end := strings.Index(line[tagStart:], closer); end >= 0 && strings.TrimSpace(line[tagStart+end+len(closer):]) != "" {
+			return "", fmt.Errorf("the comment is followed by code")
+		}
+	}
+	return code, nil
+}
+
+func commentMarker(line string) string {
+	match := commentMarkerRegex.FindStringSubmatch(line)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func inBlock(line, marker string, tagRegex *regexp.Regexp) bool {
+	if strings.TrimSpace(line) == "" || tagRegex.MatchString(line) {
+		return false
+	}
+	return commentMarker(line) == marker
+}
+
+// Edit returns the resolution as an edit of the file, used to preview it.
+func (r *Resolution) Edit() (*FileEdit, error) {
+	lines, err := readLines(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	start := r.Line - 1
+	end := start + len(r.Lines)
+	if start < 0 || end > len(lines) {
+		return nil, fmt.Errorf("%s changed since the resolution was planned", r.Path)
+	}
+	newLines := make([]string, 0, len(lines)-len(r.Lines)+1)
+	newLines = append(newLines, lines[:start]...)
+	newLines = append(newLines, r.kept()...)
+	newLines = append(newLines, lines[end:]...)
+	return &FileEdit{Path: r.Path, Old: lines, New: newLines}, nil
+}
+
+// Apply removes the lines of the resolution from the file and saves it so it can be undone.
+func (r *Resolution) Apply() error {
+	lines, err := readLines(r.Path)
+	if err != nil {
+		return err
+	}
+	start := r.Line - 1
+	end := start + len(r.Lines)
+	if end > len(lines) {
+		return fmt.Errorf("%s changed since the resolution was planned", r.Path)
+	}
+	for i, line := range r.Lines {
+		if lines[start+i] != line {
+			return fmt.Errorf("%s changed since the resolution was planned", r.Path)
+		}
+	}
+
+	r.Before, r.After = nil, nil
+	if start > 0 {
+		before := lines[start-1]
+		r.Before = &before
+	}
+	if end < len(lines) {
+		after := lines[end]
+		r.After = &after
+	}
+	lines = append(lines[:start], append(r.kept(), lines[end:]...)...)
+	if err := writeLines(r.Path, lines); err != nil {
+		return err
+	}
+	return saveUndo(r)
+}
+
+// Undo restores the lines removed by the last applied resolution.
+func Undo() (*Resolution, error) {
+	r, err := loadUndo()
+	if err != nil {
+		return nil, err
+	}
+	lines, err := readLines(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	start := r.Line - 1
+	end := start + len(r.kept())
+	if end > len(lines) {
+		return nil, fmt.Errorf("can't restore line %d, %s has %d lines", r.Line, r.Path, len(lines))
+	}
+	if (r.Keep != "" && lines[start] != r.Keep) || !r.sameContext(lines, start, end) {
+		return nil, fmt.Errorf("can't restore line %d, %s changed since the resolution", r.Line, r.Path)
+	}
+
+	restored := make([]string, 0, len(lines)+len(r.Lines))
+	restored = append(restored, lines[:start]...)
+	restored = append(restored, r.Lines...)
+	restored = append(restored, lines[end:]...)
+	if err := writeLines(r.Path, restored); err != nil {
+		return nil, err
+	}
+	return r, os.Remove(undoPath())
+}
+
+// sameContext reports whether the lines before start and from end are the ones that
+// surrounded the removed lines when the resolution was applied.
+func (r *Resolution) sameContext(lines []string, start, end int) bool {
+	if (r.Before != nil) != (start > 0) || (r.Before != nil && lines[start-1] != *r.Before) {
+		return false
+	}
+	return (r.After != nil) == (end < len(lines)) && (r.After == nil || lines[end] == *r.After)
+}
+
+// kept returns the lines that replace the removed ones: the code preceding a trailing comment, if any.
+func (r *Resolution) kept() []string {
+	if r.Keep == "" {
+		return nil
+	}
+	return []string{r.Keep}
+}
+
+// readLines returns the lines of the file, with line endings removed.
+// A trailing newline doesn't produce an empty last line.
+func readLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimSuffix(string(data), "\n")
+	if content == "" {
+		return []string{}, nil
+	}
+	return strings.Split(content, "\n"), nil
+}
+
+// writeLines writes the lines to the file, terminating each one with a newline
+// and keeping the file permissions.
+func writeLines(path string, lines []string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	content := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		content += "\n"
+	}
+	return os.WriteFile(path, []byte(content), info.Mode().Perm())
+}
+
+func undoPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "listme", "undo.json")
+}
+
+func saveUndo(r *Resolution) error {
+	path := undoPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to save undo information: %s", err)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func loadUndo() (*Resolution, error) {
+	data, err := os.ReadFile(undoPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("nothing to undo")
+		}
+		return nil, err
+	}
+	r := &Resolution{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("invalid undo information: %s", err)
+	}
+	return r, nil
+}
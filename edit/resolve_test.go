@@ -0,0 +1,145 @@
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var todoRegex = regexp.MustCompile(`(?:#|//)\s*(TODO|FIXME)\b`)
+
+func TestPlanResolveBlock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "code.py")
+	content := "x = 1\n# TODO: fix this\n# more context\n# FIXME: other\ny = 2\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := PlanResolve(path, 2, todoRegex, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Line != 2 || len(r.Lines) != 1 {
+		t.Errorf("single line: expected line 2 with 1 line, got line %d with %d lines", r.Line, len(r.Lines))
+	}
+
+	r, err = PlanResolve(path, 2, todoRegex, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Line != 2 || len(r.Lines) != 2 || r.Lines[1] != "# more context" {
+		t.Errorf("block must stop before the next tag, got line %d with %q", r.Line, r.Lines)
+	}
+
+	if _, err := PlanResolve(path, 1, todoRegex, false); err == nil {
+		t.Error("expected error for a line without tags")
+	}
+}
+
+func TestPlanResolveTrailingComment(t *testing.T) {
+	// the undo information is saved in the user cache directory
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "code.py")
+	content := "x = compute()  # TODO: optimize\ny = 2\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := PlanResolve(path, 1, todoRegex, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Keep != "x = compute()" || len(r.Lines) != 1 {
+		t.Fatalf("expected the code to be kept, got %q removing %q", r.Keep, r.Lines)
+	}
+	if err := r.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "x = compute()\ny = 2\n" {
+		t.Errorf("only the comment must be removed, got %q", got)
+	}
+
+	if _, err := Undo(); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != content {
+		t.Errorf("undo must restore the comment, got %q", got)
+	}
+
+	blockRegex := regexp.MustCompile(`/\*\s*(TODO)\b`)
+	if err := os.WriteFile(path, []byte("f(/* TODO */ x)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PlanResolve(path, 1, blockRegex, false); err == nil {
+		t.Error("expected error for a comment followed by code")
+	}
+}
+
+func TestUndo(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	content := "x = 1\n# TODO: fix this\ny = 2\n"
+	if err := os.WriteFile(filepath.Join(dir, "code.py"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// the path is relative to the directory of the resolution, not of the undo
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	r, err := PlanResolve("code.py", 2, todoRegex, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Undo(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "code.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != content {
+		t.Errorf("undo must restore the comment, got %q", got)
+	}
+
+	// the lines around the removed ones changed, so they're not restored
+	r, err = PlanResolve(filepath.Join(dir, "code.py"), 2, todoRegex, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	changed := "x = 1\nz = 3\ny = 2\n"
+	if err := os.WriteFile(filepath.Join(dir, "code.py"), []byte(changed), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Undo(); err == nil {
+		t.Error("expected an error for a file changed since the resolution")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "code.py")); err != nil || string(data) != changed {
+		t.Errorf("the changed file must be left as is, got %q", data)
+	}
+}
@@ -0,0 +1,110 @@
+package edit
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var tagNameRegex = regexp.MustCompile(`^\w+$`)
+
+// TagMap maps tags to their replacements. Sources are matched literally,
+// so they may end with punctuation, e.g. TODO! maps to BUG.
+type TagMap map[string]string
+
+// ParseTagMap parses mappings with the format FROM=TO.
+func ParseTagMap(mappings []string) (TagMap, error) {
+	m := make(TagMap, len(mappings))
+	for _, mapping := range mappings {
+		from, to, ok := strings.Cut(mapping, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mapping %q, expected FROM=TO", mapping)
+		}
+		if from == "" || strings.ContainsAny(from, " \t") || !tagNameRegex.MatchString(from[:1]) {
+			return nil, fmt.Errorf("invalid source tag %q in mapping %q", from, mapping)
+		}
+		if !tagNameRegex.MatchString(to) {
+			return nil, fmt.Errorf("invalid target tag %q in mapping %q", to, mapping)
+		}
+		m[from] = to
+	}
+	return m, nil
+}
+
+// Sources returns the source tags, longest first, so TODO! is preferred over TODO.
+func (m TagMap) Sources() []string {
+	sources := make([]string, 0, len(m))
+	for from := range m {
+		sources = append(sources, from)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if len(sources[i]) != len(sources[j]) {
+			return len(sources[i]) > len(sources[j])
+		}
+		return sources[i] < sources[j]
+	})
+	return sources
+}
+
+// Rename is the renaming of a tag found in a 1-based line of a file, starting at a
+// 1-based column, in characters, or anywhere in the line if Column is zero.
+type Rename struct {
+	Line   int
+	Column int
+	From   string
+	To     string
+}
+
+// PlanRewrite returns the edit applying the renames to the file. The source tag is
+// replaced at the column of the rename, so the same word elsewhere in the line, e.g.
+// in the code before the comment, is left as is. Without a column, the first occurrence
+// of the source tag as a separate word is replaced.
+func PlanRewrite(path string, renames []Rename) (*FileEdit, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	newLines := make([]string, len(lines))
+	copy(newLines, lines)
+
+	for _, r := range renames {
+		idx := r.Line - 1
+		if idx < 0 || idx >= len(lines) {
+			return nil, fmt.Errorf("line %d out of range, %s has %d lines", r.Line, path, len(lines))
+		}
+		loc := findSource(newLines[idx], r.Column, r.From)
+		if loc == nil {
+			return nil, fmt.Errorf("tag %s not found in %s:%d", r.From, path, r.Line)
+		}
+		newLines[idx] = newLines[idx][:loc[0]] + r.To + newLines[idx][loc[1]:]
+	}
+	return &FileEdit{Path: path, Old: lines, New: newLines}, nil
+}
+
+// findSource returns the location of the source tag starting at the column of the line,
+// or of its first occurrence if column is zero. It returns nil if the tag isn't there.
+func findSource(line string, column int, tag string) []int {
+	if column <= 0 {
+		return sourceRegex(tag).FindStringIndex(line)
+	}
+	start := columnOffset(line, column)
+	if start < 0 {
+		return nil
+	}
+	loc := sourceRegex(tag).FindStringIndex(line[start:])
+	if loc == nil || loc[0] != 0 {
+		return nil
+	}
+	return []int{start, start + loc[1]}
+}
+
+// sourceRegex matches the tag as a separate word. The end of the tag is only
+// required to be a word boundary if the tag ends with a word character.
+func sourceRegex(tag string) *regexp.Regexp {
+	expr := `\b` + regexp.QuoteMeta(tag)
+	if tagNameRegex.MatchString(tag[len(tag)-1:]) {
+		expr += `\b`
+	}
+	return regexp.MustCompile(expr)
+}
@@ -0,0 +1,44 @@
+package edit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanRewrite(t *testing.T) {
+	tests := []struct {
+		line     string
+		rename   Rename
+		expected string
+	}{
+		{"# XXX: fix this", Rename{Line: 1, Column: 3, From: "XXX", To: "FIXME"}, "# FIXME: fix this"},
+		// the tag is also written in the code before the comment
+		{`print("XXX")  # XXX: fix this`, Rename{Line: 1, Column: 17, From: "XXX", To: "FIXME"}, `print("XXX")  # FIXME: fix this`},
+		{`s = "é XXX"  # XXX: fix`, Rename{Line: 1, Column: 16, From: "XXX", To: "FIXME"}, `s = "é XXX"  # FIXME: fix`},
+		{"# Todo: fix", Rename{Line: 1, Column: 3, From: "Todo", To: "TODO"}, "# TODO: fix"},
+		// without a column, the first occurrence is renamed
+		{"# XXX: fix this", Rename{Line: 1, From: "XXX", To: "FIXME"}, "# FIXME: fix this"},
+	}
+	path := filepath.Join(t.TempDir(), "code.py")
+	for _, tt := range tests {
+		if err := os.WriteFile(path, []byte(tt.line+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		e, err := PlanRewrite(path, []Rename{tt.rename})
+		if err != nil {
+			t.Errorf("PlanRewrite(%q): %s", tt.line, err)
+			continue
+		}
+		if got := e.New[0]; got != tt.expected {
+			t.Errorf("PlanRewrite(%q) = %q, expected %q", tt.line, got, tt.expected)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(`print("XXX")  # XXX: fix this`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PlanRewrite(path, []Rename{{Line: 1, Column: 5, From: "XXX", To: "FIXME"}}); err == nil {
+		t.Error("expected an error for a tag that isn't at the column")
+	}
+}
@@ -1,13 +1,15 @@
 module github.com/mathpn/listme
 
-go 1.20
+go 1.21
 
 require (
 	github.com/akamensky/argparse v1.4.0
 	github.com/charmbracelet/lipgloss v1.0.0
 	github.com/kopoli/go-terminal-size v0.0.0-20170219200355-5c97524c8b54
-	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7
+	github.com/libgit2/git2go/v34 v34.0.0
+	github.com/mattn/go-runewidth v0.0.16
 	github.com/sabhiram/go-gitignore v0.0.0-20210923224102-525f6e181f06
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -16,9 +18,8 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/muesli/termenv v0.15.2 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
+	golang.org/x/crypto v0.0.0-20201203163018-be400aefbc4c // indirect
 	golang.org/x/sys v0.28.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/heatmap"
+	"github.com/mathpn/listme/search"
+)
+
+func runHeatmap(args []string) {
+	parser := argparse.NewParser("listme heatmap", "Render a treemap of tagged comments per directory, colored by their age, as an HTML page or SVG image.")
+	flags := addSearchFlags(parser)
+	svg := parser.Flag("", "svg", &argparse.Options{Help: "Print a bare SVG image instead of an HTML page"})
+	width := parser.Int("", "width", &argparse.Options{Default: 1200, Help: "Width of the treemap in pixels"})
+	height := parser.Int("", "height", &argparse.Options{Default: 800, Help: "Height of the treemap in pixels"})
+	parseArgs(parser, args)
+
+	if *width <= 0 || *height <= 0 {
+		fatal(fmt.Errorf("the size of the treemap must be positive"))
+	}
+
+	var matches []search.JSONMatch
+	opts := flags.options()
+	opts.Quiet = true
+	opts.Collect = func(m search.JSONMatch) {
+		matches = append(matches, m)
+	}
+	params, err := search.NewSearchParams(opts)
+	if err != nil {
+		fatal(err)
+	}
+	requireComplete(search.Search(params))
+
+	cells := heatmap.Aggregate(matches, time.Now())
+	if *svg {
+		err = heatmap.RenderSVG(os.Stdout, cells, *width, *height)
+	} else {
+		err = heatmap.RenderHTML(os.Stdout, opts.Path, cells, *width, *height)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
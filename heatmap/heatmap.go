@@ -0,0 +1,213 @@
+// Package heatmap renders the density of tagged comments per directory as a treemap.
+package heatmap
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/mathpn/listme/search"
+)
+
+// Cell is the aggregate of the tagged comments of a directory.
+//   - Count: number of tagged comments, which sets the area of the cell
+//   - Score: comments weighted by age, each one counting 1 plus its age in years,
+//     which sets the color of the cell
+type Cell struct {
+	Dir   string
+	Count int
+	Score float64
+}
+
+// Aggregate groups the matches by directory, from the most commented one.
+// Matches without a commit date count as new.
+func Aggregate(matches []search.JSONMatch, now time.Time) []Cell {
+	cells := make(map[string]*Cell)
+	for _, m := range matches {
+		dir := path.Dir(m.Path)
+		c, ok := cells[dir]
+		if !ok {
+			c = &Cell{Dir: dir}
+			cells[dir] = c
+		}
+		c.Count++
+		c.Score += ageWeight(m.Date, now)
+	}
+
+	out := make([]Cell, 0, len(cells))
+	for _, c := range cells {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Dir < out[j].Dir
+	})
+	return out
+}
+
+func ageWeight(date *time.Time, now time.Time) float64 {
+	if date == nil || date.After(now) {
+		return 1
+	}
+	return 1 + now.Sub(*date).Hours()/24/365
+}
+
+// Rect is a rectangle of the treemap.
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Layout splits the rectangle into one rectangle per value, with areas proportional
+// to the values, using the squarified treemap algorithm so cells stay close to squares.
+// Values must be sorted in decreasing order.
+func Layout(values []float64, r Rect) []Rect {
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	rects := make([]Rect, 0, len(values))
+	if total <= 0 {
+		return rects
+	}
+	scale := r.W * r.H / total
+	areas := make([]float64, len(values))
+	for i, v := range values {
+		areas[i] = v * scale
+	}
+
+	for len(areas) > 0 {
+		side := r.W
+		if r.H < side {
+			side = r.H
+		}
+		// grow the row while it improves the worst aspect ratio
+		n := 1
+		for n < len(areas) && worst(areas[:n+1], side) <= worst(areas[:n], side) {
+			n++
+		}
+		row := areas[:n]
+		areas = areas[n:]
+
+		var sum float64
+		for _, a := range row {
+			sum += a
+		}
+		if r.W >= r.H {
+			// the row is a column on the left side
+			w := sum / r.H
+			y := r.Y
+			for _, a := range row {
+				h := a / w
+				rects = append(rects, Rect{X: r.X, Y: y, W: w, H: h})
+				y += h
+			}
+			r.X += w
+			r.W -= w
+		} else {
+			// the row is a strip on the top side
+			h := sum / r.W
+			x := r.X
+			for _, a := range row {
+				w := a / h
+				rects = append(rects, Rect{X: x, Y: r.Y, W: w, H: h})
+				x += w
+			}
+			r.Y += h
+			r.H -= h
+		}
+	}
+	return rects
+}
+
+// worst returns the largest aspect ratio of the row laid out along a side of the given length.
+func worst(row []float64, side float64) float64 {
+	var sum, min, max float64
+	min = row[0]
+	for _, a := range row {
+		sum += a
+		if a < min {
+			min = a
+		}
+		if a > max {
+			max = a
+		}
+	}
+	s2 := side * side
+	return maxFloat(s2*max/(sum*sum), sum*sum/(s2*min))
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// heat returns a color from light yellow to dark red as the fraction goes from 0 to 1.
+func heat(fraction float64) string {
+	from := [3]float64{0xfe, 0xe0, 0x8b}
+	to := [3]float64{0xb2, 0x18, 0x2b}
+	var c [3]int
+	for i := range c {
+		c[i] = int(from[i] + (to[i]-from[i])*fraction)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
+
+// RenderSVG writes the treemap of the cells as an SVG image. The area of each cell is
+// proportional to its number of comments and the color to its score, relative to the hottest one.
+func RenderSVG(w io.Writer, cells []Cell, width, height int) error {
+	values := make([]float64, len(cells))
+	var maxScore float64
+	for i, c := range cells {
+		values[i] = float64(c.Count)
+		maxScore = maxFloat(maxScore, c.Score)
+	}
+	rects := Layout(values, Rect{W: float64(width), H: float64(height)})
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	if err != nil {
+		return err
+	}
+	for i, c := range cells {
+		r := rects[i]
+		dir := html.EscapeString(c.Dir)
+		fmt.Fprintf(w, `<g><title>%s: %d comments, age-weighted score %.1f</title>`, dir, c.Count, c.Score)
+		fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="#fff" stroke-width="2"/>`, r.X, r.Y, r.W, r.H, heat(c.Score/maxScore))
+		// only label cells with room for the text
+		if r.W > 60 && r.H > 34 {
+			fmt.Fprintf(w, `<text x="%.1f" y="%.1f">%s</text><text x="%.1f" y="%.1f">%d</text>`, r.X+6, r.Y+16, dir, r.X+6, r.Y+30, c.Count)
+		}
+		fmt.Fprintln(w, "</g>")
+	}
+	_, err = fmt.Fprintln(w, "</svg>")
+	return err
+}
+
+// RenderHTML writes a page embedding the treemap of the cells.
+func RenderHTML(w io.Writer, title string, cells []Cell, width, height int) error {
+	_, err := fmt.Fprintf(w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>listme heatmap: %s</title>
+<style>body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }</style>
+</head>
+<body>
+<h1>Tagged comments in %s</h1>
+<p>Area: number of comments per directory. Color: comments weighted by age, older ones are hotter.</p>
+`, html.EscapeString(title), html.EscapeString(title))
+	if err != nil {
+		return err
+	}
+	if err := RenderSVG(w, cells, width, height); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, "</body>\n</html>")
+	return err
+}
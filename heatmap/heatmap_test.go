@@ -0,0 +1,57 @@
+package heatmap
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mathpn/listme/search"
+)
+
+func TestLayout(t *testing.T) {
+	values := []float64{6, 6, 4, 3, 2, 2, 1}
+	bounds := Rect{W: 600, H: 400}
+	rects := Layout(values, bounds)
+	if len(rects) != len(values) {
+		t.Fatalf("got %d rectangles, want %d", len(rects), len(values))
+	}
+
+	const eps = 1e-6
+	var area float64
+	for i, r := range rects {
+		want := values[i] / 24 * bounds.W * bounds.H
+		if math.Abs(r.W*r.H-want) > eps {
+			t.Errorf("rectangle %d has area %f, want %f", i, r.W*r.H, want)
+		}
+		if r.X < -eps || r.Y < -eps || r.X+r.W > bounds.W+eps || r.Y+r.H > bounds.H+eps {
+			t.Errorf("rectangle %d %+v is out of bounds", i, r)
+		}
+		area += r.W * r.H
+	}
+	if math.Abs(area-bounds.W*bounds.H) > eps {
+		t.Errorf("rectangles cover %f, want %f", area, bounds.W*bounds.H)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := now.AddDate(-2, 0, 0)
+	matches := []search.JSONMatch{
+		{Path: "main.go"},
+		{Path: "search/search.go", Date: &old},
+		{Path: "search/output.go", Date: &now},
+	}
+	cells := Aggregate(matches, now)
+	if len(cells) != 2 {
+		t.Fatalf("got %d cells, want 2", len(cells))
+	}
+	if cells[0].Dir != "search" || cells[0].Count != 2 {
+		t.Errorf("got first cell %+v, want search with 2 comments", cells[0])
+	}
+	if math.Abs(cells[0].Score-4) > 0.01 {
+		t.Errorf("got score %f, want about 4", cells[0].Score)
+	}
+	if cells[1].Dir != "." || cells[1].Score != 1 {
+		t.Errorf("got second cell %+v, want . with score 1", cells[1])
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/config"
+	"github.com/mathpn/listme/hook"
+	"github.com/mathpn/listme/search"
+)
+
+// hookModes lists the accepted modes of the hook command.
+var hookModes = []string{"check-staged"}
+
+func runHook(args []string) {
+	parser := argparse.NewParser("listme hook", "Checks designed to run from git hooks.")
+	parser.SelectorPositional(hookModes, &argparse.Options{Help: "Mode: check-staged blocks the commit if added lines contain forbidden tags"})
+	forbid := parser.StringList("", "forbid", &argparse.Options{Help: "Forbidden tag, matched literally so it may end with punctuation, e.g. FIXME!. Can be repeated. Defaults to forbidden_tags of the configuration file"})
+	parseArgs(parser, args)
+
+	if err := setupLogging(false, false, "text", ""); err != nil {
+		fatal(err)
+	}
+
+	forbidden := *forbid
+	if len(forbidden) == 0 {
+		cfg, err := config.Load(".")
+		if err != nil {
+			fatal(err)
+		}
+		forbidden = cfg.ForbiddenTags
+	}
+	if len(forbidden) == 0 {
+		fatal(fmt.Errorf("no forbidden tags, use --forbid or set forbidden_tags in the configuration file"))
+	}
+
+	// longest first, so FIXME! is preferred over FIXME
+	sort.Slice(forbidden, func(i, j int) bool { return len(forbidden[i]) > len(forbidden[j]) })
+	quoted := make([]string, 0, len(forbidden))
+	for _, tag := range forbidden {
+		quoted = append(quoted, regexp.QuoteMeta(tag))
+	}
+	regex, err := search.TagRegex(quoted)
+	if err != nil {
+		fatal(err)
+	}
+
+	lines, err := hook.StagedLines()
+	if err != nil {
+		fatal(err)
+	}
+	var offending int
+	for _, line := range lines {
+		match := regex.FindStringSubmatch(line.Text)
+		if match == nil {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s:%d:%s:%s\n", line.Path, line.N, match[1], strings.TrimSpace(match[2]))
+		offending++
+	}
+	if offending > 0 {
+		fmt.Fprintf(os.Stderr, "commit blocked: %d added line(s) contain forbidden tags\n", offending)
+		exit(exitMatches)
+	}
+}
@@ -0,0 +1,196 @@
+// Package hook finds the lines changed by git diffs, such as the changes staged
+// for commit or the changes of a pull request.
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Line is a line added to or removed from a file. N is the 1-based number of the
+// line in the new version of the file, or in the old one if the line was removed.
+// RenamedTo is the new path of a removed line whose file was renamed.
+type Line struct {
+	Path      string
+	N         int
+	Text      string
+	Removed   bool
+	RenamedTo string
+}
+
+// StagedLines returns the lines added by the changes staged for commit in the git
+// repository of the working directory. Paths are relative to the repository root.
+func StagedLines() ([]Line, error) {
+	lines, err := gitDiff("--cached", "--diff-filter=d")
+	if err != nil {
+		return nil, err
+	}
+	added := lines[:0]
+	for _, line := range lines {
+		if !line.Removed {
+			added = append(added, line)
+		}
+	}
+	return added, nil
+}
+
+// BranchLines returns the lines added and removed by the commits of HEAD since it
+// diverged from base, as in a pull request. Paths are relative to the repository root.
+// Renamed files are detected, so only the lines that changed in them are returned.
+func BranchLines(base string) ([]Line, error) {
+	return gitDiff("-M", base+"...HEAD")
+}
+
+// Commit is a commit and the lines it added and removed.
+type Commit struct {
+	Hash   string
+	Author string
+	Time   time.Time
+	Lines  []Line
+}
+
+// commitMarker starts the header of each commit in the output of git log.
+const commitMarker = "listme-commit "
+
+// CommitLines returns the commits of HEAD since the ref since, from oldest to newest,
+// with the lines each of them added and removed under dir. Paths are relative to the
+// repository root. Merge commits have no lines, as their changes belong to the merged commits.
+func CommitLines(dir, since string) ([]Commit, error) {
+	cmd := exec.Command("git", "-C", dir, "-c", "core.quotePath=false", "log", "--reverse", "-p", "-M", "--no-color",
+		"--no-ext-diff", "-U0", "--format="+commitMarker+"%H%x09%at%x09%an", since+"..HEAD", "--", ".")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %v - %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseLog(out)
+}
+
+// parseLog splits the output of git log into commits, see CommitLines.
+func parseLog(out []byte) ([]Commit, error) {
+	var commits []Commit
+	// diff lines start with +, - or a space, so only headers start with the marker
+	for _, chunk := range bytes.Split(append([]byte{'\n'}, out...), []byte("\n"+commitMarker)) {
+		header, diff, _ := bytes.Cut(chunk, []byte{'\n'})
+		if len(header) == 0 {
+			continue
+		}
+		fields := strings.SplitN(string(header), "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid commit header %q", header)
+		}
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid commit header %q", header)
+		}
+		lines, err := ParseDiff(bytes.NewReader(diff))
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, Commit{Hash: fields[0], Author: fields[2], Time: time.Unix(timestamp, 0), Lines: lines})
+	}
+	return commits, nil
+}
+
+func gitDiff(args ...string) ([]Line, error) {
+	args = append([]string{"-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", "-U0"}, args...)
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff failed: %v - %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return ParseDiff(bytes.NewReader(out))
+}
+
+// ParseDiff returns the added and removed lines of a unified diff.
+func ParseDiff(r io.Reader) ([]Line, error) {
+	var lines []Line
+	var path, oldPath string
+	var n, oldN int
+	// in hunks, lines starting with +++ are added lines, not file headers
+	var inHunk bool
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for s.Scan() {
+		text := s.Text()
+		switch {
+		case strings.HasPrefix(text, "diff "):
+			inHunk = false
+		case !inHunk && strings.HasPrefix(text, "--- "):
+			oldPath = parsePath(strings.TrimPrefix(text, "--- "))
+		case !inHunk && strings.HasPrefix(text, "+++ "):
+			path = parsePath(strings.TrimPrefix(text, "+++ "))
+		case strings.HasPrefix(text, "@@ "):
+			oldStart, start, err := hunkStart(text)
+			if err != nil {
+				return nil, err
+			}
+			oldN, n = oldStart, start
+			inHunk = true
+		case !inHunk:
+		case strings.HasPrefix(text, "+"):
+			if path != "" {
+				lines = append(lines, Line{Path: path, N: n, Text: text[1:]})
+			}
+			n++
+		case strings.HasPrefix(text, "-"):
+			if oldPath != "" {
+				line := Line{Path: oldPath, N: oldN, Text: text[1:], Removed: true}
+				if path != "" && path != oldPath {
+					line.RenamedTo = path
+				}
+				lines = append(lines, line)
+			}
+			oldN++
+		case strings.HasPrefix(text, " "):
+			n++
+			oldN++
+		}
+	}
+	return lines, s.Err()
+}
+
+// parsePath returns the path of a file header, without the a/ or b/ prefix.
+// Missing files (/dev/null) have an empty path.
+func parsePath(name string) string {
+	if strings.HasPrefix(name, `"`) {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+	}
+	if name == "/dev/null" {
+		return ""
+	}
+	if strings.HasPrefix(name, "a/") || strings.HasPrefix(name, "b/") {
+		return name[2:]
+	}
+	return name
+}
+
+// hunkStart returns the first line of the old and new files in a hunk header such as @@ -1,2 +3,4 @@.
+func hunkStart(header string) (int, int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	oldStart, err1 := rangeStart(fields[1][1:])
+	start, err2 := rangeStart(fields[2][1:])
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+	return oldStart, start, nil
+}
+
+func rangeStart(r string) (int, error) {
+	start, _, _ := strings.Cut(r, ",")
+	return strconv.Atoi(start)
+}
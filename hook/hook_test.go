@@ -0,0 +1,84 @@
+package hook
+
+import (
+	"strings"
+	"testing"
+)
+
+const diff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -3,0 +4,2 @@ import (
++// FIXME! remove before merging
++	"os"
+@@ -10 +12,2 @@ func main() {
+-	old()
++	new() // TODO: later
+++++ counter
+diff --git a/new file.py b/new file.py
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/new file.py
+@@ -0,0 +1 @@
++# BUG: breaks on empty input
+diff --git a/old.py b/lib/new.py
+similarity index 90%
+rename from old.py
+rename to lib/new.py
+--- a/old.py
++++ b/lib/new.py
+@@ -2 +2 @@
+-# TODO: rename
++# TODO: renamed
+`
+
+func TestParseDiff(t *testing.T) {
+	lines, err := ParseDiff(strings.NewReader(diff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Line{
+		{Path: "main.go", N: 4, Text: "// FIXME! remove before merging"},
+		{Path: "main.go", N: 5, Text: "\t\"os\""},
+		{Path: "main.go", N: 10, Text: "\told()", Removed: true},
+		{Path: "main.go", N: 12, Text: "\tnew() // TODO: later"},
+		{Path: "main.go", N: 13, Text: "+++ counter"},
+		{Path: "new file.py", N: 1, Text: "# BUG: breaks on empty input"},
+		{Path: "old.py", N: 2, Text: "# TODO: rename", Removed: true, RenamedTo: "lib/new.py"},
+		{Path: "lib/new.py", N: 2, Text: "# TODO: renamed"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(lines), len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d: got %+v, want %+v", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	log := "listme-commit aaaa\t1700000000\tAda Lovelace\n\n" + diff +
+		"listme-commit bbbb\t1700000100\tGrace Hopper\n" +
+		"listme-commit cccc\t1700000200\tAda Lovelace\n\n" +
+		"diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +0,0 @@\n-// TODO: done\n"
+	commits, err := parseLog([]byte(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 3 {
+		t.Fatalf("got %d commits, want 3: %+v", len(commits), commits)
+	}
+	if c := commits[0]; c.Hash != "aaaa" || c.Author != "Ada Lovelace" || c.Time.Unix() != 1700000000 || len(c.Lines) != 8 {
+		t.Errorf("unexpected first commit %+v", c)
+	}
+	if len(commits[1].Lines) != 0 {
+		t.Errorf("expected a commit without lines, got %+v", commits[1].Lines)
+	}
+	want := Line{Path: "a.go", N: 1, Text: "// TODO: done", Removed: true}
+	if c := commits[2]; len(c.Lines) != 1 || c.Lines[0] != want {
+		t.Errorf("got lines %+v, want %+v", c.Lines, want)
+	}
+}
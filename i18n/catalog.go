@@ -0,0 +1,108 @@
+package i18n
+
+// catalog maps English messages to their translations for each locale.
+// Translations must keep the same format verbs, in the same order.
+var catalog = map[string]map[string]string{
+	PortugueseBR: {
+		"Line":                                "Linha",
+		"OLD":                                 "ANTIGO",
+		"(%d comment)":                        "(%d comentário)",
+		"(%d comments)":                       "(%d comentários)",
+		"(%d comment, oldest %s)":             "(%d comentário, mais antigo: %s)",
+		"(%d comments, oldest %s)":            "(%d comentários, mais antigo: %s)",
+		"no comment":                          "sem comentário",
+		"malformed: %s":                       "grafia incorreta: %s",
+		"lint: %s":                            "lint: %s",
+		"no text":                             "sem texto",
+		"too short":                           "curto demais",
+		"no assignee":                         "sem responsável",
+		"no issue":                            "sem issue",
+		"%d comments flagged by lint rules":   "%d comentários apontados pelas regras de lint",
+		"… and %d more (use --all to expand)": "… e mais %d (use --all para expandir)",
+		"Scanned %d files (%d skipped) in %s": "%d arquivos analisados (%d ignorados) em %s",
+		"By extension":                        "Por extensão",
+		"By directory":                        "Por diretório",
+		"By owner":                            "Por responsável",
+		"Tag co-occurrence":                   "Tags em conjunto",
+		"Mixed files":                         "Arquivos com várias tags",
+		"%d files":                            "%d arquivos",
+		"1 file":                              "1 arquivo",
+		"in %s":                               "em %s",
+		"%d comments":                         "%d comentários",
+		"1 comment":                           "1 comentário",
+		"listme: %s in %s":                    "listme: %s em %s",
+		", 1 error":                           ", 1 erro",
+		", %d errors":                         ", %d erros",
+		"average age: %d days":                "idade média: %d dias",
+		"skipped %d files:":                   "%d arquivos ignorados:",
+		"    … and %d more":                   "    … e mais %d",
+		"(line %d)":                           "(linha %d)",
+		"permission denied":                   "permissão negada",
+		"unreadable":                          "ilegível",
+		"larger than the size limit":          "maior que o limite de tamanho",
+		"not a text file":                     "não é um arquivo de texto",
+		"unsupported encoding":                "codificação não suportada",
+		"today":                               "hoje",
+		"1 day ago":                           "há 1 dia",
+		"%d days ago":                         "há %d dias",
+		"%d months ago":                       "há %d meses",
+		"%d years ago":                        "há %d anos",
+		// layout of absolute dates
+		"2006-01-02": "02/01/2006",
+
+		"scan interrupted after %d files, results are incomplete":      "análise interrompida após %d arquivos, os resultados estão incompletos",
+		"scan timed out after %d files, results are incomplete":        "tempo esgotado após %d arquivos, os resultados estão incompletos",
+		"git blame failed for %d files, their comments have no author": "git blame falhou em %d arquivos, seus comentários não têm autor",
+	},
+	Spanish: {
+		"Line":                                "Línea",
+		"OLD":                                 "ANTIGUO",
+		"(%d comment)":                        "(%d comentario)",
+		"(%d comments)":                       "(%d comentarios)",
+		"(%d comment, oldest %s)":             "(%d comentario, más antiguo: %s)",
+		"(%d comments, oldest %s)":            "(%d comentarios, más antiguo: %s)",
+		"no comment":                          "sin comentario",
+		"malformed: %s":                       "ortografía incorrecta: %s",
+		"lint: %s":                            "lint: %s",
+		"no text":                             "sin texto",
+		"too short":                           "demasiado corto",
+		"no assignee":                         "sin responsable",
+		"no issue":                            "sin issue",
+		"%d comments flagged by lint rules":   "%d comentarios señalados por las reglas de lint",
+		"… and %d more (use --all to expand)": "… y %d más (use --all para expandir)",
+		"Scanned %d files (%d skipped) in %s": "%d archivos analizados (%d omitidos) en %s",
+		"By extension":                        "Por extensión",
+		"By directory":                        "Por directorio",
+		"By owner":                            "Por propietario",
+		"Tag co-occurrence":                   "Etiquetas en conjunto",
+		"Mixed files":                         "Archivos con varias etiquetas",
+		"%d files":                            "%d archivos",
+		"1 file":                              "1 archivo",
+		"in %s":                               "en %s",
+		"%d comments":                         "%d comentarios",
+		"1 comment":                           "1 comentario",
+		"listme: %s in %s":                    "listme: %s en %s",
+		", 1 error":                           ", 1 error",
+		", %d errors":                         ", %d errores",
+		"average age: %d days":                "antigüedad media: %d días",
+		"skipped %d files:":                   "%d archivos omitidos:",
+		"    … and %d more":                   "    … y %d más",
+		"(line %d)":                           "(línea %d)",
+		"permission denied":                   "permiso denegado",
+		"unreadable":                          "ilegible",
+		"larger than the size limit":          "mayor que el límite de tamaño",
+		"not a text file":                     "no es un archivo de texto",
+		"unsupported encoding":                "codificación no soportada",
+		"today":                               "hoy",
+		"1 day ago":                           "hace 1 día",
+		"%d days ago":                         "hace %d días",
+		"%d months ago":                       "hace %d meses",
+		"%d years ago":                        "hace %d años",
+		// layout of absolute dates
+		"2006-01-02": "02/01/2006",
+
+		"scan interrupted after %d files, results are incomplete":      "análisis interrumpido tras %d archivos, los resultados están incompletos",
+		"scan timed out after %d files, results are incomplete":        "tiempo agotado tras %d archivos, los resultados están incompletos",
+		"git blame failed for %d files, their comments have no author": "git blame falló en %d archivos, sus comentarios no tienen autor",
+	},
+}
@@ -0,0 +1,77 @@
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Supported locales. English is used for missing translations.
+const (
+	English      = "en"
+	PortugueseBR = "pt-BR"
+	Spanish      = "es"
+)
+
+// Locales lists the supported locales.
+var Locales = []string{English, PortugueseBR, Spanish}
+
+var current = English
+
+// SetLocale selects the locale used by T and Sprintf.
+// Names are matched as in the LANG environment variable, so pt_BR.UTF-8 selects pt-BR.
+func SetLocale(name string) error {
+	locale, ok := match(name)
+	if !ok {
+		return fmt.Errorf("unsupported locale %q, use one of %s", name, strings.Join(Locales, ", "))
+	}
+	current = locale
+	return nil
+}
+
+// FromEnv returns the locale set by the LC_ALL, LC_MESSAGES or LANG environment
+// variables, in this order of precedence. If none is set to a supported locale,
+// English is returned.
+func FromEnv() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(key)
+		if value == "" {
+			continue
+		}
+		if locale, ok := match(value); ok {
+			return locale
+		}
+		return English
+	}
+	return English
+}
+
+// match returns the supported locale for a name such as pt_BR.UTF-8, es_AR or en-US.
+// Any variant of Portuguese uses pt-BR and any variant of Spanish uses es.
+func match(name string) (string, bool) {
+	name = strings.SplitN(name, ".", 2)[0]
+	name = strings.SplitN(name, "@", 2)[0]
+	lang, _, _ := strings.Cut(strings.ReplaceAll(name, "_", "-"), "-")
+	switch strings.ToLower(lang) {
+	case "en", "c", "posix":
+		return English, true
+	case "pt":
+		return PortugueseBR, true
+	case "es":
+		return Spanish, true
+	}
+	return "", false
+}
+
+// T returns the translation of the English message to the current locale.
+func T(message string) string {
+	if translated, ok := catalog[current][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Sprintf formats the translation of the English format string.
+func Sprintf(format string, a ...any) string {
+	return fmt.Sprintf(T(format), a...)
+}
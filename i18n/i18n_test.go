@@ -0,0 +1,36 @@
+package i18n
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := map[string]string{
+		"pt_BR.UTF-8": PortugueseBR,
+		"pt-PT":       PortugueseBR,
+		"es_AR.UTF-8": Spanish,
+		"en_US":       English,
+		"C":           English,
+		"de_DE.UTF-8": "",
+	}
+	for name, expected := range cases {
+		locale, ok := match(name)
+		if locale != expected || ok != (expected != "") {
+			t.Errorf("%s: expected %q, got %q", name, expected, locale)
+		}
+	}
+}
+
+func TestTranslation(t *testing.T) {
+	defer SetLocale(English)
+	if err := SetLocale("es"); err != nil {
+		t.Fatal(err)
+	}
+	if got := Sprintf("(%d comments)", 3); got != "(3 comentarios)" {
+		t.Errorf("unexpected translation %q", got)
+	}
+	if got := T("untranslated message"); got != "untranslated message" {
+		t.Errorf("missing translations must fall back to English, got %q", got)
+	}
+	if err := SetLocale("xx"); err == nil {
+		t.Error("expected error for unsupported locale")
+	}
+}
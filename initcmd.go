@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/config"
+	"github.com/mathpn/listme/i18n"
+	"github.com/mathpn/listme/matcher"
+	"github.com/mathpn/listme/pretty"
+)
+
+// initFileName is the name of the configuration file written by listme init.
+const initFileName = ".listme.yml"
+
+func runInit(args []string) {
+	parser := argparse.NewParser("listme init", "Write a commented configuration file at the root of the repository.")
+	defaults := parser.Flag("", "defaults", &argparse.Options{Help: "Do not ask questions, write the default settings"})
+	force := parser.Flag("", "force", &argparse.Options{Help: "Overwrite an existing configuration file"})
+	parseArgs(parser, args)
+
+	if err := setupLogging(false, false, "text", ""); err != nil {
+		fatal(err)
+	}
+
+	root := "."
+	if repoRoot, err := matcher.RepoRoot("."); err == nil {
+		root = repoRoot
+	}
+	if !*force {
+		for _, name := range config.FileNames {
+			if _, err := os.Stat(filepath.Join(root, name)); !errors.Is(err, fs.ErrNotExist) {
+				fatal(fmt.Errorf("%s already exists, use --force to overwrite it", relPath(filepath.Join(root, name))))
+			}
+		}
+	}
+
+	cfg := &config.Config{
+		AgeTiers: []config.AgeTier{{Label: pretty.DefaultAgeTierLabel, Days: defaultOldCommitLimit}},
+	}
+	if !*defaults {
+		if err := askConfig(cfg); err != nil {
+			fatal(err)
+		}
+	}
+
+	path := filepath.Join(root, initFileName)
+	if err := os.WriteFile(path, []byte(config.Template(cfg)), 0o644); err != nil {
+		fatal(err)
+	}
+	if _, err := config.Parse(path); err != nil {
+		fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", relPath(path))
+}
+
+// askConfig fills cfg with the answers read from stdin. Empty answers keep the current settings.
+func askConfig(cfg *config.Config) error {
+	in := bufio.NewReader(os.Stdin)
+	ask := func(question, current string) string {
+		fmt.Fprintf(os.Stderr, "%s [%s] ", question, current)
+		answer, _ := in.ReadString('\n')
+		return strings.TrimSpace(answer)
+	}
+
+	if locale := ask("Language of the output labels ("+strings.Join(i18n.Locales, ", ")+")?", "from environment"); locale != "" {
+		if err := i18n.SetLocale(locale); err != nil {
+			return err
+		}
+		cfg.Locale = locale
+	}
+
+	if days := ask("Mark lines as old after how many days?", strconv.Itoa(defaultOldCommitLimit)); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return fmt.Errorf("the number of days must be a non-negative integer")
+		}
+		cfg.AgeTiers[0].Days = n
+	}
+
+	var err error
+	if cfg.FailOn, err = parseTagList(ask("Tags that make --ci runs fail, separated by commas?", "none")); err != nil {
+		return err
+	}
+	forbidden := ask("Tags that block commits with listme hook check-staged, separated by commas?", "none")
+	for _, tag := range strings.Split(forbidden, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			cfg.ForbiddenTags = append(cfg.ForbiddenTags, tag)
+		}
+	}
+	return nil
+}
+
+// parseTagList splits a comma-separated answer into tags and validates them.
+func parseTagList(answer string) ([]string, error) {
+	if answer == "" {
+		return nil, nil
+	}
+	tags := splitTags([]string{answer})
+	for i := range tags {
+		tags[i] = strings.TrimSpace(tags[i])
+	}
+	return tags, validateTags(tags)
+}
@@ -0,0 +1,218 @@
+// Package listme searches source trees for tagged comments, such as TODO and FIXME,
+// for Go programs that embed listme instead of running it and parsing its output.
+//
+// Scan runs the search of the listme command with the provided options and returns
+// the tagged comments as they're found:
+//
+//	results, err := listme.Scan(ctx, listme.Options{Path: ".", Tags: []string{"BUG", "FIXME"}})
+//	if err != nil {
+//		return err
+//	}
+//	for r := range results {
+//		fmt.Println(r.Path, r.Line, r.Tag, r.Text, r.Author)
+//	}
+//
+// Nothing is printed: files that can't be searched are skipped, and log messages go to
+// the default slog logger. The configuration file is not read. Several searches may run
+// at the same time, with different options.
+package listme
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/mathpn/listme/pretty"
+	"github.com/mathpn/listme/search"
+)
+
+// DefaultTags lists the tags searched if Options.Tags is empty.
+var DefaultTags = search.DefaultTags
+
+// LintRules flag low-quality comments, see Options.Lint.
+type LintRules = search.LintRules
+
+// Options configure a search. The zero value searches the current directory for
+// DefaultTags with the defaults of the listme command.
+//   - Path: file or directory to search, recursively. Defaults to the current directory
+//   - Tags: tags to search for, made of letters, digits and underscores. Defaults to DefaultTags
+//   - Glob: glob pattern of the files searched, e.g. *.go. Defaults to every file
+//   - Types: only search files of these types, by language, e.g. go or python
+//   - Author: only report comments whose commit author has this name
+//   - NewerThan: only report comments committed within this number of days (0 disables it)
+//   - NoGit: do not run git blame, leaving authors and commits empty. Outside of git
+//     repositories, comments have no author either way
+//   - TrackedOnly: only search files tracked by git
+//   - NoDefaultExcludes: also search dependency and build directories, see search.DefaultExcludes
+//   - ExcludeDirs: names of directories that are never searched
+//   - FuzzyTags: also report misspelled tags, e.g. TOOD, see Result.Malformed
+//   - Tasks: also report unchecked Markdown task items with the TASK tag
+//   - Symbols: find the function or type enclosing each comment, see Result.Symbol
+//   - Lint: flag low-quality comments, see Result.Lint
+//   - Workers: number of files searched at the same time. Defaults to 128
+//   - MaxFileSize: maximum size of the files searched, in MB. Defaults to 5
+type Options struct {
+	Path              string
+	Tags              []string
+	Glob              string
+	Types             []string
+	Author            string
+	NewerThan         int
+	NoGit             bool
+	TrackedOnly       bool
+	NoDefaultExcludes bool
+	ExcludeDirs       []string
+	FuzzyTags         bool
+	Tasks             bool
+	Symbols           bool
+	Lint              *LintRules
+	Workers           int
+	MaxFileSize       int64
+}
+
+// Result is a tagged comment.
+//   - Path: path of the file, relative to Options.Path if it's a directory
+//   - Line: 1-based line number
+//   - Column: 1-based column where the tag starts, in characters
+//   - Tag: matched tag, e.g. TODO
+//   - Severity: severity of the tag: info, warning or error
+//   - Text: comment text following the tag
+//   - Fingerprint: stable identifier of the comment across runs, even if its line changes
+//   - Author: git author of the line, if known
+//   - Commit: short hash of the commit of the line, if known
+//   - Date: date of the commit of the line, zero if unknown
+//   - Language: programming language of the file, if known
+//   - Owners: owners of the file in the CODEOWNERS file of the repository, if any
+//   - Malformed: the tag as written if it's a misspelling of Tag, with Options.FuzzyTags
+//   - Symbol: function or type enclosing the comment, with Options.Symbols, if found
+//   - Lint: lint issues of the comment, with Options.Lint, see search.LintIssues
+type Result struct {
+	Path        string
+	Line        int
+	Column      int
+	Tag         string
+	Severity    string
+	Text        string
+	Fingerprint string
+	Author      string
+	Commit      string
+	Date        time.Time
+	Language    string
+	Owners      []string
+	Malformed   string
+	Symbol      string
+	Lint        []string
+}
+
+// same rule as the --tags flag
+var tagRegex = regexp.MustCompile(`^\w+$`)
+
+// defaults of the flags of the listme command
+const (
+	defaultWorkers        = 128
+	defaultMaxFileSize    = 5
+	defaultOldCommitLimit = 60
+)
+
+// Scan searches for tagged comments in the background and sends them to the returned
+// channel, which is closed once the search is done. Results arrive in no particular
+// order, as files are searched concurrently. The channel must be drained, or ctx
+// cancelled, for the search to finish; once ctx is cancelled, no more files are searched.
+func Scan(ctx context.Context, opts Options) (<-chan Result, error) {
+	tags := opts.Tags
+	if len(tags) == 0 {
+		tags = DefaultTags
+	}
+	for _, tag := range tags {
+		if !tagRegex.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: tags must be non-empty and contain only alphanumeric characters", tag)
+		}
+	}
+	if opts.NewerThan < 0 {
+		return nil, fmt.Errorf("invalid NewerThan %d: it must be a non-negative number of days", opts.NewerThan)
+	}
+	path := opts.Path
+	if path == "" {
+		path = "."
+	}
+	glob := opts.Glob
+	if glob == "" {
+		glob = "*"
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	maxFileSize := opts.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxFileSize
+	}
+	newerThan := -1
+	if opts.NewerThan > 0 {
+		newerThan = opts.NewerThan
+	}
+
+	results := make(chan Result)
+	params, err := search.NewSearchParams(search.Options{
+		Path:              path,
+		Tags:              tags,
+		Glob:              glob,
+		Types:             opts.Types,
+		Author:            opts.Author,
+		CommitAgeFilter:   newerThan,
+		OldCommitLimit:    defaultOldCommitLimit,
+		NoGit:             opts.NoGit,
+		TrackedOnly:       opts.TrackedOnly,
+		NoDefaultExcludes: opts.NoDefaultExcludes,
+		ExcludeDirs:       opts.ExcludeDirs,
+		FuzzyTags:         opts.FuzzyTags,
+		Tasks:             opts.Tasks,
+		ShowSymbol:        opts.Symbols,
+		Lint:              opts.Lint,
+		Workers:           workers,
+		MaxFileSize:       maxFileSize,
+		Style:             pretty.PlainStyle,
+		Quiet:             true,
+		Diagnostics:       io.Discard,
+		Context:           ctx,
+		Collect: func(m search.JSONMatch) {
+			select {
+			case results <- newResult(m):
+			case <-ctx.Done():
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		defer close(results)
+		search.Search(params)
+	}()
+	return results, nil
+}
+
+func newResult(m search.JSONMatch) Result {
+	r := Result{
+		Path:        m.Path,
+		Line:        m.Line,
+		Column:      m.Column,
+		Tag:         m.Tag,
+		Severity:    pretty.LookupTag(m.Tag).Severity.String(),
+		Text:        m.Text,
+		Fingerprint: m.Fingerprint,
+		Author:      m.Author,
+		Commit:      m.Commit,
+		Language:    m.Language,
+		Owners:      m.Owners,
+		Malformed:   m.Malformed,
+		Symbol:      m.Symbol,
+		Lint:        m.Lint,
+	}
+	if m.Date != nil {
+		r.Date = *m.Date
+	}
+	return r
+}
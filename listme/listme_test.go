@@ -0,0 +1,111 @@
+package listme
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"code.py":  "# TODO: first\nx = 1\n# BUG: second\n",
+		"notes.md": "nothing to do\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := Scan(context.Background(), Options{Path: dir, NoGit: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Result
+	for r := range results {
+		got = append(got, r)
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Line < got[j].Line })
+	if len(got) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(got))
+	}
+	first := got[0]
+	if first.Path != "code.py" || first.Line != 1 || first.Tag != "TODO" || first.Text != "first" || first.Language != "Python" {
+		t.Errorf("unexpected first result %+v", first)
+	}
+	if got[1].Tag != "BUG" || got[1].Severity != "error" {
+		t.Errorf("expected a BUG with error severity, got %+v", got[1])
+	}
+
+	if _, err := Scan(context.Background(), Options{Path: dir, Tags: []string{"TO DO"}}); err == nil {
+		t.Error("expected an error for an invalid tag")
+	}
+}
+
+func TestScanCancel(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 50; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.go", i)), []byte("// TODO: x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := Scan(ctx, Options{Path: dir, NoGit: true, Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-results
+	cancel()
+	// the channel is closed without draining the remaining results
+	for range results {
+	}
+}
+
+func TestScanConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "code.py"), []byte("x = 1\n# TODO: fix\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "code.py"},
+		{"-c", "user.name=Ada Lovelace", "-c", "user.email=ada@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+	}
+
+	// searches embedded in the same program don't share their settings
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := Scan(context.Background(), Options{Path: dir, Workers: 2})
+			if err != nil {
+				errs <- err
+				return
+			}
+			var got []Result
+			for r := range results {
+				got = append(got, r)
+			}
+			if len(got) != 1 || got[0].Author != "Ada Lovelace" || got[0].Commit == "" {
+				errs <- fmt.Errorf("expected a TODO of Ada Lovelace, got %+v", got)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -1,24 +1,109 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/akamensky/argparse"
-	logging "github.com/op/go-logging"
 
+	"github.com/mathpn/listme/blame"
+	"github.com/mathpn/listme/clipboard"
+	"github.com/mathpn/listme/config"
+	"github.com/mathpn/listme/edit"
+	"github.com/mathpn/listme/hook"
+	"github.com/mathpn/listme/i18n"
+	"github.com/mathpn/listme/matcher"
 	"github.com/mathpn/listme/pretty"
 	"github.com/mathpn/listme/search"
 )
 
-var log = logging.MustGetLogger("listme")
-var format = logging.MustStringFormatter(`%{color}%{level}%{color:reset}: %{message}`)
-var tags = []string{"BUG", "FIXME", "XXX", "TODO", "HACK", "OPTIMIZE", "NOTE"}
+var tags = search.DefaultTags
 var tagValRegex = regexp.MustCompile(`^(\w+)$`)
 
+// defaultOldCommitLimit is the default age in days after which lines are marked as old.
+const defaultOldCommitLimit = 60
+
+// logFormats lists the accepted values of --log-format.
+var logFormats = []string{"text", "json"}
+
+// outputPresets lists the accepted values of --output.
+var outputPresets = []string{"locations"}
+
+// commands maps subcommand names to their entry points.
+// Any other first argument is treated as the path of a regular search.
+var commands = map[string]func(args []string){
+	"stats":      runStats,
+	"resolve":    runResolve,
+	"sync":       runSync,
+	"rewrite":    runRewrite,
+	"heatmap":    runHeatmap,
+	"hook":       runHook,
+	"pr-comment": runPRComment,
+	"resolved":   runResolved,
+	"cache":      runCache,
+	"init":       runInit,
+	"doctor":     runDoctor,
+	"why":        runWhy,
+	"schema":     runSchema,
+	"digest":     runDigest,
+}
+
+func validateTagDefs(defs []string) error {
+	for _, def := range defs {
+		name := strings.SplitN(def, ":", 2)[0]
+		if err := validateTags([]string{name}); err != nil {
+			return err
+		}
+		if _, err := pretty.ParseTagDef(def); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateFileTypes(types []string) error {
+	for _, name := range splitTags(types) {
+		if _, err := search.ParseFileType(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateAgeTiers(tiers []string) error {
+	for _, tier := range tiers {
+		if _, err := pretty.ParseAgeTier(tier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTimeout(args []string) error {
+	for _, arg := range args {
+		d, err := time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("the timeout must be a positive duration, e.g. 30s or 2m")
+		}
+	}
+	return nil
+}
+
+// validateTags checks the tags, which may be comma-separated as in -T BUG,FIXME.
 func validateTags(tags []string) error {
-	for _, tag := range tags {
+	for _, tag := range splitTags(tags) {
 		match := tagValRegex.MatchString(tag)
 		if !match {
 			return fmt.Errorf("provided tags must be non-empty and contain only alphanumeric characters")
@@ -27,67 +112,903 @@ func validateTags(tags []string) error {
 	return nil
 }
 
+func validateBlameOptions(opts []string) error {
+	_, err := blame.ParseOptions(splitTags(opts))
+	return err
+}
+
+// splitTags splits comma-separated values, so -T BUG,FIXME is the same as -T BUG FIXME.
+func splitTags(values []string) []string {
+	var tags []string
+	for _, v := range values {
+		tags = append(tags, strings.Split(v, ",")...)
+	}
+	return tags
+}
+
+// searchFlags holds the flags shared by all commands that perform a search.
+type searchFlags struct {
+	path           *string
+	tags           *[]string
+	tagDefs        *[]string
+	excludeTags    *[]string
+	glob           *string
+	author         *string
+	owner          *string
+	ageFilter      *int
+	oldCommitLimit *int
+	ageTiers       *[]string
+	maxFileSize    *int
+	maxLineLength  *int
+	maxOpenFiles   *int
+	fullPath       *bool
+	noAuthor       *bool
+	showDate       *bool
+	dateFormat     *string
+	showHash       *bool
+	showOwner      *bool
+	showLanguage   *bool
+	showSymbol     *bool
+	types          *[]string
+	showOldest     *bool
+	noSummary      *bool
+	skipReport     *bool
+	bySeverity     *bool
+	maxPerFile     *int
+	wrapIndent     *string
+	wrapMarker     *string
+	all            *bool
+	noGit          *bool
+	noGitAll       *bool
+	noDefExcludes  *bool
+	noCache        *bool
+	useIndex       *bool
+	trackedOnly    *bool
+	inclUntracked  *bool
+	fetchBlame     *bool
+	prefetchBlame  *bool
+	ageMode        *string
+	blameOpts      *[]string
+	localAuthor    *bool
+	timeout        *string
+	blameTimeout   *string
+	resume         *string
+	gitDir         *string
+	workTree       *string
+	tasks          *bool
+	fuzzyTags      *bool
+	lint           *bool
+	lintMinLength  *int
+	lintRequire    *[]string
+	bw             *bool
+	plain          *bool
+	theme          *string
+	format         *string
+	output         *string
+	workers        *int
+	pprof          *string
+	verbose        *bool
+	debug          *bool
+	logFormat      *string
+	logFile        *string
+
+	parser *argparse.Parser
+	// set by options
+	config *config.Config
+}
+
+func addSearchFlags(parser *argparse.Parser) *searchFlags {
+	return &searchFlags{
+		parser:         parser,
+		path:           parser.StringPositional(&argparse.Options{Help: "Path to folder or file to be searched. Search is recursive."}),
+		tags:           parser.StringList("T", "tags", &argparse.Options{Default: tags, Validate: validateTags, Help: "Tags to search for, separated by spaces or commas, e.g. -T BUG,FIXME"}),
+		tagDefs:        parser.StringList("", "tag", &argparse.Options{Validate: validateTagDefs, Help: "Define a tag with the format NAME:color:emoji:severity and add it to the search. Only the name is required. Can be repeated"}),
+		excludeTags:    parser.StringList("", "exclude-tags", &argparse.Options{Validate: validateTags, Help: "Tags removed from the search, separated by spaces or commas, e.g. --exclude-tags NOTE,HACK"}),
+		types:          parser.StringList("", "type", &argparse.Options{Validate: validateFileTypes, Help: "Only search files of these types, separated by spaces or commas, e.g. --type sh,python. Scripts without an extension are detected by their shebang line"}),
+		glob:           parser.String("g", "glob", &argparse.Options{Default: "*", Help: "Glob pattern to filter files in the search. Use a single-quoted string. Example: '*.go'"}),
+		author:         parser.String("a", "author", &argparse.Options{Help: "Filter lines by commit author"}),
+		owner:          parser.String("", "owner", &argparse.Options{Help: "Only search files owned by this team or user in the CODEOWNERS file, e.g. @org/payments-team or @payments-team"}),
+		ageFilter:      parser.Int("n", "newer-than", &argparse.Options{Default: -1, Help: "Filters lines based on the age of commits, showing only lines committed within the specified number of days"}),
+		oldCommitLimit: parser.Int("o", "old-commit-mark-limit", &argparse.Options{Default: defaultOldCommitLimit, Help: "Sets the age limit for marking commits as old, with commits older than the specified limit being marked"}),
+		ageTiers:       parser.StringList("", "age-tier", &argparse.Options{Validate: validateAgeTiers, Help: "Mark lines older than a number of days with a badge, with the format LABEL:DAYS:color. The color is optional. Can be repeated, e.g. --age-tier STALE:90 --age-tier ANCIENT:365. Replaces the OLD badge of --old-commit-mark-limit"}),
+		maxFileSize:    parser.Int("f", "max-file-size", &argparse.Options{Default: 5, Help: "Maximum file size to scan (in MB)"}),
+		maxLineLength:  parser.Int("", "max-line-length", &argparse.Options{Default: 64, Help: "Maximum line length to scan (in KB). Longer lines, e.g. in minified files, are skipped"}),
+		maxOpenFiles:   parser.Int("", "max-open-files", &argparse.Options{Default: 0, Help: "Maximum number of files read at the same time, including by git blame, independently from --workers. Lower it on network filesystems or spinning disks. By default, one per worker"}),
+		fullPath:       parser.Flag("F", "full-path", &argparse.Options{Help: "Print full absolute path of the files"}),
+		noAuthor:       parser.Flag("A", "no-author", &argparse.Options{Help: "Do not print git author information"}),
+		showDate:       parser.Flag("", "show-date", &argparse.Options{Help: "Show the commit date of each line in a column next to the author. Independent from --no-author"}),
+		dateFormat:     parser.String("", "date-format", &argparse.Options{Default: "relative", Help: "Format of the --show-date column: relative (e.g. 3 months ago), absolute (e.g. 2023-04-01, following the locale) or a Go layout string (e.g. '02 Jan 2006')"}),
+		showHash:       parser.Flag("", "show-hash", &argparse.Options{Help: "Show the short commit hash of each line in a column next to the text"}),
+		showOldest:     parser.Flag("", "show-oldest", &argparse.Options{Help: "Show the age of the oldest comment of each file next to its name, e.g. (7 comments, oldest 14mo)"}),
+		showOwner:      parser.Flag("", "show-owner", &argparse.Options{Help: "Show the owners of each file from the CODEOWNERS file next to its name"}),
+		showSymbol:     parser.Flag("", "show-symbol", &argparse.Options{Help: "Show the function or type enclosing each comment after its text, e.g. in func Search(...). Found with heuristics for common languages"}),
+		showLanguage:   parser.Flag("", "show-language", &argparse.Options{Help: "Show the language of each file, detected from its extension or shebang line, next to its name"}),
+		noSummary:      parser.Flag("S", "no-summary", &argparse.Options{Help: "Do not print summary box for each file"}),
+		skipReport:     parser.Flag("", "skip-report", &argparse.Options{Help: "List every file skipped as binary or with an unsupported encoding at the end, with the line where the text detection gave up, instead of the first ones"}),
+		bySeverity:     parser.Flag("", "by-severity", &argparse.Options{Help: "Also count comments by severity (error, warning, info) in the summary boxes and --stats totals. Enabled by --ci"}),
+		maxPerFile:     parser.Int("", "max-per-file", &argparse.Options{Default: 20, Help: "Maximum number of comments shown per file, the remaining ones are collapsed. Does not apply to plain and machine-readable output"}),
+		wrapIndent:     parser.Selector("", "wrap-indent", search.WrapIndents, &argparse.Options{Default: search.TagIndent, Help: "Alignment of wrapped comment lines: tag (under the tag) or text (under the comment text)"}),
+		wrapMarker:     parser.String("", "wrap-marker", &argparse.Options{Help: "Prefix of wrapped comment lines, e.g. '↳ '"}),
+		all:            parser.Flag("", "all", &argparse.Options{Help: "Show all comments of every file, disabling --max-per-file"}),
+		tasks:          parser.Flag("", "tasks", &argparse.Options{Help: "Also report unchecked Markdown task items (- [ ]) with the TASK tag"}),
+		fuzzyTags:      parser.Flag("", "fuzzy-tags", &argparse.Options{Help: "Also report misspelled tags in comments, e.g. TOOD, Todo or FIX ME, flagged as malformed"}),
+		lint:           parser.Flag("", "lint", &argparse.Options{Help: "Flag low-quality comments: tags without text and, following the lint rules of the configuration file, comments that are too short or miss an assignee or issue reference. Flagged comments are labeled and counted separately in --stats"}),
+		lintMinLength:  parser.Int("", "lint-min-length", &argparse.Options{Default: 0, Help: "Flag comments shorter than this number of characters. Implies --lint"}),
+		lintRequire:    parser.StringList("", "lint-require", &argparse.Options{Validate: validateLintRequire, Help: "Flag comments missing an assignee, as in TODO(alice) or @alice, or an issue reference, as in #123, PROJ-123 or a link, e.g. --lint-require assignee,issue. Implies --lint"}),
+		noDefExcludes:  parser.Flag("", "no-default-excludes", &argparse.Options{Help: "Also search dependency and build directories: " + strings.Join(search.DefaultExcludes, ", ")}),
+		noGit:          parser.Flag("", "no-git", &argparse.Options{Help: "Do not use git blame, disabling author information. Used by default outside of git repositories"}),
+		noGitAll:       parser.Flag("", "no-git-everything", &argparse.Options{Help: "Skip all git work, including repository detection and .gitignore files, to search non-repository directories as fast as possible"}),
+		noCache:        parser.Flag("", "no-cache", &argparse.Options{Help: "Run git blame for every file instead of reusing the results of previous searches. See listme cache"}),
+		trackedOnly:    parser.Flag("", "tracked-only", &argparse.Options{Help: "Only scan files tracked by git, skipping work-in-progress files that were never committed"}),
+		inclUntracked:  parser.Flag("", "include-untracked", &argparse.Options{Help: "Also scan files not tracked by git, unless ignored. The default, unless include_untracked is false in the configuration file"}),
+		useIndex:       parser.Flag("", "use-index", &argparse.Options{Help: "List files from the git index and only scan files whose content changed since the last search. For very large repositories"}),
+		prefetchBlame:  parser.Flag("", "prefetch-blame", &argparse.Options{Help: "Start git blame as soon as the first tagged comment of a file is found, while the rest of the file is scanned. Faster on blame-heavy runs, but whole files are blamed"}),
+		ageMode:        parser.Selector("", "age-mode", blame.AgeModes, &argparse.Options{Default: "last-touched", Help: "Date comments by the commit that last touched their line (last-touched) or by the one that introduced it (introduced), following the history of the line across whitespace changes and moves. Introduced is slower"}),
+		blameOpts:      parser.StringList("", "blame-opts", &argparse.Options{Validate: validateBlameOptions, Help: "git blame options, separated by commas: w ignores whitespace changes, M follows lines moved within a file and C lines copied from other files, e.g. --blame-opts w,M,C. Keeps mass reformats from looking like fresh comments of whoever ran the formatter"}),
+		localAuthor:    parser.Flag("", "local-author", &argparse.Options{Help: "Attribute lines that can't be blamed, outside of git repositories or not committed yet, to the git user.name or the OS user, marked as (local)"}),
+		fetchBlame:     parser.Flag("", "fetch-blame", &argparse.Options{Help: "In partial clones, let git blame fetch the missing history on demand instead of reporting an unknown author"}),
+		timeout:        parser.String("", "timeout", &argparse.Options{Validate: validateTimeout, Help: "Stop the search after this duration, e.g. 30s or 2m, printing the results found so far"}),
+		blameTimeout:   parser.String("", "blame-timeout", &argparse.Options{Validate: validateTimeout, Help: "Stop git blame of a file after this duration, e.g. 5s, reporting its comments without author instead of waiting"}),
+		resume:         parser.String("", "resume", &argparse.Options{Help: "Save the progress of the search to this state file and, if it exists, continue the interrupted search it records instead of starting over"}),
+		gitDir:         parser.String("", "git-dir", &argparse.Options{Help: "Path to the git repository, as the GIT_DIR environment variable"}),
+		workTree:       parser.String("", "work-tree", &argparse.Options{Help: "Path to the working tree of the repository, as the GIT_WORK_TREE environment variable"}),
+		bw:             parser.Flag("b", "bw", &argparse.Options{Help: "Use black and white style"}),
+		theme:          parser.Selector("", "theme", pretty.ThemeNames, &argparse.Options{Default: pretty.AutoTheme, Help: "Color theme of the full style: " + strings.Join(pretty.ThemeNames, ", ") + ". By default, the theme depends on the terminal background"}),
+		plain:          parser.Flag("p", "plain", &argparse.Options{Help: "Use plain style. Ideal for machine consumption. Used by default when redirecting the output"}),
+		format:         parser.Selector("", "format", search.Formats, &argparse.Options{Default: "text", Help: "Output format: text uses the selected style, json prints a single document, jsonl one record per line, html a self-contained report, locations path:line:column lines and sarif a SARIF 2.1.0 log for code scanning platforms"}),
+		output:         parser.Selector("", "output", outputPresets, &argparse.Options{Help: "Output preset: locations prints path:line:column for each comment without any decoration, for the file pickers of Helix and Kakoune. Same as --format locations"}),
+		workers:        parser.Int("w", "workers", &argparse.Options{Default: 128, Help: "[debug] Number of search workers. There's likely no need to change this"}),
+		pprof:          addPprofFlag(parser),
+		verbose:        parser.Flag("v", "verbose", &argparse.Options{Help: "Enable info logging level"}),
+		debug:          parser.Flag("d", "debug", &argparse.Options{Help: "Add debug verbosity"}),
+		logFormat:      parser.Selector("", "log-format", logFormats, &argparse.Options{Default: "text", Help: "Format of log messages: text or json"}),
+		logFile:        parser.String("", "log-file", &argparse.Options{Help: "Append log messages to this file instead of stderr"}),
+	}
+}
+
+// isSet reports whether the flag with the provided long name was given on the command line.
+func (f *searchFlags) isSet(name string) bool {
+	for _, arg := range f.parser.GetArgs() {
+		if arg.GetLname() == name {
+			return arg.GetParsed()
+		}
+	}
+	return false
+}
+
+// applyConfig replaces the defaults of the flags that weren't given on the command
+// line with the settings of the configuration file.
+func (f *searchFlags) applyConfig(cfg *config.Config) error {
+	if err := validateConfigDefaults(cfg); err != nil {
+		return err
+	}
+	if len(cfg.Tags) > 0 && !f.isSet("tags") {
+		*f.tags = cfg.Tags
+	}
+	if cfg.Glob != "" && !f.isSet("glob") {
+		*f.glob = cfg.Glob
+	}
+	if !f.isSet("bw") && !f.isSet("plain") {
+		*f.bw = cfg.Style == "bw"
+		*f.plain = cfg.Style == "plain"
+	}
+	if cfg.OldCommitLimit != nil && !f.isSet("old-commit-mark-limit") {
+		*f.oldCommitLimit = *cfg.OldCommitLimit
+	}
+	if cfg.Workers > 0 && !f.isSet("workers") {
+		*f.workers = cfg.Workers
+	}
+	if cfg.Lint.MinLength > 0 && !f.isSet("lint-min-length") {
+		*f.lintMinLength = cfg.Lint.MinLength
+	}
+	return nil
+}
+
+// validateConfigDefaults checks the settings of the configuration file that replace flag defaults.
+func validateConfigDefaults(cfg *config.Config) error {
+	if err := validateTags(cfg.Tags); err != nil {
+		return fmt.Errorf("invalid tags in config file: %s", err)
+	}
+	if cfg.Style != "" && !slices.Contains(config.Styles, cfg.Style) {
+		return fmt.Errorf("invalid style %q in config file, expected one of %s", cfg.Style, strings.Join(config.Styles, ", "))
+	}
+	if cfg.OldCommitLimit != nil && *cfg.OldCommitLimit < 0 {
+		return fmt.Errorf("invalid old_commit_limit in config file: it must be a non-negative number of days")
+	}
+	if cfg.Workers < 0 {
+		return fmt.Errorf("invalid workers in config file: it must be a positive integer")
+	}
+	if slices.ContainsFunc(cfg.Exclude, func(dir string) bool { return dir == "" || strings.ContainsAny(dir, `/\`) }) {
+		return fmt.Errorf("invalid exclude in config file: entries must be directory names")
+	}
+	if cfg.Lint.MinLength < 0 {
+		return fmt.Errorf("invalid lint min_length in config file: it must be a non-negative number of characters")
+	}
+	return nil
+}
+
+// lintRequirements lists the values of --lint-require.
+var lintRequirements = []string{"assignee", "issue"}
+
+func validateLintRequire(args []string) error {
+	for _, req := range splitTags(args) {
+		if !slices.Contains(lintRequirements, strings.ToLower(req)) {
+			return fmt.Errorf("unknown lint requirement %q, expected one of %s", req, strings.Join(lintRequirements, ", "))
+		}
+	}
+	return nil
+}
+
+// lintRules returns the lint rules of the configuration file and the flags, or nil
+// if comments aren't linted.
+func (f *searchFlags) lintRules(cfg *config.Config) *search.LintRules {
+	required := splitTags(*f.lintRequire)
+	if !*f.lint && !f.isSet("lint-min-length") && len(required) == 0 {
+		return nil
+	}
+	rules := &search.LintRules{
+		MinLength:       *f.lintMinLength,
+		RequireAssignee: cfg.Lint.RequireAssignee,
+		RequireIssue:    cfg.Lint.RequireIssue,
+	}
+	for _, req := range required {
+		switch strings.ToLower(req) {
+		case "assignee":
+			rules.RequireAssignee = true
+		case "issue":
+			rules.RequireIssue = true
+		}
+	}
+	return rules
+}
+
+// options validates the parsed flags, sets up logging and returns the search options.
+func (f *searchFlags) options() search.Options {
+	if err := setupLogging(*f.verbose, *f.debug, *f.logFormat, *f.logFile); err != nil {
+		fatal(err)
+	}
+
+	if *f.maxFileSize <= 0 {
+		fatal(fmt.Errorf("max-file-size must be a positive integer"))
+	}
+	if *f.maxLineLength <= 0 {
+		fatal(fmt.Errorf("max-line-length must be a positive integer"))
+	}
+	if *f.maxOpenFiles < 0 {
+		fatal(fmt.Errorf("max-open-files must be a non-negative integer"))
+	}
+	if *f.lintMinLength < 0 {
+		fatal(fmt.Errorf("lint-min-length must be a non-negative integer"))
+	}
+
+	if *f.pprof != "" {
+		startPprof(*f.pprof)
+	}
+
+	if err := setupGitEnv(*f.gitDir, *f.workTree); err != nil {
+		fatal(err)
+	}
+
+	cfg, err := config.Load(*f.path)
+	if err != nil {
+		fatal(err)
+	}
+	if err := f.applyConfig(cfg); err != nil {
+		fatal(err)
+	}
+	f.config = cfg
+
+	style, err := pretty.GetStyle(*f.bw, *f.plain)
+	if err != nil {
+		fatal(err)
+	}
+	if err := pretty.SetTheme(*f.theme, style); err != nil {
+		fatal(err)
+	}
+
+	outFormat, err := search.ParseFormat(*f.format)
+	if err != nil {
+		fatal(err)
+	}
+	if *f.output != "" {
+		if outFormat != search.TextFormat && outFormat != search.LocationsFormat {
+			fatal(fmt.Errorf("--output %s can't be used with --format %s", *f.output, *f.format))
+		}
+		// locations is the only preset
+		outFormat = search.LocationsFormat
+	}
+
+	ageMode, err := blame.ParseAgeMode(*f.ageMode)
+	if err != nil {
+		fatal(err)
+	}
+
+	locale := cfg.Locale
+	if locale == "" {
+		locale = i18n.FromEnv()
+	}
+	if err := i18n.SetLocale(locale); err != nil {
+		fatal(err)
+	}
+	commentPrefixes := make(map[string][]string, len(cfg.CommentPrefixes))
+	for ext, prefixes := range cfg.CommentPrefixes {
+		commentPrefixes[ext] = prefixes
+	}
+
+	var ageTiers []pretty.AgeTier
+	for _, tier := range cfg.AgeTiers {
+		if tier.Label == "" || tier.Days < 0 {
+			fatal(fmt.Errorf("invalid age tier in config file: a label and a non-negative number of days are required"))
+		}
+		ageTiers = append(ageTiers, pretty.AgeTier{Label: tier.Label, Days: tier.Days, Color: tier.Color})
+	}
+	if len(*f.ageTiers) > 0 {
+		ageTiers = ageTiers[:0]
+		for _, s := range *f.ageTiers {
+			tier, err := pretty.ParseAgeTier(s)
+			if err != nil {
+				fatal(err)
+			}
+			ageTiers = append(ageTiers, tier)
+		}
+	}
+
+	authorRules := make([]search.AuthorRule, 0, len(cfg.Authors))
+	for _, rule := range cfg.Authors {
+		if rule.Match == "" || (rule.Name == "") == !rule.Exclude {
+			fatal(fmt.Errorf("invalid author rule in config file: a match and either a name or exclude are required"))
+		}
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			fatal(fmt.Errorf("invalid author rule in config file: %s", err))
+		}
+		authorRules = append(authorRules, search.AuthorRule{Match: match, Name: rule.Name, Exclude: rule.Exclude})
+	}
+
+	for ext, size := range cfg.MaxFileSizes {
+		if size <= 0 {
+			fatal(fmt.Errorf("invalid max_file_sizes in config file: the size of %s files must be a positive integer", ext))
+		}
+	}
+
+	// validated by validateTimeout
+	var timeout, blameTimeout time.Duration
+	if *f.timeout != "" {
+		timeout, _ = time.ParseDuration(*f.timeout)
+	}
+	if *f.blameTimeout != "" {
+		blameTimeout, _ = time.ParseDuration(*f.blameTimeout)
+	}
+
+	dateFormat := pretty.NoDate
+	if *f.showDate {
+		dateFormat, err = pretty.ParseDateFormat(*f.dateFormat)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	if *f.trackedOnly && *f.inclUntracked {
+		fatal(fmt.Errorf("--tracked-only and --include-untracked can't be used together"))
+	}
+	trackedOnly := *f.trackedOnly
+	if !*f.trackedOnly && !*f.inclUntracked && cfg.IncludeUntracked != nil && !*cfg.IncludeUntracked && !*f.noGitAll {
+		// the configuration only applies inside repositories, the user configuration file
+		// is also used elsewhere, where every file is untracked
+		_, err := matcher.RepoRoot(*f.path)
+		trackedOnly = err == nil
+	}
+
+	maxPerFile := *f.maxPerFile
+	if *f.all || maxPerFile < 0 {
+		maxPerFile = 0
+	}
+
+	searchTags := splitTags(*f.tags)
+	for _, s := range *f.tagDefs {
+		def, err := pretty.ParseTagDef(s)
+		if err != nil {
+			fatal(err)
+		}
+		pretty.RegisterTag(def)
+		if !slices.Contains(searchTags, def.Name) {
+			searchTags = append(searchTags, def.Name)
+		}
+	}
+	if excluded := splitTags(*f.excludeTags); len(excluded) > 0 {
+		searchTags = slices.DeleteFunc(searchTags, func(tag string) bool {
+			return slices.Contains(excluded, tag)
+		})
+		if len(searchTags) == 0 {
+			fatal(fmt.Errorf("all tags are excluded, there's nothing to search for"))
+		}
+	}
+
+	return search.Options{
+		Path:               *f.path,
+		Tags:               searchTags,
+		ExtensionTags:      cfg.ExtensionTags,
+		AuthorRules:        authorRules,
+		CommentPrefixes:    commentPrefixes,
+		FuzzyTags:          *f.fuzzyTags,
+		DocumentationRules: cfg.DocumentationRules,
+		Tasks:              *f.tasks,
+		Workers:            *f.workers,
+		Style:              style,
+		Format:             outFormat,
+		OldCommitLimit:     *f.oldCommitLimit,
+		AgeTiers:           ageTiers,
+		DateFormat:         dateFormat,
+		ShowHash:           *f.showHash,
+		ShowOwner:          *f.showOwner,
+		ShowLanguage:       *f.showLanguage,
+		ShowSymbol:         *f.showSymbol,
+		Types:              splitTags(*f.types),
+		ShowOldest:         *f.showOldest,
+		Owner:              *f.owner,
+		CommitAgeFilter:    *f.ageFilter,
+		MaxFileSize:        int64(*f.maxFileSize),
+		MaxFileSizes:       cfg.MaxFileSizes,
+		MaxOpenFiles:       *f.maxOpenFiles,
+		MaxLineLength:      *f.maxLineLength,
+		FullPath:           *f.fullPath,
+		NoSummary:          *f.noSummary,
+		SkipReport:         *f.skipReport,
+		BySeverity:         *f.bySeverity,
+		MaxPerFile:         maxPerFile,
+		WrapIndent:         *f.wrapIndent,
+		WrapMarker:         *f.wrapMarker,
+		NoAuthor:           *f.noAuthor,
+		NoGit:              *f.noGit,
+		NoGitEverything:    *f.noGitAll,
+		NoDefaultExcludes:  *f.noDefExcludes,
+		ExcludeDirs:        cfg.Exclude,
+		NoCache:            *f.noCache,
+		UseIndex:           *f.useIndex,
+		TrackedOnly:        trackedOnly,
+		FetchBlame:         *f.fetchBlame,
+		PrefetchBlame:      *f.prefetchBlame,
+		AgeMode:            ageMode,
+		BlameOptions:       splitTags(*f.blameOpts),
+		LocalAuthor:        *f.localAuthor,
+		Glob:               *f.glob,
+		Author:             *f.author,
+		Context:            interruptContext(),
+		Timeout:            timeout,
+		BlameTimeout:       blameTimeout,
+		Resume:             *f.resume,
+		Lint:               f.lintRules(cfg),
+	}
+}
+
+// interruptContext returns a context cancelled by SIGINT or SIGTERM, so searches stop
+// cleanly and print the results found so far. A second signal terminates listme.
+func interruptContext() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx
+}
+
+// requireComplete is used by commands that act on every result of a search,
+// which exit instead of acting on partial results if the search was interrupted.
+func requireComplete(stats *search.Stats) *search.Stats {
+	if stats.Interrupted() {
+		fatal(fmt.Errorf("the search was interrupted, nothing was done"))
+	}
+	return stats
+}
+
+// setupGitEnv sets GIT_DIR and GIT_WORK_TREE from the flags, if provided, and makes
+// them absolute, since git commands run from the directory of each file. If only
+// GIT_DIR is set, the working directory is the work tree, as in git.
+func setupGitEnv(gitDir, workTree string) error {
+	if gitDir != "" {
+		os.Setenv("GIT_DIR", gitDir)
+	}
+	if workTree != "" {
+		os.Setenv("GIT_WORK_TREE", workTree)
+	}
+	if os.Getenv("GIT_DIR") != "" && os.Getenv("GIT_WORK_TREE") == "" {
+		os.Setenv("GIT_WORK_TREE", ".")
+	}
+	for _, key := range []string{"GIT_DIR", "GIT_WORK_TREE"} {
+		if os.Getenv(key) == "" {
+			continue
+		}
+		abs, err := filepath.Abs(os.Getenv(key))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", key, err)
+		}
+		os.Setenv(key, abs)
+	}
+	return nil
+}
+
+// setupLogging sets the default slog logger. Log messages never go to stdout,
+// which is reserved for results: they're written to stderr or, if provided, appended to logFile.
+func setupLogging(verbose, debug bool, logFormat, logFile string) error {
+	level := slog.LevelWarn
+	if verbose {
+		level = slog.LevelInfo
+	}
+	if debug {
+		level = slog.LevelDebug
+	}
+
+	var w io.Writer = os.Stderr
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %s", err)
+		}
+		w = f
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch logFormat {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		if logFile == "" {
+			// timestamps are noise on an interactive terminal
+			opts.ReplaceAttr = dropTime
+		}
+		handler = slog.NewTextHandler(w, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+// Exit statuses of listme.
+//   - exitOK: the search finished, with or without matches
+//   - exitMatches: matches were found with --exit-nonzero-on-match, tags of --fail-on above their
+//     --max-count, or tags of fail_on with --ci
+//   - exitError: invalid arguments or configuration, the search was interrupted, or files
+//     couldn't be read (see search.Stats.Failed). Skipped files and failed git blame are
+//     only reported on stderr, so they don't hide the result of --fail-on or --ci
+const (
+	exitOK      = 0
+	exitMatches = 1
+	exitError   = 2
+)
+
+// exitZero makes listme always exit with exitOK, set by --exit-zero.
+var exitZero bool
+
+// exit terminates listme with the status code, unless exitZero is set.
+func exit(code int) {
+	if exitZero {
+		code = exitOK
+	}
+	os.Exit(code)
+}
+
+// fatal logs the error and exits with exitError.
+func fatal(err error) {
+	slog.Error(err.Error())
+	exit(exitError)
+}
+
+// addPprofFlag registers the --pprof flag, which only exists in builds with the pprof
+// tag (see pprof.go), so the profiling endpoints stay out of release binaries and of
+// the help. Otherwise, the returned address is always empty.
+var addPprofFlag = func(parser *argparse.Parser) *string { return new(string) }
+
+// startPprof serves the net/http/pprof endpoints on addr in the background. It's only
+// set in builds with the pprof tag, where --pprof can be given.
+var startPprof func(addr string)
+
+// relPath returns the path relative to the working directory, using forward slashes,
+// if it's inside of it. Otherwise, the path is returned unchanged.
+func relPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+// printDiff prints the edit as a unified diff to stdout, colored according to the style.
+func printDiff(e *edit.FileEdit, style pretty.Style) {
+	pretty.RenderDiff(os.Stdout, e.Diff(diffName(e.Path)), style)
+}
+
+// diffName returns the name of the file in the headers of a diff: its path relative to
+// the root of its repository, as in git diff, so the diff can be applied with git apply.
+// Outside of repositories, the path is relative to the working directory if it's inside
+// of it, and absolute otherwise.
+func diffName(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return relPath(path)
+	}
+	if root, err := matcher.RepoRoot(abs); err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return relPath(abs)
+}
+
+func parseArgs(parser *argparse.Parser, args []string) {
+	err := parser.Parse(args)
+	if err != nil {
+		fmt.Fprint(os.Stderr, parser.Usage(err))
+		exit(exitError)
+	}
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[1:])
+			return
+		}
+	}
+
 	parser := argparse.NewParser("listme", "Summarize you FIXME, TODO, XXX (and other tags) comments so you don't forget them.")
-	path := parser.StringPositional(&argparse.Options{Help: "Path to folder or file to be searched. Search is recursive."})
-	tags := parser.StringList("T", "tags", &argparse.Options{Default: tags, Validate: validateTags, Help: "Tags to search for, input should be separated by spaces"})
-	glob := parser.String("g", "glob", &argparse.Options{Default: "*", Help: "Glob pattern to filter files in the search. Use a single-quoted string. Example: '*.go'"})
-	author := parser.String("a", "author", &argparse.Options{Help: "Filter lines by commit author"})
-	ageFilter := parser.Int("n", "newer-than", &argparse.Options{Default: -1, Help: "Filters lines based on the age of commits, showing only lines committed within the specified number of days"})
-	oldCommitLimit := parser.Int("o", "old-commit-mark-limit", &argparse.Options{Default: 60, Help: "Sets the age limit for marking commits as old, with commits older than the specified limit being marked"})
-	maxFileSize := parser.Int("f", "max-file-size", &argparse.Options{Default: 5, Help: "Maximum file size to scan (in MB)"})
-	fullPath := parser.Flag("F", "full-path", &argparse.Options{Help: "Print full absolute path of the files"})
-	noAuthor := parser.Flag("A", "no-author", &argparse.Options{Help: "Do not print git author information"})
-	noSummary := parser.Flag("S", "no-summary", &argparse.Options{Help: "Do not print summary box for each file"})
-	bw := parser.Flag("b", "bw", &argparse.Options{Help: "Use black and white style"})
-	plain := parser.Flag("p", "plain", &argparse.Options{Help: "Use plain style. Ideal for machine consumption. Used by default when redirecting the output"})
-	workers := parser.Int("w", "workers", &argparse.Options{Default: 128, Help: "[debug] Number of search workers. There's likely no need to change this"})
-	verbose := parser.Flag("v", "verbose", &argparse.Options{Help: "Enable info logging level"})
-	debug := parser.Flag("d", "debug", &argparse.Options{Help: "Add debug verbosity"})
-
-	err := parser.Parse(os.Args)
+	flags := addSearchFlags(parser)
+	stats := parser.Flag("", "stats", &argparse.Options{Help: "Print totals per tag, number of files scanned and skipped, and elapsed time at the end"})
+	ordered := parser.Flag("", "ordered", &argparse.Options{Help: "Print files in a deterministic order (the order of the walk) while still streaming results as soon as all earlier files are scanned"})
+	timings := parser.Flag("", "timings", &argparse.Options{Help: "Print per-phase durations and the slowest files to stderr"})
+	copyReport := parser.Flag("", "copy", &argparse.Options{Help: "Also copy the results to the system clipboard, in the plain style format"})
+	quickfix := parser.String("", "write-quickfix", &argparse.Options{Help: "Also write the results to a file as a jump list for editors, e.g. .listme.qf, loaded with :cfile in Vim or opened in Emacs"})
+	quickfixFormat := parser.Selector("", "quickfix-format", search.QuickfixFormats, &argparse.Options{Default: "vim", Help: "Format of the --write-quickfix file: vim (Vim and Neovim errorformat) or emacs (compilation-mode)"})
+	browser := parser.Flag("", "browser", &argparse.Options{Help: "Write the HTML report to a temporary file and open it with the default browser"})
+	listFiles := parser.Flag("", "list-files", &argparse.Options{Help: "Print the files that would be searched, one per line, without searching them"})
+	exitOnMatch := parser.Flag("", "exit-nonzero-on-match", &argparse.Options{Help: "Exit with status 1 if any tagged comment is found"})
+	exitZeroFlag := parser.Flag("", "exit-zero", &argparse.Options{Help: "Always exit with status 0, even if errors are found"})
+	patch := parser.Flag("", "patch", &argparse.Options{Help: "Read a unified diff from stdin, e.g. from git diff, and only report the tagged comments of its added lines. The changed files must be at the new version of the diff"})
+	failOnTags := parser.StringList("", "fail-on", &argparse.Options{Validate: validateTags, Help: "Exit with status 1 if any of these tags is found, separated by commas, e.g. --fail-on FIXME,BUG. Replaces fail_on of the configuration file with --ci"})
+	maxCounts := parser.StringList("", "max-count", &argparse.Options{Validate: validateMaxCounts, Help: "Number of comments allowed before --fail-on fails, either for all of its tags, e.g. --max-count 5, or per tag with TAG:N, e.g. --max-count TODO:20. Can be repeated"})
+	ci := parser.Flag("", "ci", &argparse.Options{Help: "Preset for CI pipelines: plain output (GitHub annotations in GitHub Actions), deterministic order, and a non-zero exit status if tags listed in fail_on of the configuration file are found"})
+	// usage errors happen before the flags are available
+	exitZero = slices.Contains(os.Args, "--exit-zero")
+	parseArgs(parser, os.Args)
+	exitZero = *exitZeroFlag
+
+	opts := flags.options()
+	failTags := splitTags(*failOnTags)
+	if len(failTags) == 0 && *ci {
+		failTags = flags.config.FailOn
+	}
+	thresholds, err := failThresholds(failTags, splitTags(*maxCounts))
 	if err != nil {
-		fmt.Print(parser.Usage(err))
-		panic(err)
+		fatal(err)
 	}
-
-	if *maxFileSize <= 0 {
-		panic("max-file-size must be a positive integer")
+	for tag := range thresholds {
+		if !slices.Contains(opts.Tags, tag) {
+			slog.Warn(fmt.Sprintf("%s is not searched for, so it never makes listme fail", tag))
+		}
+	}
+	if *patch {
+		if opts.Patch, err = readPatch(os.Stdin); err != nil {
+			fatal(err)
+		}
+	}
+	opts.Stats = *stats
+	opts.Ordered = *ordered
+	opts.Timings = *timings
+	// humans piping the output still get feedback, scripts reading stderr don't
+	opts.ExitSummary = !pretty.IsTerminal(os.Stdout) && pretty.IsTerminal(os.Stderr)
+	if *listFiles {
+		// blame is never needed
+		opts.NoGit = true
+		params, err := search.NewSearchParams(opts)
+		if err != nil {
+			fatal(err)
+		}
+		if listStats := search.ListFiles(params); listStats.Failed() {
+			exit(exitError)
+		}
+		return
+	}
+	if *ci {
+		opts.Style = pretty.PlainStyle
+		opts.Ordered = true
+		opts.BySeverity = true
+		if opts.Format == search.TextFormat && os.Getenv("GITHUB_ACTIONS") == "true" {
+			opts.Format = search.GitHubFormat
+		}
+	}
+	var htmlReport *os.File
+	if *browser {
+		f, err := os.CreateTemp("", "listme-*.html")
+		if err != nil {
+			fatal(fmt.Errorf("failed to create HTML report: %s", err))
+		}
+		htmlReport = f
+		opts.Format = search.HTMLFormat
+		opts.Output = f
+	}
+	var quickfixFile *os.File
+	if *quickfix != "" {
+		f, err := os.Create(*quickfix)
+		if err != nil {
+			fatal(fmt.Errorf("failed to create quickfix file: %s", err))
+		}
+		quickfixFile = f
+		opts.Quickfix = f
+		// validated by the selector
+		opts.QuickfixFormat, _ = search.ParseQuickfixFormat(*quickfixFormat)
+	}
+	var report strings.Builder
+	if *copyReport {
+		opts.Collect = func(m search.JSONMatch) {
+			var date time.Time
+			if m.Date != nil {
+				date = *m.Date
+			}
+			search.WritePlainLine(&report, m.Path, m.Line, m.Column, m.Tag, m.Commit, date, m.Text)
+		}
+	}
+	params, err := search.NewSearchParams(opts)
+	if err != nil {
+		fatal(err)
 	}
+	searchStats := search.Search(params)
 
-	logging.SetFormatter(format)
-	b := logging.NewLogBackend(os.Stderr, "", 0)
-	bFormatter := logging.NewBackendFormatter(b, format)
-	logging.SetBackend(bFormatter)
-	logging.SetLevel(logging.WARNING, "")
-	if *verbose {
-		logging.SetLevel(logging.INFO, "")
+	if *copyReport {
+		if err := clipboard.Write(report.String()); err != nil {
+			fatal(fmt.Errorf("failed to copy results to the clipboard: %s", err))
+		}
+		slog.Info("results copied to the clipboard")
+	}
+	if quickfixFile != nil {
+		if err := quickfixFile.Close(); err != nil {
+			fatal(fmt.Errorf("failed to write quickfix file: %s", err))
+		}
+		slog.Info("quickfix file written", "path", quickfixFile.Name())
 	}
-	if *debug {
-		logging.SetLevel(logging.DEBUG, "")
+	if htmlReport != nil {
+		if err := htmlReport.Close(); err != nil {
+			fatal(fmt.Errorf("failed to write HTML report: %s", err))
+		}
+		slog.Info("HTML report written", "path", htmlReport.Name())
+		if err := openBrowser(htmlReport.Name()); err != nil {
+			fatal(err)
+		}
+	}
+	if searchStats.Interrupted() || searchStats.Failed() {
+		exit(exitError)
+	}
+	failOn(searchStats, thresholds)
+	if *exitOnMatch && searchStats.Total() > 0 {
+		exit(exitMatches)
 	}
+}
 
-	style, err := pretty.GetStyle(*bw, *plain)
+// readPatch returns the lines added by the unified diff read from f.
+func readPatch(f *os.File) (search.Patch, error) {
+	if pretty.IsTerminal(f) {
+		return nil, fmt.Errorf("--patch reads a diff from stdin, e.g. git diff | listme --patch")
+	}
+	lines, err := hook.ParseDiff(f)
 	if err != nil {
-		log.Fatal(err)
-	}
-
-	params, err := search.NewSearchParams(
-		*path,
-		*tags,
-		*workers,
-		style,
-		*oldCommitLimit,
-		*ageFilter,
-		int64(*maxFileSize),
-		*fullPath,
-		*noSummary,
-		*noAuthor,
-		*glob,
-		*author,
-	)
+		return nil, fmt.Errorf("failed to read the diff: %s", err)
+	}
+	patch := make(search.Patch)
+	for _, line := range lines {
+		if !line.Removed {
+			patch.Add(line.Path, line.N, line.Text)
+		}
+	}
+	return patch, nil
+}
+
+// failThresholds returns the number of comments allowed per tag before failing. The
+// tags allow none unless maxCounts sets a number for all of them, e.g. 5, or for a
+// single tag, e.g. TODO:20, which is checked even if it isn't one of the tags.
+func failThresholds(tags, maxCounts []string) (map[string]int, error) {
+	thresholds := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		thresholds[tag] = 0
+	}
+	for _, s := range maxCounts {
+		tag, count, err := parseMaxCount(s)
+		if err != nil {
+			return nil, err
+		}
+		if tag != "" {
+			thresholds[tag] = count
+			continue
+		}
+		if len(tags) == 0 {
+			return nil, fmt.Errorf("--max-count %s requires --fail-on tags, or use TAG:N", s)
+		}
+		for _, tag := range tags {
+			thresholds[tag] = count
+		}
+	}
+	return thresholds, nil
+}
+
+// parseMaxCount parses a --max-count value, N or TAG:N. The tag is empty for N.
+func parseMaxCount(s string) (string, int, error) {
+	tag, value, found := strings.Cut(s, ":")
+	if !found {
+		tag, value = "", s
+	} else if !tagValRegex.MatchString(tag) {
+		return "", 0, fmt.Errorf("invalid max count %q: tags must be non-empty and contain only alphanumeric characters", s)
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return "", 0, fmt.Errorf("invalid max count %q: expected a non-negative number, optionally preceded by a tag, e.g. 5 or TODO:20", s)
+	}
+	return tag, count, nil
+}
+
+func validateMaxCounts(values []string) error {
+	for _, s := range splitTags(values) {
+		if _, _, err := parseMaxCount(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failOn exits with exitMatches if any tag was found more times than its threshold.
+func failOn(stats *search.Stats, thresholds map[string]int) {
+	found := stats.Tags()
+	tags := make([]string, 0, len(thresholds))
+	for tag := range thresholds {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	var failed []string
+	for _, tag := range tags {
+		count, limit := found[tag], thresholds[tag]
+		if count <= limit {
+			continue
+		}
+		msg := fmt.Sprintf("%d comment(s) tagged with %s", count, tag)
+		if limit > 0 {
+			msg += fmt.Sprintf(", more than the %d allowed", limit)
+		}
+		failed = append(failed, msg)
+	}
+	if len(failed) > 0 {
+		slog.Error("found " + strings.Join(failed, "; "))
+		exit(exitMatches)
+	}
+}
+
+func runStats(args []string) {
+	parser := argparse.NewParser("listme stats", "Print statistics about tagged comments, broken down by file extension.")
+	flags := addSearchFlags(parser)
+	byOwner := parser.Flag("", "by-owner", &argparse.Options{Help: "Break the statistics down by CODEOWNERS owner instead, with the average age of the comments of each owner"})
+	coOccurrence := parser.Flag("", "co-occurrence", &argparse.Options{Help: "Show how many files have each pair of tags instead, and the files that mix tags the most"})
+	parseArgs(parser, args)
+
+	opts := flags.options()
+	if opts.Format == search.HTMLFormat || opts.Format == search.LocationsFormat || opts.Format == search.SARIFFormat {
+		fatal(fmt.Errorf("the %s format is not supported by stats", *flags.format))
+	}
+	if *byOwner && *coOccurrence {
+		fatal(fmt.Errorf("--by-owner and --co-occurrence can't be used together"))
+	}
+	opts.Quiet = true
+	opts.ByOwner = *byOwner
+	opts.CoOccurrence = *coOccurrence
+	params, err := search.NewSearchParams(opts)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
-	search.Search(params)
+	stats := search.Search(params)
+	stats.RenderReport(opts.Style, opts.Format)
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseMaxCount(t *testing.T) {
+	tests := []struct {
+		value string
+		tag   string
+		count int
+		err   bool
+	}{
+		{value: "5", count: 5},
+		{value: "0", count: 0},
+		{value: "TODO:20", tag: "TODO", count: 20},
+		{value: "FIX_ME2:0", tag: "FIX_ME2", count: 0},
+		{value: "", err: true},
+		{value: "five", err: true},
+		{value: "-1", err: true},
+		{value: "TODO:", err: true},
+		{value: "TODO:-3", err: true},
+		{value: ":5", err: true},
+		{value: "TO-DO:5", err: true},
+		{value: "TODO:5:6", err: true},
+	}
+	for _, test := range tests {
+		tag, count, err := parseMaxCount(test.value)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %q and %d", test.value, tag, count)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.value, err)
+			continue
+		}
+		if tag != test.tag || count != test.count {
+			t.Errorf("%q: expected %q and %d, got %q and %d", test.value, test.tag, test.count, tag, count)
+		}
+	}
+}
+
+func TestFailThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      []string
+		maxCounts []string
+		expected  map[string]int
+		err       bool
+	}{
+		{
+			name:     "no max count",
+			tags:     []string{"BUG", "FIXME"},
+			expected: map[string]int{"BUG": 0, "FIXME": 0},
+		},
+		{
+			name:      "global",
+			tags:      []string{"BUG", "FIXME"},
+			maxCounts: []string{"3"},
+			expected:  map[string]int{"BUG": 3, "FIXME": 3},
+		},
+		{
+			name:      "per tag",
+			tags:      []string{"BUG", "FIXME"},
+			maxCounts: []string{"FIXME:10"},
+			expected:  map[string]int{"BUG": 0, "FIXME": 10},
+		},
+		{
+			name:      "per tag overrides global",
+			tags:      []string{"BUG", "FIXME"},
+			maxCounts: []string{"3", "FIXME:10"},
+			expected:  map[string]int{"BUG": 3, "FIXME": 10},
+		},
+		{
+			name:      "global after per tag",
+			tags:      []string{"BUG", "FIXME"},
+			maxCounts: []string{"FIXME:10", "3"},
+			expected:  map[string]int{"BUG": 3, "FIXME": 3},
+		},
+		{
+			name:      "tag not in fail-on",
+			tags:      []string{"BUG"},
+			maxCounts: []string{"TODO:20"},
+			expected:  map[string]int{"BUG": 0, "TODO": 20},
+		},
+		{
+			name:      "per tag without fail-on",
+			maxCounts: []string{"TODO:20"},
+			expected:  map[string]int{"TODO": 20},
+		},
+		{
+			name:      "global without fail-on",
+			maxCounts: []string{"5"},
+			err:       true,
+		},
+		{
+			name:      "invalid count",
+			tags:      []string{"BUG"},
+			maxCounts: []string{"BUG:many"},
+			err:       true,
+		},
+		{
+			name:      "invalid tag",
+			tags:      []string{"BUG"},
+			maxCounts: []string{"B-UG:1"},
+			err:       true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			thresholds, err := failThresholds(test.tags, test.maxCounts)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", thresholds)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !maps.Equal(thresholds, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, thresholds)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+//go:build libgit2
+
+package matcher
+
+import (
+	"path/filepath"
+
+	git "github.com/libgit2/git2go/v34"
+)
+
+func init() {
+	newIgnoreChecker = libgit2IgnoreChecker
+}
+
+// libgit2IgnoreChecker checks paths with libgit2, which applies every ignore
+// rule of the repository, including .git/info/exclude and the global excludes file.
+func libgit2IgnoreChecker(repoRoot string) (func(path string) bool, error) {
+	repo, err := git.OpenRepository(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	return func(path string) bool {
+		rel, err := filepath.Rel(repoRoot, path)
+		if err != nil || rel == "." {
+			return false
+		}
+		ignored, err := repo.IsPathIgnored(filepath.ToSlash(rel))
+		return err == nil && ignored
+	}, nil
+}
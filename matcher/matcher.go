@@ -3,16 +3,15 @@ package matcher
 import (
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
-	"github.com/op/go-logging"
 	gitignore "github.com/sabhiram/go-gitignore"
 )
 
-var log = logging.MustGetLogger("listme")
-
 const (
 	// gitDirName is a special folder where all the git stuff is.
 	gitDirName = ".git"
@@ -31,16 +30,30 @@ const (
 //   - Match: file should be scanned
 //   - GitIgnore: ignored due to .gitignore
 //   - GlobIgnore: ignored due to glob pattern
+//
+// InGitRepo reports whether the searched path is inside a git repository.
+//
+// Explain also returns why a path is ignored, see matcher.Explain.
 type Matcher interface {
 	Match(path string) MatchType
+	Explain(path string) (MatchType, string)
+	InGitRepo() bool
 }
 
 type matcher struct {
-	root string
-	gi   map[string]*gitignore.GitIgnore
-	glob string
+	root    string
+	gitDir  string
+	gi      map[string]*gitignore.GitIgnore
+	ignored func(path string) bool
+	glob    string
+	inRepo  bool
 }
 
+// newIgnoreChecker returns a function that reports whether a path of the repository
+// is ignored by git. It's only set when listme is built with an alternative backend
+// (see libgit2.go), otherwise the .gitignore files are parsed by listme itself.
+var newIgnoreChecker func(repoRoot string) (func(path string) bool, error)
+
 // NewMatcher returns a Matcher. If a git repository is found on the provided path or on a
 // parent directory, all .gitignore files are respected. The provided glob provides an additional
 // filter.
@@ -48,26 +61,39 @@ type matcher struct {
 // If a glob pattern is not needed, pass '*'.
 func NewMatcher(path string, glob string) Matcher {
 	path = filepath.Clean(path)
-	repoRoot, err := detectDotGit(path)
+	repoRoot, err := detectRepoRoot(path)
 	if err != nil {
-		log.Debugf("no git repository found in %s: %s", path, err)
-		return &matcher{root: path, gi: make(map[string]*gitignore.GitIgnore, 0), glob: glob}
+		slog.Debug("no git repository found", "path", path, "error", err)
+		return NewGlobMatcher(path, glob)
+	}
+	if newIgnoreChecker != nil {
+		ignored, err := newIgnoreChecker(repoRoot)
+		if err == nil {
+			return &matcher{root: repoRoot, gitDir: gitDir(), ignored: ignored, glob: glob, inRepo: true}
+		}
+		slog.Debug("ignore backend failed, falling back to .gitignore files", "error", err)
 	}
 	matchers, err := walkGitignore(repoRoot, path)
 	if err != nil {
-		log.Errorf("error while parsing .gitignore files: %s", err)
+		slog.Error("error while parsing .gitignore files", "error", err)
 	}
-	return &matcher{root: repoRoot, gi: matchers, glob: glob}
+	return &matcher{root: repoRoot, gitDir: gitDir(), gi: matchers, glob: glob, inRepo: true}
 
 }
 
+// NewGlobMatcher returns a Matcher that only filters by the glob pattern. It doesn't
+// look for a git repository, so .gitignore files are not respected.
+func NewGlobMatcher(path string, glob string) Matcher {
+	return &matcher{root: filepath.Clean(path), gi: make(map[string]*gitignore.GitIgnore, 0), glob: glob}
+}
+
 func walkGitignore(repoRoot string, refPath string) (map[string]*gitignore.GitIgnore, error) {
 	matchers := make(map[string]*gitignore.GitIgnore)
 
 	parseGitignore := func(path string) {
 		matcher, err := gitignore.CompileIgnoreFile(path)
 		if err != nil {
-			log.Warningf("failed to parse .gitignore %s: %v\n", path, err)
+			slog.Warn("failed to parse .gitignore", "path", path, "error", err)
 			return
 		}
 
@@ -77,7 +103,7 @@ func walkGitignore(repoRoot string, refPath string) (map[string]*gitignore.GitIg
 
 	walker := func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			log.Errorf("file walk error: %s", err)
+			slog.Error("file walk error", "path", path, "error", err)
 			return nil
 		}
 
@@ -88,7 +114,7 @@ func walkGitignore(repoRoot string, refPath string) (map[string]*gitignore.GitIg
 		}
 
 		if MatchGit(path) {
-			log.Debugf(".gitignore search: skipping .git folder %s", path)
+			slog.Debug(".gitignore search: skipping .git folder", "path", path)
 			return filepath.SkipDir
 		}
 
@@ -96,13 +122,13 @@ func walkGitignore(repoRoot string, refPath string) (map[string]*gitignore.GitIg
 		isSub, _ := isSubfolder(path, refPath)
 		isSubRev, _ := isSubfolder(refPath, path)
 		if !isSub && !isSubRev {
-			log.Debugf(".gitignore search: skipping %s since it's outside of %s hierachy", path, refPath)
+			slog.Debug(".gitignore search: skipping path outside of search hierarchy", "path", path, "root", refPath)
 			return filepath.SkipDir
 		}
 
 		// If an entire folder is ignored by a .gitignore, stop walking
 		if gitignoreMatch(matchers, path, repoRoot) {
-			log.Debugf(".gitignore search: skipping %s due to .gitignore patterns", path)
+			slog.Debug(".gitignore search: skipping path due to .gitignore patterns", "path", path)
 			return filepath.SkipDir
 		}
 
@@ -111,7 +137,7 @@ func walkGitignore(repoRoot string, refPath string) (map[string]*gitignore.GitIg
 			// Check if a .gitignore file exists in the directory
 			gitignorePath := filepath.Join(path, ".gitignore")
 			if _, err := os.Stat(gitignorePath); err == nil {
-				log.Debugf("parsing new .gitignore file: %s", gitignorePath)
+				slog.Debug("parsing new .gitignore file", "path", gitignorePath)
 				parseGitignore(gitignorePath)
 			}
 		}
@@ -127,14 +153,48 @@ func walkGitignore(repoRoot string, refPath string) (map[string]*gitignore.GitIg
 	return matchers, nil
 }
 
+func (m *matcher) InGitRepo() bool {
+	return m.inRepo
+}
+
+// Explain returns the match type of the path and, if it's ignored, the reason:
+// the GIT_DIR, the .gitignore pattern or the glob.
+func (m *matcher) Explain(path string) (MatchType, string) {
+	t := m.Match(path)
+	switch t {
+	case GitIgnore:
+		if m.gitDir != "" && isInside(path, m.gitDir) {
+			return t, "GIT_DIR " + m.gitDir
+		}
+		if m.ignored != nil {
+			return t, "git ignore rules"
+		}
+		_, pattern := gitignoreMatchHow(m.gi, path, m.root)
+		return t, pattern
+	case GlobIgnore:
+		return t, fmt.Sprintf("glob %q", m.glob)
+	}
+	return t, ""
+}
+
 func (m *matcher) Match(path string) MatchType {
-	if gitignoreMatch(m.gi, path, m.root) {
+	// a GIT_DIR inside the work tree may not be named .git
+	if m.gitDir != "" {
+		if isSub, _ := isSubfolder(path, m.gitDir); isSub {
+			return GitIgnore
+		}
+	}
+	if m.ignored != nil {
+		if m.ignored(path) {
+			return GitIgnore
+		}
+	} else if gitignoreMatch(m.gi, path, m.root) {
 		return GitIgnore
 	}
 	base := filepath.Base(path)
 	matched, err := filepath.Match(m.glob, base)
 	if err != nil {
-		log.Infof("glob match error with path %s: %s", path, err)
+		slog.Info("glob match error", "path", path, "error", err)
 		return Match
 	}
 	if !matched {
@@ -144,8 +204,15 @@ func (m *matcher) Match(path string) MatchType {
 }
 
 func gitignoreMatch(matchers map[string]*gitignore.GitIgnore, path string, root string) bool {
+	matched, _ := gitignoreMatchHow(matchers, path, root)
+	return matched
+}
+
+// gitignoreMatchHow is gitignoreMatch that also returns the matching pattern,
+// with the format path/to/.gitignore:line:pattern, relative to the root.
+func gitignoreMatchHow(matchers map[string]*gitignore.GitIgnore, path string, root string) (bool, string) {
 	if len(matchers) == 0 {
-		return false
+		return false, ""
 	}
 
 	dir := filepath.Dir(path)
@@ -154,23 +221,27 @@ func gitignoreMatch(matchers map[string]*gitignore.GitIgnore, path string, root
 		if ok {
 			checkPath, err := filepath.Rel(dir, path)
 			if err == nil {
-				if matcher.MatchesPath(checkPath) {
-					return true
+				if matched, pattern := matcher.MatchesPathHow(checkPath); matched {
+					source := filepath.Join(dir, ".gitignore")
+					if rel, err := filepath.Rel(root, source); err == nil {
+						source = rel
+					}
+					return true, fmt.Sprintf("%s:%d:%s", source, pattern.LineNo, pattern.Line)
 				}
 			} else {
-				log.Errorf("error while getting relative path from %s using %s as root: %s", path, root, err)
+				slog.Error("error while getting relative path", "path", path, "root", root, "error", err)
 			}
 		}
 
 		// Stop if we have reached the root of the repository
 		if dir == root {
-			return false
+			return false, ""
 		}
 
 		// Move up one directory in the hierarchy
 		parentDir := filepath.Dir(dir)
 		if parentDir == dir {
-			return false
+			return false, ""
 		}
 		dir = parentDir
 	}
@@ -181,6 +252,73 @@ func MatchGit(path string) bool {
 	return strings.HasSuffix(path, separator+gitDirName) || strings.Contains(path, separator+gitDirName+separator)
 }
 
+// gitDir returns the absolute path of GIT_DIR, if set.
+func gitDir() string {
+	dir := os.Getenv("GIT_DIR")
+	if dir == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	return abs
+}
+
+// RepoRoot returns the root of the git work tree that contains path, a file or a
+// directory, according to git rev-parse --show-toplevel. It fails if path is not
+// inside a work tree.
+func RepoRoot(path string) (string, error) {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %v", err)
+	}
+	root := strings.TrimSpace(string(out))
+	if root == "" {
+		return "", fmt.Errorf("%s is not inside a work tree", path)
+	}
+	return filepath.Clean(filepath.FromSlash(root)), nil
+}
+
+// detectRepoRoot returns the root of the work tree that contains path. As in git,
+// the GIT_WORK_TREE environment variable takes precedence over the search for a
+// .git directory, and if only GIT_DIR is set, the working directory is the root.
+// If no .git directory is found, git rev-parse is used as a fallback.
+func detectRepoRoot(path string) (string, error) {
+	root := os.Getenv("GIT_WORK_TREE")
+	if root == "" && os.Getenv("GIT_DIR") != "" {
+		var err error
+		if root, err = os.Getwd(); err != nil {
+			return "", err
+		}
+	}
+	if root == "" {
+		root, err := detectDotGit(path)
+		if err == nil {
+			return root, nil
+		}
+		// unusual layouts may have no .git entry in the hierarchy of path, ask git
+		if root, gitErr := RepoRoot(path); gitErr == nil && isInside(path, root) {
+			slog.Debug("found git repo root with git rev-parse", "path", root)
+			return root, nil
+		}
+		return "", err
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	if !isInside(path, root) {
+		return "", fmt.Errorf("path is outside of the work tree %s", root)
+	}
+	return root, nil
+}
+
 func detectDotGit(startDir string) (string, error) {
 	startDir, err := replaceTildeWithHomeDir(startDir)
 	if err != nil {
@@ -188,13 +326,13 @@ func detectDotGit(startDir string) (string, error) {
 	}
 
 	for {
-		log.Debugf("searching for git repo in %s", startDir)
+		slog.Debug("searching for git repo", "path", startDir)
 		if isSystemRoot(startDir) {
 			return "", fmt.Errorf("reached the system root directory")
 		}
 
 		if hasGitDirectory(startDir) {
-			log.Debugf("found git repo root in %s", startDir)
+			slog.Debug("found git repo root", "path", startDir)
 			return startDir, nil
 		}
 
@@ -211,7 +349,7 @@ func detectDotGit(startDir string) (string, error) {
 func isSubfolder(subfolder, parentFolder string) (bool, error) {
 	relPath, err := filepath.Rel(parentFolder, subfolder)
 	if err != nil {
-		log.Errorf("subfolder check failed: %s", err)
+		slog.Error("subfolder check failed", "error", err)
 		return false, err
 	}
 
@@ -219,6 +357,11 @@ func isSubfolder(subfolder, parentFolder string) (bool, error) {
 	return !strings.HasPrefix(relPath, ".."), nil
 }
 
+func isInside(path, dir string) bool {
+	isSub, _ := isSubfolder(path, dir)
+	return isSub
+}
+
 // Check if a directory is the system root
 func isSystemRoot(dir string) bool {
 	return dir == "/" || strings.HasSuffix(dir, `:\`)
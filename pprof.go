@@ -0,0 +1,26 @@
+//go:build pprof
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	_ "net/http/pprof"
+
+	"github.com/akamensky/argparse"
+)
+
+func init() {
+	addPprofFlag = func(parser *argparse.Parser) *string {
+		return parser.String("", "pprof", &argparse.Options{Help: "[debug] Serve net/http/pprof on the provided address during the search. Example: ':6060'"})
+	}
+	startPprof = func(addr string) {
+		go func() {
+			slog.Info("serving pprof", "url", fmt.Sprintf("http://%s/debug/pprof/", addr))
+			if err := http.ListenAndServe(addr, nil); err != nil {
+				slog.Error("pprof server failed", "error", err)
+			}
+		}()
+	}
+}
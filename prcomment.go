@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/hook"
+	"github.com/mathpn/listme/search"
+	"github.com/mathpn/listme/tracker"
+)
+
+// prCommentMarker identifies the comment kept up to date by pr-comment.
+const prCommentMarker = "<!-- listme:pr-comment -->"
+
+// maximum number of added or removed comments listed in the summary
+const prCommentMaxListed = 50
+
+// taggedLine is a tagged comment added or removed by a pull request.
+type taggedLine struct {
+	hook.Line
+	Tag     string
+	Comment string
+}
+
+// fingerprint identifies the comment regardless of its line, as search.Fingerprint.
+// Removed lines of renamed files are identified by their new path.
+func (t taggedLine) fingerprint() string {
+	path := t.Path
+	if t.RenamedTo != "" {
+		path = t.RenamedTo
+	}
+	return search.Fingerprint(path, t.Tag, t.Comment, 0)
+}
+
+// content identifies the comment by its tag and text, regardless of its location.
+func (t taggedLine) content() string {
+	return t.Tag + "\x00" + strings.Join(strings.Fields(t.Comment), " ")
+}
+
+// movedLine is a tagged comment moved by a pull request, to another line or file.
+type movedLine struct {
+	from, to taggedLine
+}
+
+// pairMoved returns the comments that were only moved, removed from one place and
+// added to another without changes, and the ones actually added and removed. Moves
+// within the same file (or its renamed version) are paired first, by fingerprint,
+// then moves between files, by tag and comment.
+func pairMoved(added, removed []taggedLine) ([]taggedLine, []taggedLine, []movedLine) {
+	var moved []movedLine
+	for _, key := range []func(taggedLine) string{
+		taggedLine.fingerprint,
+		taggedLine.content,
+	} {
+		byKey := make(map[string][]int)
+		for i, t := range added {
+			byKey[key(t)] = append(byKey[key(t)], i)
+		}
+		paired := make(map[int]bool)
+		remaining := removed[:0:0]
+		for _, t := range removed {
+			candidates := byKey[key(t)]
+			if len(candidates) == 0 {
+				remaining = append(remaining, t)
+				continue
+			}
+			moved = append(moved, movedLine{from: t, to: added[candidates[0]]})
+			paired[candidates[0]] = true
+			byKey[key(t)] = candidates[1:]
+		}
+		removed = remaining
+		unpaired := added[:0:0]
+		for i, t := range added {
+			if !paired[i] {
+				unpaired = append(unpaired, t)
+			}
+		}
+		added = unpaired
+	}
+	return added, removed, moved
+}
+
+func runPRComment(args []string) {
+	parser := argparse.NewParser("listme pr-comment", "Post or update a comment summarizing the tagged comments added and removed by the current pull request.")
+	flags := addSearchFlags(parser)
+	repo := parser.String("", "repo", &argparse.Options{Help: "GitHub repository with the format owner/repo. Defaults to GITHUB_REPOSITORY or the origin remote"})
+	pr := parser.Int("", "pr", &argparse.Options{Help: "Number of the pull request. Defaults to the pull request that triggered the GitHub Actions run"})
+	base := parser.String("", "base", &argparse.Options{Help: "Base branch or commit of the pull request. Defaults to the base of the pull request that triggered the GitHub Actions run"})
+	dryRun := parser.Flag("", "dry-run", &argparse.Options{Help: "Print the comment instead of posting it"})
+	parseArgs(parser, args)
+
+	opts := flags.options()
+	if *pr == 0 || *base == "" {
+		number, sha, err := tracker.PullRequestEvent()
+		if err != nil && (*base == "" || !*dryRun) {
+			fatal(fmt.Errorf("%s, use --pr and --base", err))
+		}
+		if *pr == 0 {
+			*pr = number
+		}
+		if *base == "" {
+			*base = sha
+		}
+	}
+
+	regex, err := search.TagRegex(opts.Tags)
+	if err != nil {
+		fatal(err)
+	}
+	lines, err := hook.BranchLines(*base)
+	if err != nil {
+		fatal(err)
+	}
+	var added, removed []taggedLine
+	for _, line := range lines {
+		match := regex.FindStringSubmatch(line.Text)
+		if match == nil {
+			continue
+		}
+		t := taggedLine{Line: line, Tag: match[1], Comment: strings.TrimSpace(match[2])}
+		if line.Removed {
+			removed = append(removed, t)
+		} else {
+			added = append(added, t)
+		}
+	}
+
+	opts.Quiet = true
+	params, err := search.NewSearchParams(opts)
+	if err != nil {
+		fatal(err)
+	}
+	stats := requireComplete(search.Search(params))
+
+	added, removed, moved := pairMoved(added, removed)
+	body := prCommentBody(added, removed, moved, stats.Tags())
+	if *dryRun {
+		fmt.Print(body)
+		return
+	}
+	gh, err := tracker.NewGitHub(*repo, *flags.path)
+	if err != nil {
+		fatal(err)
+	}
+	url, err := gh.UpsertComment(*pr, prCommentMarker, body)
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "updated %s\n", url)
+}
+
+// prCommentBody returns the Markdown summary: a table of added, removed, moved and
+// total comments per tag, followed by the lists of added, removed and moved comments.
+func prCommentBody(added, removed []taggedLine, moved []movedLine, totals map[string]int) string {
+	addedByTag := make(map[string]int)
+	for _, t := range added {
+		addedByTag[t.Tag]++
+	}
+	removedByTag := make(map[string]int)
+	for _, t := range removed {
+		removedByTag[t.Tag]++
+	}
+	movedByTag := make(map[string]int)
+	for _, m := range moved {
+		movedByTag[m.to.Tag]++
+	}
+	tagSet := make(map[string]bool)
+	for _, counts := range []map[string]int{addedByTag, removedByTag, movedByTag, totals} {
+		for tag := range counts {
+			tagSet[tag] = true
+		}
+	}
+	tags := make([]string, 0, len(tagSet))
+	for tag := range tagSet {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var b strings.Builder
+	fmt.Fprintln(&b, prCommentMarker)
+	fmt.Fprintln(&b, "### Tagged comments")
+	fmt.Fprintln(&b)
+	fmt.Fprintf(&b, "This pull request adds **%d** and removes **%d** tagged comments", len(added), len(removed))
+	if len(moved) > 0 {
+		fmt.Fprintf(&b, ", and moves **%d**", len(moved))
+	}
+	fmt.Fprintln(&b, ".")
+	if len(tags) > 0 {
+		fmt.Fprintln(&b)
+		fmt.Fprintln(&b, "| Tag | Added | Removed | Moved | Total |")
+		fmt.Fprintln(&b, "| --- | ---: | ---: | ---: | ---: |")
+		for _, tag := range tags {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d |\n", tag, addedByTag[tag], removedByTag[tag], movedByTag[tag], totals[tag])
+		}
+	}
+	writeTaggedList(&b, "Added", added)
+	writeTaggedList(&b, "Removed", removed)
+	writeMovedList(&b, moved)
+	return b.String()
+}
+
+func writeMovedList(b *strings.Builder, moved []movedLine) {
+	if len(moved) == 0 {
+		return
+	}
+	fmt.Fprintln(b)
+	fmt.Fprintf(b, "<details><summary>Moved (%d)</summary>\n\n", len(moved))
+	for i, m := range moved {
+		if i == prCommentMaxListed {
+			fmt.Fprintf(b, "- … and %d more\n", len(moved)-i)
+			break
+		}
+		fmt.Fprintf(b, "- `%s:%d` → `%s:%d` **%s** %s\n", m.from.Path, m.from.N, m.to.Path, m.to.N, m.to.Tag, m.to.Comment)
+	}
+	fmt.Fprintln(b, "\n</details>")
+}
+
+func writeTaggedList(b *strings.Builder, title string, lines []taggedLine) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintln(b)
+	fmt.Fprintf(b, "<details><summary>%s (%d)</summary>\n\n", title, len(lines))
+	for i, t := range lines {
+		if i == prCommentMaxListed {
+			fmt.Fprintf(b, "- … and %d more\n", len(lines)-i)
+			break
+		}
+		fmt.Fprintf(b, "- `%s:%d` **%s** %s\n", t.Path, t.N, t.Tag, t.Comment)
+	}
+	fmt.Fprintln(b, "\n</details>")
+}
@@ -0,0 +1,97 @@
+package pretty
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/mathpn/listme/i18n"
+)
+
+// DefaultAgeTierLabel is the label of the single tier used unless tiers are configured.
+const DefaultAgeTierLabel = "OLD"
+
+// AgeTier marks lines committed more than Days days ago with a badge next to the author.
+//   - Label: text of the badge, e.g. STALE
+//   - Color: background color of the badge, a hex code or ANSI color number.
+//     If empty, the theme color of old commits is used
+type AgeTier struct {
+	Label string
+	Days  int
+	Color string
+}
+
+// ParseAgeTier parses an age tier with the format
+//
+//	LABEL:DAYS:color
+//
+// The color is optional.
+func ParseAgeTier(s string) (AgeTier, error) {
+	fields := strings.SplitN(s, ":", 3)
+	if len(fields) < 2 || fields[0] == "" {
+		return AgeTier{}, fmt.Errorf("invalid age tier %q, expected LABEL:DAYS:color", s)
+	}
+	days, err := strconv.Atoi(fields[1])
+	if err != nil || days < 0 {
+		return AgeTier{}, fmt.Errorf("invalid age tier %q: days must be a non-negative integer", s)
+	}
+	tier := AgeTier{Label: fields[0], Days: days}
+	if len(fields) > 2 {
+		tier.Color = fields[2]
+	}
+	return tier, nil
+}
+
+// AgeTiers holds age tiers resolved against a reference time.
+type AgeTiers struct {
+	tiers []AgeTier
+	now   time.Time
+}
+
+// NewAgeTiers returns the tiers with ages relative to now.
+func NewAgeTiers(tiers []AgeTier, now time.Time) *AgeTiers {
+	sorted := make([]AgeTier, len(tiers))
+	copy(sorted, tiers)
+	// the oldest tier a commit belongs to wins
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Days > sorted[j].Days })
+	return &AgeTiers{tiers: sorted, now: now}
+}
+
+// tierFor returns the tier of a commit made at t, if any.
+func (a *AgeTiers) tierFor(t time.Time) (AgeTier, bool) {
+	if a == nil || t.IsZero() {
+		return AgeTier{}, false
+	}
+	for _, tier := range a.tiers {
+		if t.Before(a.now.Add(-time.Duration(tier.Days) * 24 * time.Hour)) {
+			return tier, true
+		}
+	}
+	return AgeTier{}, false
+}
+
+// labelWidth returns the width of the longest label, including its separator.
+func (a *AgeTiers) labelWidth() int {
+	if a == nil {
+		return 0
+	}
+	var width int
+	for _, tier := range a.tiers {
+		if w := utf8.RuneCountInString(i18n.T(tier.Label)) + 1; w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+func (t AgeTier) style() lipgloss.Style {
+	if t.Color == "" {
+		return oldCommitStyle
+	}
+	return Color{Fg: oldCommitColor.Fg, Bg: t.Color}.style(boldStyle)
+}
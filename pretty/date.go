@@ -0,0 +1,167 @@
+package pretty
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mathpn/listme/i18n"
+)
+
+// DateFormat of the commit date column.
+//   - NoDate: the column is hidden
+//   - RelativeDate: age of the commit, e.g. 3 months ago
+//   - AbsoluteDate: date of the commit, e.g. 2023-04-01, see SetDateLayout
+type DateFormat int
+
+const (
+	NoDate DateFormat = iota
+	RelativeDate
+	AbsoluteDate
+)
+
+// DateFormats lists the accepted names of the date formats.
+var DateFormats = []string{"relative", "absolute"}
+
+// ParseDateFormat returns the DateFormat with the provided name.
+// Any other name is taken as a Go layout string, e.g. "02 Jan 2006",
+// which selects AbsoluteDate and sets the layout with SetDateLayout.
+func ParseDateFormat(name string) (DateFormat, error) {
+	switch name {
+	case "relative":
+		return RelativeDate, nil
+	case "absolute":
+		return AbsoluteDate, nil
+	default:
+		if err := SetDateLayout(name); err != nil {
+			return NoDate, fmt.Errorf("unknown date format %q, expected one of %s or a Go layout string such as 02/01/2006", name, strings.Join(DateFormats, ", "))
+		}
+		return AbsoluteDate, nil
+	}
+}
+
+// defaultDateLayout is translated, so absolute dates follow the conventions of the locale.
+const defaultDateLayout = "2006-01-02"
+
+// dateLayout is the layout of absolute dates, or empty to use the one of the locale.
+var dateLayout string
+
+// absoluteWidths caches the width of dates formatted with each layout.
+var absoluteWidths sync.Map
+
+// SetDateLayout sets the Go layout string of absolute dates, e.g. "Jan 2, 2006".
+// An empty layout restores the default one of the current locale.
+// Month and day names are always in English.
+func SetDateLayout(layout string) error {
+	// a layout without elements formats any date as itself, unlike the reference date of Go layouts
+	probe := time.Date(2017, time.November, 25, 23, 59, 58, 0, time.UTC)
+	if layout != "" && probe.Format(layout) == layout {
+		return fmt.Errorf("invalid date layout %q: it has no date or time elements", layout)
+	}
+	dateLayout = layout
+	return nil
+}
+
+func absoluteDateLayout() string {
+	if dateLayout != "" {
+		return dateLayout
+	}
+	return i18n.T(defaultDateLayout)
+}
+
+// absoluteDateWidth returns the maximum width of a date formatted with the layout,
+// which depends on the month and weekday if their names are part of it.
+func absoluteDateWidth(layout string) int {
+	if width, ok := absoluteWidths.Load(layout); ok {
+		return width.(int)
+	}
+	width := 0
+	for month := time.January; month <= time.December; month++ {
+		// days 24 to 30 cover every weekday with two digits
+		for day := 24; day <= 30; day++ {
+			date := time.Date(2006, month, day, 15, 4, 5, 0, time.UTC)
+			if w := utf8.RuneCountInString(date.Format(layout)); w > width {
+				width = w
+			}
+		}
+	}
+	absoluteWidths.Store(layout, width)
+	return width
+}
+
+// DateWidth returns the width of the strings returned by PrettyDate.
+func DateWidth(format DateFormat) int {
+	switch format {
+	case AbsoluteDate:
+		return absoluteDateWidth(absoluteDateLayout())
+	case RelativeDate:
+		samples := []string{
+			i18n.T("today"),
+			i18n.T("1 day ago"),
+			// two digits are enough, except for very old commits
+			i18n.Sprintf("%d days ago", 99),
+			i18n.Sprintf("%d months ago", 99),
+			i18n.Sprintf("%d years ago", 99),
+		}
+		width := 0
+		for _, sample := range samples {
+			if w := utf8.RuneCountInString(sample); w > width {
+				width = w
+			}
+		}
+		return width
+	default:
+		return 0
+	}
+}
+
+// PrettyDate returns the commit date with the provided format, padded to DateWidth.
+// Unknown dates are rendered as blank space.
+func PrettyDate(t time.Time, format DateFormat, now time.Time) string {
+	var date string
+	switch {
+	case format == NoDate:
+		return ""
+	case t.IsZero():
+	case format == AbsoluteDate:
+		date = t.Format(absoluteDateLayout())
+	default:
+		date = relativeDate(t, now)
+	}
+	pad := DateWidth(format) - utf8.RuneCountInString(date)
+	if pad < 0 {
+		pad = 0
+	}
+	return date + strings.Repeat(" ", pad)
+}
+
+// ShortAge returns the age of a commit in a compact form, e.g. 12d, 14mo or 3y.
+func ShortAge(t time.Time, now time.Time) string {
+	days := int(now.Sub(t).Hours() / 24)
+	switch {
+	case days < 60:
+		return fmt.Sprintf("%dd", days)
+	case days < 730:
+		return fmt.Sprintf("%dmo", days/30)
+	default:
+		return fmt.Sprintf("%dy", days/365)
+	}
+}
+
+func relativeDate(t time.Time, now time.Time) string {
+	days := int(now.Sub(t).Hours() / 24)
+	switch {
+	case days < 1:
+		return i18n.T("today")
+	case days == 1:
+		return i18n.T("1 day ago")
+	case days < 60:
+		return i18n.Sprintf("%d days ago", days)
+	case days < 730:
+		return i18n.Sprintf("%d months ago", days/30)
+	default:
+		return i18n.Sprintf("%d years ago", days/365)
+	}
+}
@@ -0,0 +1,38 @@
+package pretty
+
+import (
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Diff styles, set by the selected theme
+var diffAddStyle lipgloss.Style
+var diffDeleteStyle lipgloss.Style
+var diffHunkStyle lipgloss.Style
+
+// RenderDiff writes a unified diff, coloring its lines: added lines in green, removed
+// lines in red and hunk headers in cyan. File headers are bold.
+// If style != FullStyle, the diff is written unchanged. Lines are never wrapped,
+// so the diff can still be applied.
+func RenderDiff(w io.Writer, diff string, style Style) {
+	if style != FullStyle || diff == "" {
+		io.WriteString(w, diff)
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			lines[i] = boldStyle.Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = diffHunkStyle.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = diffDeleteStyle.Render(line)
+		}
+	}
+	io.WriteString(w, strings.Join(lines, "\n")+"\n")
+}
@@ -1,15 +1,23 @@
+// Package pretty renders the human-readable output of listme. Render* functions
+// write whole lines to the provided io.Writer and fit them to the provided width,
+// so output can be rendered into separate buffers, e.g. concurrently or in tests.
+// Pretty* functions return fragments of a line, such as the line number or the author.
 package pretty
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
-	"time"
+	"unicode/utf8"
 
 	"github.com/mathpn/listme/blame"
+	"github.com/mathpn/listme/i18n"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
 )
 
 // Style used to print to stdout
@@ -22,26 +30,63 @@ const (
 )
 const boldCode = "\x1b[1m"
 const resetBold = "\x1b[22m"
+const italicCode = "\x1b[3m"
+const resetItalic = "\x1b[23m"
+const underlineCode = "\x1b[4m"
+const resetUnderline = "\x1b[24m"
+
+// metadataRegex matches the metadata usually written in tagged comments: assignees
+// (@alice), issue references (#123 or PROJ-123) and due dates (2024-05-01).
+var metadataRegex = regexp.MustCompile(`(?:^|[\s(\[,;:])(?:(@[\w.-]*\w)|(#\d+|[A-Z][A-Z0-9]+-\d+)|(\d{4}-\d{2}-\d{2}))\b`)
 
 // Styles
 var baseStyle = lipgloss.NewStyle()
 var boldStyle = baseStyle.Copy().Bold(true)
-var filenameColorStyle = boldStyle.Copy().Foreground(lipgloss.Color("#0087d7"))
 var borderStyle = baseStyle.Copy().Border(lipgloss.RoundedBorder()).MarginLeft(2)
-var oldCommitStyle = boldStyle.Copy().Foreground(lipgloss.Color("#dadada")).Background(lipgloss.Color("#d70000"))
-var todoStyle = baseStyle.Copy().Foreground(lipgloss.Color("#5fafaf"))
-var xxxStyle = baseStyle.Copy().Foreground(lipgloss.Color("#000000")).Background(lipgloss.Color("#d7af00"))
-var fixmeStyle = baseStyle.Copy().Foreground(lipgloss.Color("#ff0000"))
-var optimizeStyle = baseStyle.Copy().Foreground(lipgloss.Color("#d75f00"))
-var bugStyle = baseStyle.Copy().Foreground(lipgloss.Color("#eeeeee")).Background(lipgloss.Color("#870000"))
-var noteStyle = baseStyle.Copy().Foreground(lipgloss.Color("#87af87"))
-var hackStyle = baseStyle.Copy().Foreground(lipgloss.Color("#d7d700"))
+
+// Colored styles, set by the selected theme
+var filenameColorStyle lipgloss.Style
+var oldCommitStyle lipgloss.Style
+var oldCommitColor Color
 
 // Bold returns the provided string with bold style
 func Bold(str string) string {
 	return boldCode + str + resetBold
 }
 
+// Italic returns the provided string with italic style
+func Italic(str string) string {
+	return italicCode + str + resetItalic
+}
+
+// Underline returns the provided string underlined
+func Underline(str string) string {
+	return underlineCode + str + resetUnderline
+}
+
+// HighlightMetadata returns the comment text with its metadata highlighted in the full
+// style: assignees in bold, issue references underlined and due dates in italic.
+func HighlightMetadata(text string, style Style) string {
+	if style != FullStyle {
+		return text
+	}
+	var b strings.Builder
+	var last int
+	for _, match := range metadataRegex.FindAllStringSubmatchIndex(text, -1) {
+		for group, codes := range [][2]string{{boldCode, resetBold}, {underlineCode, resetUnderline}, {italicCode, resetItalic}} {
+			start, end := match[2+2*group], match[3+2*group]
+			if start < 0 {
+				continue
+			}
+			b.WriteString(text[last:start])
+			b.WriteString(codes[0] + text[start:end] + codes[1])
+			last = end
+		}
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
 // PrettyLineNumber returns a string with the format
 //
 //	[Line 123]
@@ -51,54 +96,71 @@ func Bold(str string) string {
 func PrettyLineNumber(number int, maxDigits int) string {
 	strNumber := fmt.Sprint(number)
 	pad := strings.Repeat(" ", maxDigits-len(strNumber))
-	return fmt.Sprintf("  [Line %s%d] ", pad, number)
+	return fmt.Sprintf("  [%s %s%d] ", i18n.T("Line"), pad, number)
+}
+
+// LineNumberWidth returns the width of the strings returned by PrettyLineNumber.
+func LineNumberWidth(maxDigits int) int {
+	return maxDigits + utf8.RuneCountInString(i18n.T("Line")) + 6
 }
 
-// PrettyFilename returns a string with the format
+// RenderFilename writes a line with the format
 //
 //   - tests/generic_code.py (10 comments)
 //
-// The string if formatted according to the provided style (colorful or black-and-white).
-func PrettyFilename(path string, nComments int, style Style) string {
+// The age of the oldest comment, if provided, follows the number of comments, as in
+// (10 comments, oldest 14mo). The language of the file in brackets, as in [Go], and
+// the owners of the file, if any, follow them.
+// The line is formatted according to the provided style (colorful or black-and-white).
+// In the full style, the path takes the color of tag, the most severe tag of the file
+// (see SeverestTag), if it's a warning or an error, so files with bugs stand out.
+// Paths that don't fit the width are shortened from the left, so the file name stays visible.
+// A width of zero or less means unlimited.
+func RenderFilename(w io.Writer, width int, path string, nComments int, tag, oldest, language, owners string, style Style) {
 	var styler lipgloss.Style
 	switch style {
 	case BWStyle:
 		styler = boldStyle
 	case FullStyle:
 		styler = filenameColorStyle
+		if def := LookupTag(tag); tag != "" && def.Severity > SeverityInfo && hasColor(def.Style) {
+			styler = def.Style.Copy().Bold(true)
+		}
 	default:
 		styler = baseStyle
 	}
-	fname := styler.Render(fmt.Sprintf("• %s", path))
 	var comments string
-	if nComments > 1 {
-		comments = fmt.Sprintf("(%d comments)", nComments)
-	} else {
-		comments = fmt.Sprintf("(%d comment)", nComments)
+	switch {
+	case oldest != "" && nComments > 1:
+		comments = i18n.Sprintf("(%d comments, oldest %s)", nComments, oldest)
+	case oldest != "":
+		comments = i18n.Sprintf("(%d comment, oldest %s)", nComments, oldest)
+	case nComments > 1:
+		comments = i18n.Sprintf("(%d comments)", nComments)
+	default:
+		comments = i18n.Sprintf("(%d comment)", nComments)
+	}
+	if language != "" {
+		comments += " [" + language + "]"
+	}
+	if owners != "" {
+		comments += " " + owners
+	}
+	// the bullet, the path and the comments are separated by spaces
+	if maxPath := width - runewidth.StringWidth(comments) - 3; width > 0 && maxPath > 1 && runewidth.StringWidth(path) > maxPath {
+		path = runewidth.TruncateLeft(path, runewidth.StringWidth(path)-maxPath+1, "…")
 	}
-	return fname + " " + comments
+	fname := styler.Render(fmt.Sprintf("• %s", path))
+	fmt.Fprintln(w, fname+" "+comments)
 }
 
 // Emojify prepends the tag string with an emoji
 func Emojify(tag string) string {
-	switch tag {
-	case "TODO":
-		return "✓ TODO"
-	case "XXX":
-		return "✘ XXX"
-	case "FIXME":
-		return "⚠ FIXME"
-	case "OPTIMIZE":
-		return " OPTIMIZE"
-	case "BUG":
-		return "☢ BUG"
-	case "NOTE":
-		return "✐ NOTE"
-	case "HACK":
-		return "✄ HACK"
-	default:
-		return "⚠ " + tag
+	emoji := LookupTag(tag).Emoji
+	if emoji == "" {
+		return tag
 	}
+	return emoji + " " + tag
 }
 
 // Colorize colorizes the provided text according to the tag and style.
@@ -107,72 +169,138 @@ func Colorize(text string, tag string, style Style) string {
 	if style != FullStyle {
 		return text
 	}
-	switch tag {
-	case "TODO":
-		return todoStyle.Render(text)
-	case "XXX":
-		return xxxStyle.Render(text)
-	case "FIXME":
-		return fixmeStyle.Render(text)
-	case "OPTIMIZE":
-		return optimizeStyle.Render(text)
-	case "BUG":
-		return bugStyle.Render(text)
-	case "NOTE":
-		return noteStyle.Render(text)
-	case "HACK":
-		return hackStyle.Render(text)
-	default:
-		return text
-	}
+	return LookupTag(tag).Style.Render(text)
 }
 
 // PrettyBlame returns a string with the format
 //
 //	[John Doe]
 //
-// If the commit is older than ageLimit (in days), the format is
+// If the commit belongs to an age tier, the label of the oldest matching tier is added
 //
 //	[OLD John Doe]
 //
 // Color is added according to the style.
-func PrettyBlame(blame *blame.LineBlame, oldCommitTime time.Time, style Style) string {
+func PrettyBlame(blame *blame.LineBlame, tiers *AgeTiers, style Style) string {
 	blameStr := fmt.Sprintf("[%s]", blame.Author)
-	if blame.Time.IsZero() {
+	tier, ok := tiers.tierFor(blame.Time)
+	if !ok {
 		return blameStr
 	}
 
-	if blame.Time.Before(oldCommitTime) {
-		blameStr = fmt.Sprintf("[OLD %s]", blame.Author)
-		if style == FullStyle {
-			blameStr = oldCommitStyle.Render(blameStr)
-		}
+	blameStr = fmt.Sprintf("[%s %s]", i18n.T(tier.Label), blame.Author)
+	if style == FullStyle {
+		blameStr = tier.style().Render(blameStr)
 	}
 	return blameStr
 }
 
-func PrettySummary(counter map[string]int, style Style) string {
+// BlameWidth returns the maximum width of the strings returned by PrettyBlame,
+// including a leading separator.
+func BlameWidth(tiers *AgeTiers) int {
+	return blame.MaxAuthorLength + tiers.labelWidth() + 3
+}
+
+// PrettyHash returns the short commit hash padded to HashWidth.
+// Unknown hashes are rendered as blank space.
+func PrettyHash(hash string) string {
+	return fmt.Sprintf("%-*s", blame.ShortHashLength, hash)
+}
+
+// HashWidth returns the width of the strings returned by PrettyHash.
+func HashWidth() int {
+	return blame.ShortHashLength
+}
+
+// RenderSummary writes a box with the number of comments of each tag, sorted by tag.
+// Tags that don't fit the width are moved to a new row. A width of zero or less means unlimited.
+func RenderSummary(w io.Writer, width int, counter map[string]int, style Style) {
+	fmt.Fprintln(w, borderStyle.Render(strings.Join(summaryRows(width, counter, style), "\n")))
+}
+
+// RenderSeveritySummary writes a box as RenderSummary, preceded by a row with the
+// number of comments of each severity, from errors to info.
+func RenderSeveritySummary(w io.Writer, width int, counter map[string]int, style Style) {
+	severities := CountSeverities(counter)
+	var row string
+	for s := SeverityError; s >= SeverityInfo; s-- {
+		if severities[s] > 0 {
+			row += fmt.Sprintf(" %s %d ", s, severities[s])
+		}
+	}
+	rows := append([]string{" " + Bold(row) + " "}, summaryRows(width, counter, style)...)
+	fmt.Fprintln(w, borderStyle.Render(strings.Join(rows, "\n")))
+}
+
+// CountSeverities returns the number of comments of each severity, given the number of comments of each tag.
+func CountSeverities(counter map[string]int) map[Severity]int {
+	severities := make(map[Severity]int, len(Severities))
+	for tag, count := range counter {
+		severities[LookupTag(tag).Severity] += count
+	}
+	return severities
+}
+
+// SeverestTag returns the tag of the highest severity, given the number of comments of
+// each tag. Among tags of the same severity, the most common one is returned, and the
+// first in alphabetical order if they're tied.
+func SeverestTag(counter map[string]int) string {
+	var severest string
+	var severity Severity
+	for tag, count := range counter {
+		s := LookupTag(tag).Severity
+		if severest != "" && s < severity {
+			continue
+		}
+		if severest != "" && s == severity && (count < counter[severest] || (count == counter[severest] && tag > severest)) {
+			continue
+		}
+		severest, severity = tag, s
+	}
+	return severest
+}
+
+// hasColor reports whether the style sets a foreground or a background color.
+func hasColor(s lipgloss.Style) bool {
+	_, noFg := s.GetForeground().(lipgloss.NoColor)
+	_, noBg := s.GetBackground().(lipgloss.NoColor)
+	return !noFg || !noBg
+}
+
+// summaryRows returns the rows of tags of a summary box.
+func summaryRows(width int, counter map[string]int, style Style) []string {
 	tags := make([]string, 0, len(counter))
 	for tag := range counter {
 		tags = append(tags, tag)
 	}
-
 	sort.Strings(tags)
-	boxStr := " "
+
+	// the margin, the borders and the padding of the box
+	maxRow := width - borderStyle.GetHorizontalFrameSize() - 2
+	var rows []string
+	row, rowWidth := "", 0
 	for _, tag := range tags {
 		tagStr := fmt.Sprintf(" %s %d ", Emojify(tag), counter[tag])
-		if style == FullStyle {
-			tagStr = Colorize(tagStr, tag, style)
+		tagWidth := runewidth.StringWidth(tagStr)
+		if width > 0 && rowWidth > 0 && rowWidth+tagWidth > maxRow {
+			rows = append(rows, row)
+			row, rowWidth = "", 0
 		}
-		boxStr += tagStr
+		row += Colorize(tagStr, tag, style)
+		rowWidth += tagWidth
 	}
-	return borderStyle.Render(boxStr + " ")
+	rows = append(rows, row)
+	for i := range rows {
+		rows[i] = " " + rows[i] + " "
+	}
+	return rows
 }
 
 // GetStyle returns the style that should be used. FullStyle is the default.
 // If bw, then BWStyle. If plain, then PlainStyle.
 //
 // If the output (stdout) is redirected, PlainStyle is always used.
+// IsTerminal tells whether it is.
 func GetStyle(bw bool, plain bool) (Style, error) {
 	if bw && plain {
 		return -1, fmt.Errorf("only one style can be specified")
@@ -186,7 +314,7 @@ func GetStyle(bw bool, plain bool) (Style, error) {
 
 	var style Style
 	switch {
-	case (fi.Mode() & os.ModeCharDevice) == 0:
+	case !isCharDevice(fi):
 		style = PlainStyle
 	case bw:
 		style = BWStyle
@@ -197,3 +325,13 @@ func GetStyle(bw bool, plain bool) (Style, error) {
 	}
 	return style, nil
 }
+
+// IsTerminal reports whether f is a terminal rather than a file or a pipe.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && isCharDevice(fi)
+}
+
+func isCharDevice(fi os.FileInfo) bool {
+	return fi.Mode()&os.ModeCharDevice != 0
+}
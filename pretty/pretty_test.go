@@ -0,0 +1,75 @@
+package pretty
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestRenderFilename(t *testing.T) {
+	var b bytes.Buffer
+	RenderFilename(&b, 0, "search/search.go", 2, "BUG", "", "", "", PlainStyle)
+	if got, expected := b.String(), "• search/search.go (2 comments)\n"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	b.Reset()
+	RenderFilename(&b, 24, "a/very/long/path/to/search.go", 1, "", "", "", "", PlainStyle)
+	got := strings.TrimSuffix(b.String(), "\n")
+	if runewidth.StringWidth(got) != 24 || !strings.HasPrefix(got, "• …") || !strings.HasSuffix(got, "search.go (1 comment)") {
+		t.Errorf("expected the path to be shortened to fit 24 columns, got %q", got)
+	}
+}
+
+func TestSeverestTag(t *testing.T) {
+	tests := []struct {
+		counter  map[string]int
+		expected string
+	}{
+		{map[string]int{}, ""},
+		{map[string]int{"TODO": 5, "NOTE": 1}, "TODO"},
+		{map[string]int{"TODO": 5, "HACK": 1}, "HACK"},
+		{map[string]int{"TODO": 5, "HACK": 3, "FIXME": 1, "BUG": 1}, "BUG"},
+		{map[string]int{"FIXME": 2, "BUG": 1}, "FIXME"},
+	}
+	for _, tt := range tests {
+		if got := SeverestTag(tt.counter); got != tt.expected {
+			t.Errorf("SeverestTag(%v) = %q, expected %q", tt.counter, got, tt.expected)
+		}
+	}
+}
+
+func TestRenderSummary(t *testing.T) {
+	counter := map[string]int{"BUG": 1, "FIXME": 2, "TODO": 3}
+	for _, width := range []int{0, 20} {
+		var b bytes.Buffer
+		RenderSummary(&b, width, counter, PlainStyle)
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		// the box has a top and a bottom border around the rows
+		rows := len(lines) - 2
+		for _, line := range lines {
+			if width > 0 && runewidth.StringWidth(line) > width {
+				t.Errorf("width %d: line %q is too wide", width, line)
+			}
+		}
+		if width == 0 && rows != 1 {
+			t.Errorf("expected a single row without width limit, got %q", lines)
+		}
+		if width > 0 && rows < 2 {
+			t.Errorf("expected tags to wrap in %d columns, got %q", width, lines)
+		}
+	}
+}
+
+func TestHighlightMetadata(t *testing.T) {
+	text := "ask @alice about #12 and PROJ-7 before 2024-05-01, see foo@bar.com"
+	expected := "ask \x1b[1m@alice\x1b[22m about \x1b[4m#12\x1b[24m and \x1b[4mPROJ-7\x1b[24m before \x1b[3m2024-05-01\x1b[23m, see foo@bar.com"
+	if got := HighlightMetadata(text, FullStyle); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if got := HighlightMetadata(text, BWStyle); got != text {
+		t.Errorf("expected no highlighting in the BW style, got %q", got)
+	}
+}
@@ -0,0 +1,113 @@
+package pretty
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Severity of a tag, used to rank tagged comments.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+// Severities lists the accepted names of severities, in increasing order.
+var Severities = []string{"info", "warning", "error"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(Severities) {
+		return "unknown"
+	}
+	return Severities[s]
+}
+
+// ParseSeverity returns the Severity with the provided name.
+func ParseSeverity(name string) (Severity, error) {
+	for i, s := range Severities {
+		if strings.EqualFold(name, s) {
+			return Severity(i), nil
+		}
+	}
+	return SeverityInfo, fmt.Errorf("unknown severity %q, expected one of %s", name, strings.Join(Severities, ", "))
+}
+
+// TagDef defines how a tag is rendered.
+//   - Emoji: symbol prepended to the tag name
+//   - Style: style used to colorize lines with the tag in FullStyle
+//   - Severity: severity of the tag
+type TagDef struct {
+	Name     string
+	Emoji    string
+	Style    lipgloss.Style
+	Severity Severity
+}
+
+const defaultEmoji = "⚠"
+
+var tagDefsMu sync.RWMutex
+
+// built-in tags, their styles are set by the selected theme
+var tagDefs = map[string]TagDef{
+	"BUG":      {Name: "BUG", Emoji: "☢", Severity: SeverityError},
+	"FIXME":    {Name: "FIXME", Emoji: "⚠", Severity: SeverityError},
+	"XXX":      {Name: "XXX", Emoji: "✘", Severity: SeverityWarning},
+	"HACK":     {Name: "HACK", Emoji: "✄", Severity: SeverityWarning},
+	"OPTIMIZE": {Name: "OPTIMIZE", Emoji: "", Severity: SeverityInfo},
+	"TODO":     {Name: "TODO", Emoji: "✓", Severity: SeverityInfo},
+	"NOTE":     {Name: "NOTE", Emoji: "✐", Severity: SeverityInfo},
+	"TASK":     {Name: "TASK", Emoji: "☐", Severity: SeverityInfo},
+}
+
+// RegisterTag adds or replaces the definition of a tag.
+func RegisterTag(def TagDef) {
+	tagDefsMu.Lock()
+	defer tagDefsMu.Unlock()
+	tagDefs[def.Name] = def
+}
+
+// LookupTag returns the definition of a tag. Unknown tags get the default emoji,
+// no color and SeverityInfo.
+func LookupTag(tag string) TagDef {
+	tagDefsMu.RLock()
+	defer tagDefsMu.RUnlock()
+	if def, ok := tagDefs[tag]; ok {
+		return def
+	}
+	return TagDef{Name: tag, Emoji: defaultEmoji, Style: baseStyle, Severity: SeverityInfo}
+}
+
+// ParseTagDef parses a tag definition with the format
+//
+//	NAME:color:emoji:severity
+//
+// Only the name is required. The color is a hex code (e.g. #ff8700) or an ANSI color number.
+// Omitted fields keep the value of the built-in definition, if there's one.
+func ParseTagDef(s string) (TagDef, error) {
+	fields := strings.SplitN(s, ":", 4)
+	name := fields[0]
+	if name == "" {
+		return TagDef{}, fmt.Errorf("invalid tag definition %q: missing tag name", s)
+	}
+
+	def := LookupTag(name)
+	if len(fields) > 1 && fields[1] != "" {
+		def.Style = baseStyle.Copy().Foreground(lipgloss.Color(fields[1]))
+	}
+	if len(fields) > 2 && fields[2] != "" {
+		def.Emoji = fields[2]
+	}
+	if len(fields) > 3 && fields[3] != "" {
+		severity, err := ParseSeverity(fields[3])
+		if err != nil {
+			return TagDef{}, fmt.Errorf("invalid tag definition %q: %s", s, err)
+		}
+		def.Severity = severity
+	}
+	return def, nil
+}
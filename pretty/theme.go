@@ -0,0 +1,235 @@
+package pretty
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Color is a foreground and an optional background color.
+// Colors are hex codes (e.g. #ff8700) or ANSI color numbers.
+type Color struct {
+	Fg string
+	Bg string
+}
+
+func (c Color) style(base lipgloss.Style) lipgloss.Style {
+	s := base.Copy()
+	if c.Fg != "" {
+		s = s.Foreground(lipgloss.Color(c.Fg))
+	}
+	if c.Bg != "" {
+		s = s.Background(lipgloss.Color(c.Bg))
+	}
+	return s
+}
+
+// Theme is the color palette used by FullStyle.
+//   - Filename: file names, rendered in bold
+//   - OldCommit: badge of lines from old commits, rendered in bold
+//   - Tags: colors of the built-in tags
+//   - DiffAdd, DiffDelete, DiffHunk: lines of diff previews
+type Theme struct {
+	Filename   Color
+	OldCommit  Color
+	Tags       map[string]Color
+	DiffAdd    Color
+	DiffDelete Color
+	DiffHunk   Color
+}
+
+// Theme names with special meaning.
+//   - AutoTheme: DefaultTheme, or LightTheme if the terminal has a light background
+//   - DefaultTheme: colors for dark backgrounds
+//   - LightTheme: the default colors adjusted for light backgrounds
+const (
+	AutoTheme    = "auto"
+	DefaultTheme = "default"
+	LightTheme   = "default-light"
+)
+
+// ThemeNames lists the names of the built-in themes, and AutoTheme.
+var ThemeNames = []string{
+	AutoTheme, DefaultTheme, LightTheme, "solarized-dark", "solarized-light", "dracula", "gruvbox", "high-contrast",
+}
+
+var themes = map[string]*Theme{
+	DefaultTheme: {
+		Filename:  Color{Fg: "#0087d7"},
+		OldCommit: Color{Fg: "#dadada", Bg: "#d70000"},
+		Tags: map[string]Color{
+			"BUG":      {Fg: "#eeeeee", Bg: "#870000"},
+			"FIXME":    {Fg: "#ff0000"},
+			"XXX":      {Fg: "#000000", Bg: "#d7af00"},
+			"HACK":     {Fg: "#d7d700"},
+			"OPTIMIZE": {Fg: "#d75f00"},
+			"TODO":     {Fg: "#5fafaf"},
+			"NOTE":     {Fg: "#87af87"},
+			"TASK":     {Fg: "#5f87d7"},
+		},
+		DiffAdd:    Color{Fg: "#5faf5f"},
+		DiffDelete: Color{Fg: "#d75f5f"},
+		DiffHunk:   Color{Fg: "#5fafaf"},
+	},
+	LightTheme: {
+		Filename:  Color{Fg: "#005faf"},
+		OldCommit: Color{Fg: "#ffffff", Bg: "#d70000"},
+		Tags: map[string]Color{
+			"BUG":      {Fg: "#ffffff", Bg: "#870000"},
+			"FIXME":    {Fg: "#d70000"},
+			"XXX":      {Fg: "#000000", Bg: "#ffd75f"},
+			"HACK":     {Fg: "#875f00"},
+			"OPTIMIZE": {Fg: "#af5f00"},
+			"TODO":     {Fg: "#008787"},
+			"NOTE":     {Fg: "#5f8700"},
+			"TASK":     {Fg: "#005fd7"},
+		},
+		DiffAdd:    Color{Fg: "#008700"},
+		DiffDelete: Color{Fg: "#d70000"},
+		DiffHunk:   Color{Fg: "#008787"},
+	},
+	"solarized-dark": {
+		Filename:  Color{Fg: "#268bd2"},
+		OldCommit: Color{Fg: "#fdf6e3", Bg: "#dc322f"},
+		Tags: map[string]Color{
+			"BUG":      {Fg: "#fdf6e3", Bg: "#d33682"},
+			"FIXME":    {Fg: "#dc322f"},
+			"XXX":      {Fg: "#002b36", Bg: "#b58900"},
+			"HACK":     {Fg: "#b58900"},
+			"OPTIMIZE": {Fg: "#cb4b16"},
+			"TODO":     {Fg: "#2aa198"},
+			"NOTE":     {Fg: "#859900"},
+			"TASK":     {Fg: "#6c71c4"},
+		},
+		DiffAdd:    Color{Fg: "#859900"},
+		DiffDelete: Color{Fg: "#dc322f"},
+		DiffHunk:   Color{Fg: "#2aa198"},
+	},
+	"solarized-light": {
+		Filename:  Color{Fg: "#268bd2"},
+		OldCommit: Color{Fg: "#fdf6e3", Bg: "#dc322f"},
+		Tags: map[string]Color{
+			"BUG":      {Fg: "#fdf6e3", Bg: "#d33682"},
+			"FIXME":    {Fg: "#dc322f"},
+			"XXX":      {Fg: "#fdf6e3", Bg: "#b58900"},
+			"HACK":     {Fg: "#cb4b16"},
+			"OPTIMIZE": {Fg: "#6c71c4"},
+			"TODO":     {Fg: "#2aa198"},
+			"NOTE":     {Fg: "#586e75"},
+			"TASK":     {Fg: "#268bd2"},
+		},
+		DiffAdd:    Color{Fg: "#859900"},
+		DiffDelete: Color{Fg: "#dc322f"},
+		DiffHunk:   Color{Fg: "#2aa198"},
+	},
+	"dracula": {
+		Filename:  Color{Fg: "#bd93f9"},
+		OldCommit: Color{Fg: "#282a36", Bg: "#ff5555"},
+		Tags: map[string]Color{
+			"BUG":      {Fg: "#282a36", Bg: "#ff79c6"},
+			"FIXME":    {Fg: "#ff5555"},
+			"XXX":      {Fg: "#282a36", Bg: "#f1fa8c"},
+			"HACK":     {Fg: "#f1fa8c"},
+			"OPTIMIZE": {Fg: "#ffb86c"},
+			"TODO":     {Fg: "#8be9fd"},
+			"NOTE":     {Fg: "#50fa7b"},
+			"TASK":     {Fg: "#ff79c6"},
+		},
+		DiffAdd:    Color{Fg: "#50fa7b"},
+		DiffDelete: Color{Fg: "#ff5555"},
+		DiffHunk:   Color{Fg: "#8be9fd"},
+	},
+	"gruvbox": {
+		Filename:  Color{Fg: "#83a598"},
+		OldCommit: Color{Fg: "#282828", Bg: "#fb4934"},
+		Tags: map[string]Color{
+			"BUG":      {Fg: "#282828", Bg: "#d3869b"},
+			"FIXME":    {Fg: "#fb4934"},
+			"XXX":      {Fg: "#282828", Bg: "#fabd2f"},
+			"HACK":     {Fg: "#fabd2f"},
+			"OPTIMIZE": {Fg: "#fe8019"},
+			"TODO":     {Fg: "#8ec07c"},
+			"NOTE":     {Fg: "#b8bb26"},
+			"TASK":     {Fg: "#d3869b"},
+		},
+		DiffAdd:    Color{Fg: "#b8bb26"},
+		DiffDelete: Color{Fg: "#fb4934"},
+		DiffHunk:   Color{Fg: "#8ec07c"},
+	},
+	// the 16 ANSI colors, which the terminal renders according to its own palette
+	"high-contrast": {
+		Filename:  Color{Fg: "12"},
+		OldCommit: Color{Fg: "15", Bg: "1"},
+		Tags: map[string]Color{
+			"BUG":      {Fg: "15", Bg: "1"},
+			"FIXME":    {Fg: "9"},
+			"XXX":      {Fg: "0", Bg: "11"},
+			"HACK":     {Fg: "11"},
+			"OPTIMIZE": {Fg: "13"},
+			"TODO":     {Fg: "14"},
+			"NOTE":     {Fg: "10"},
+			"TASK":     {Fg: "12"},
+		},
+		DiffAdd:    Color{Fg: "10"},
+		DiffDelete: Color{Fg: "9"},
+		DiffHunk:   Color{Fg: "14"},
+	},
+}
+
+func init() {
+	applyTheme(themes[DefaultTheme])
+}
+
+// SetTheme selects one of the built-in themes.
+// Tags defined with RegisterTag before the call get the theme colors if they're built-in tags.
+// AutoTheme only inspects the terminal in FullStyle, as other styles have no colors.
+func SetTheme(name string, style Style) error {
+	if name == AutoTheme {
+		name = DefaultTheme
+		if style == FullStyle && hasLightBackground() {
+			name = LightTheme
+		}
+	}
+	theme, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(ThemeNames, ", "))
+	}
+	applyTheme(theme)
+	return nil
+}
+
+// hasLightBackground reports whether the terminal has a light background. COLORFGBG is
+// used if set, as in "15;0" where the last field is the ANSI background color. Otherwise,
+// the terminal is queried for its background color, which is assumed dark if unknown.
+func hasLightBackground() bool {
+	if colorFGBG := os.Getenv("COLORFGBG"); colorFGBG != "" {
+		fields := strings.Split(colorFGBG, ";")
+		bg, err := strconv.Atoi(fields[len(fields)-1])
+		if err == nil {
+			// ANSI colors 7 (white) and 9 to 15 (bright colors) are light
+			return bg == 7 || (bg >= 9 && bg <= 15)
+		}
+	}
+	return !lipgloss.HasDarkBackground()
+}
+
+func applyTheme(theme *Theme) {
+	filenameColorStyle = theme.Filename.style(boldStyle)
+	oldCommitColor = theme.OldCommit
+	oldCommitStyle = theme.OldCommit.style(boldStyle)
+	diffAddStyle = theme.DiffAdd.style(baseStyle)
+	diffDeleteStyle = theme.DiffDelete.style(baseStyle)
+	diffHunkStyle = theme.DiffHunk.style(baseStyle)
+
+	tagDefsMu.Lock()
+	defer tagDefsMu.Unlock()
+	for tag, color := range theme.Tags {
+		if def, ok := tagDefs[tag]; ok {
+			def.Style = color.style(baseStyle)
+			tagDefs[tag] = def
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/edit"
+	"github.com/mathpn/listme/pretty"
+	"github.com/mathpn/listme/search"
+)
+
+func runResolve(args []string) {
+	parser := argparse.NewParser("listme resolve", "Delete a tagged comment from a file. The last resolution can be undone.")
+	location := parser.StringPositional(&argparse.Options{Help: "Location of the comment with the format path:line"})
+	tags := parser.StringList("T", "tags", &argparse.Options{Default: tags, Validate: validateTags, Help: "Tags to search for, separated by spaces or commas"})
+	block := parser.Flag("B", "block", &argparse.Options{Help: "Delete the whole comment block instead of a single line"})
+	yes := parser.Flag("y", "yes", &argparse.Options{Help: "Do not ask for confirmation"})
+	undo := parser.Flag("u", "undo", &argparse.Options{Help: "Restore the lines deleted by the last resolution"})
+	dryRun := parser.Flag("", "dry-run", &argparse.Options{Help: "Print the diff of the deletion without changing the file"})
+	parseArgs(parser, args)
+
+	if err := setupLogging(false, false, "text", ""); err != nil {
+		fatal(err)
+	}
+
+	if *undo {
+		r, err := edit.Undo()
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Fprintf(os.Stderr, "restored %d line(s) in %s:%d\n", len(r.Lines), r.Path, r.Line)
+		return
+	}
+
+	path, line, err := parseLocation(*location)
+	if err != nil {
+		fatal(err)
+	}
+	regex, err := search.TagRegex(splitTags(*tags))
+	if err != nil {
+		fatal(err)
+	}
+	r, err := edit.PlanResolve(path, line, regex, *block)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *dryRun {
+		e, err := r.Edit()
+		if err != nil {
+			fatal(err)
+		}
+		style, err := pretty.GetStyle(false, false)
+		if err != nil {
+			fatal(err)
+		}
+		printDiff(e, style)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%s:%d\n", r.Path, r.Line)
+	for _, l := range r.Lines {
+		fmt.Fprintf(os.Stderr, "- %s\n", l)
+	}
+	question := fmt.Sprintf("delete %d line(s)?", len(r.Lines))
+	if r.Keep != "" {
+		fmt.Fprintf(os.Stderr, "+ %s\n", r.Keep)
+		question = "delete the trailing comment?"
+	}
+	if !*yes && !confirm(question) {
+		fmt.Fprintln(os.Stderr, "aborted")
+		return
+	}
+	if err := r.Apply(); err != nil {
+		fatal(err)
+	}
+	fmt.Fprintln(os.Stderr, "deleted, run 'listme resolve --undo' to restore")
+}
+
+// parseLocation parses a location with the format path:line.
+func parseLocation(location string) (string, int, error) {
+	idx := strings.LastIndex(location, ":")
+	if idx < 1 {
+		return "", 0, fmt.Errorf("invalid location %q, expected path:line", location)
+	}
+	line, err := strconv.Atoi(location[idx+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid line number in location %q", location)
+	}
+	return location[:idx], line, nil
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/blame"
+	"github.com/mathpn/listme/hook"
+	"github.com/mathpn/listme/pretty"
+	"github.com/mathpn/listme/search"
+)
+
+// resolvedLine is a tagged comment removed by a commit and not added back since.
+type resolvedLine struct {
+	taggedLine
+	column int
+	commit *hook.Commit
+}
+
+// JSONResolved is the JSON output of the resolved subcommand.
+type JSONResolved struct {
+	Since    string              `json:"since"`
+	Total    int                 `json:"total"`
+	Tags     map[string]int      `json:"tags"`
+	Comments []JSONResolvedMatch `json:"comments"`
+}
+
+// JSONResolvedMatch is a tagged comment removed since the ref, and the commit that removed it.
+type JSONResolvedMatch struct {
+	Path        string `json:"path"`
+	Line        int    `json:"line"`
+	Tag         string `json:"tag"`
+	Text        string `json:"text"`
+	Fingerprint string `json:"fingerprint"`
+	Commit      string `json:"commit"`
+	Author      string `json:"author"`
+	Date        string `json:"date"`
+}
+
+func runResolved(args []string) {
+	parser := argparse.NewParser("listme resolved", "Report the tagged comments removed by the commits since a ref, e.g. the debt paid down since the last release.")
+	flags := addSearchFlags(parser)
+	since := parser.String("", "since", &argparse.Options{Required: true, Help: "Branch, tag or commit to start from, e.g. v1.0.0"})
+	parseArgs(parser, args)
+
+	opts := flags.options()
+	if opts.Format != search.TextFormat && opts.Format != search.JSONFormat {
+		fatal(fmt.Errorf("the %s format is not supported by resolved", *flags.format))
+	}
+	regex, err := search.TagRegex(opts.Tags)
+	if err != nil {
+		fatal(err)
+	}
+	dir := opts.Path
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+		fatal(fmt.Errorf("resolved requires a directory, got %s", dir))
+	}
+	commits, err := hook.CommitLines(dir, *since)
+	if err != nil {
+		fatal(err)
+	}
+	excluded := make(map[string]bool)
+	for i := range commits {
+		name, ok := search.NormalizeAuthor(opts.AuthorRules, commits[i].Author)
+		excluded[commits[i].Hash] = !ok
+		commits[i].Author = name
+	}
+	resolved := resolvedSince(commits, regex)
+	// comments resolved by excluded authors, such as bots, aren't reported
+	resolved = slices.DeleteFunc(resolved, func(r resolvedLine) bool { return excluded[r.commit.Hash] })
+
+	if opts.Format == search.JSONFormat {
+		out := JSONResolved{Since: *since, Total: len(resolved), Tags: resolvedTags(resolved), Comments: make([]JSONResolvedMatch, 0, len(resolved))}
+		for _, r := range resolved {
+			out.Comments = append(out.Comments, JSONResolvedMatch{
+				Path:        r.Path,
+				Line:        r.N,
+				Tag:         r.Tag,
+				Text:        r.Comment,
+				Fingerprint: r.fingerprint(),
+				Commit:      r.commit.Hash,
+				Author:      r.commit.Author,
+				Date:        r.commit.Time.UTC().Format("2006-01-02T15:04:05Z"),
+			})
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	var b strings.Builder
+	renderResolved(&b, *since, resolved, opts.Style)
+	fmt.Print(b.String())
+}
+
+// resolvedSince returns the tagged comments removed by the commits, in order. A comment
+// added back by the same or a later commit, with the same tag and text, was only moved
+// or reverted, so it isn't resolved.
+func resolvedSince(commits []hook.Commit, regex *regexp.Regexp) []resolvedLine {
+	var resolved []resolvedLine
+	for i := range commits {
+		var added []taggedLine
+		for _, line := range commits[i].Lines {
+			match := regex.FindStringSubmatchIndex(line.Text)
+			if match == nil {
+				continue
+			}
+			t := taggedLine{Line: line, Tag: line.Text[match[2]:match[3]], Comment: strings.TrimSpace(line.Text[match[4]:match[5]])}
+			if line.Removed {
+				column := utf8.RuneCountInString(line.Text[:match[2]]) + 1
+				resolved = append(resolved, resolvedLine{taggedLine: t, column: column, commit: &commits[i]})
+			} else {
+				added = append(added, t)
+			}
+		}
+		for _, t := range added {
+			for j := len(resolved) - 1; j >= 0; j-- {
+				if resolved[j].content() == t.content() {
+					resolved = append(resolved[:j], resolved[j+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return resolved
+}
+
+func resolvedTags(resolved []resolvedLine) map[string]int {
+	tags := make(map[string]int)
+	for _, r := range resolved {
+		tags[r.Tag]++
+	}
+	return tags
+}
+
+// renderResolved writes the resolved comments grouped by commit, after the totals per
+// tag. The plain style has a line per comment instead, as in the search output, with the
+// commit that removed the comment, see search.WritePlainLine.
+func renderResolved(b *strings.Builder, since string, resolved []resolvedLine, style pretty.Style) {
+	if style == pretty.PlainStyle {
+		for _, r := range resolved {
+			hash := r.commit.Hash[:min(blame.ShortHashLength, len(r.commit.Hash))]
+			search.WritePlainLine(b, r.Path, r.N, r.column, r.Tag, hash, r.commit.Time, r.Comment)
+		}
+		return
+	}
+
+	fmt.Fprintln(b, pretty.Bold(fmt.Sprintf("%d tagged comments resolved since %s", len(resolved), since)))
+	tags := resolvedTags(resolved)
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	sort.Strings(names)
+	for i, tag := range names {
+		names[i] = fmt.Sprintf("%s %d", tag, tags[tag])
+	}
+	if len(names) > 0 {
+		fmt.Fprintf(b, "  %s\n", strings.Join(names, "  "))
+	}
+
+	var commit *hook.Commit
+	for _, r := range resolved {
+		if r.commit != commit {
+			commit = r.commit
+			fmt.Fprintln(b)
+			fmt.Fprintf(b, "• %s %s %s\n", pretty.Bold(commit.Hash[:min(7, len(commit.Hash))]), commit.Time.Format("2006-01-02"), commit.Author)
+		}
+		fmt.Fprintf(b, "  %s:%d %s %s\n", r.Path, r.N, pretty.Bold(r.Tag), r.Comment)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/edit"
+	"github.com/mathpn/listme/search"
+)
+
+func runRewrite(args []string) {
+	parser := argparse.NewParser("listme rewrite", "Rename tags according to a mapping. Changes are previewed as a unified diff unless --write is used.")
+	flags := addSearchFlags(parser)
+	mappings := parser.StringList("", "map", &argparse.Options{Help: "Tag mapping with the format FROM=TO, e.g. XXX=FIXME. The source is matched literally and may end with punctuation, e.g. TODO!=BUG. Can be repeated. Required unless --fuzzy-tags is used"})
+	write := parser.Flag("", "write", &argparse.Options{Help: "Apply the changes to the files instead of previewing them"})
+	dryRun := parser.Flag("", "dry-run", &argparse.Options{Help: "Only print the diff of the changes. This is the default unless --write is used"})
+	parseArgs(parser, args)
+
+	opts := flags.options()
+	if *write && *dryRun {
+		fatal(fmt.Errorf("--write and --dry-run can't be used together"))
+	}
+	if len(*mappings) == 0 && !opts.FuzzyTags {
+		fatal(fmt.Errorf("at least one --map is required, unless --fuzzy-tags is used"))
+	}
+	tagMap, err := edit.ParseTagMap(*mappings)
+	if err != nil {
+		fatal(err)
+	}
+
+	renames := make(map[string][]edit.Rename)
+	if len(tagMap) > 0 {
+		opts.Tags = make([]string, 0, len(tagMap))
+		for _, from := range tagMap.Sources() {
+			opts.Tags = append(opts.Tags, regexp.QuoteMeta(from))
+		}
+	}
+	opts.FullPath = true
+	opts.Quiet = true
+	opts.Collect = func(m search.JSONMatch) {
+		// misspelled tags are fixed, and renamed as well if mapped
+		from, to := m.Tag, tagMap[m.Tag]
+		if m.Malformed != "" {
+			from = m.Malformed
+			if to == "" {
+				to = m.Tag
+			}
+		}
+		if to == "" {
+			return
+		}
+		renames[m.Path] = append(renames[m.Path], edit.Rename{Line: m.Line, Column: m.Column, From: from, To: to})
+	}
+	params, err := search.NewSearchParams(opts)
+	if err != nil {
+		fatal(err)
+	}
+	requireComplete(search.Search(params))
+
+	paths := make([]string, 0, len(renames))
+	for path := range renames {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var count int
+	for _, path := range paths {
+		e, err := edit.PlanRewrite(path, renames[path])
+		if err != nil {
+			fatal(err)
+		}
+		printDiff(e, opts.Style)
+		if *write {
+			if err := e.Apply(); err != nil {
+				fatal(err)
+			}
+		}
+		count += len(renames[path])
+	}
+
+	switch {
+	case count == 0:
+		fmt.Fprintln(os.Stderr, "no tags to rewrite")
+	case *write:
+		fmt.Fprintf(os.Stderr, "rewrote %d tag(s) in %d file(s)\n", count, len(paths))
+	default:
+		fmt.Fprintf(os.Stderr, "%d tag(s) in %d file(s) would be rewritten, use --write to apply\n", count, len(paths))
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/search"
+)
+
+func runSchema(args []string) {
+	parser := argparse.NewParser("listme schema", "Print the JSON Schema of the machine-readable output, to validate it or generate typed clients.")
+	format := parser.Selector("", "format", []string{"json", "jsonl"}, &argparse.Options{Default: "json", Help: "Output format described by the schema: json (the whole document) or jsonl (a single record)"})
+	parseArgs(parser, args)
+
+	if err := setupLogging(false, false, "text", ""); err != nil {
+		fatal(err)
+	}
+
+	outFormat, err := search.ParseFormat(*format)
+	if err != nil {
+		fatal(err)
+	}
+	schema, err := search.JSONSchema(outFormat)
+	if err != nil {
+		fatal(err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"regexp"
+
+	"github.com/mathpn/listme/blame"
+)
+
+// AuthorRule merges or excludes the commit authors whose name matches Match, beyond
+// what the .mailmap file of the repository does: e.g. the identities of a contractor
+// are renamed to Name, and the lines of bot accounts are dropped with Exclude.
+type AuthorRule struct {
+	Match   *regexp.Regexp
+	Name    string
+	Exclude bool
+}
+
+// NormalizeAuthor returns the name of the author after the first matching rule,
+// or false if the author is excluded. Authors without matching rules are unchanged.
+func NormalizeAuthor(rules []AuthorRule, author string) (string, bool) {
+	for _, rule := range rules {
+		if !rule.Match.MatchString(author) {
+			continue
+		}
+		if rule.Exclude {
+			return "", false
+		}
+		return rule.Name, true
+	}
+	return author, true
+}
+
+// normalizeAuthors applies the author rules to the blamed lines, dropping the lines
+// of excluded authors.
+func normalizeAuthors(rules []AuthorRule, lines []*matchLine) []*matchLine {
+	kept := lines[:0]
+	for _, line := range lines {
+		if line.blame == nil {
+			kept = append(kept, line)
+			continue
+		}
+		author := line.blame.FullAuthor
+		if author == "" {
+			author = line.blame.Author
+		}
+		name, ok := NormalizeAuthor(rules, author)
+		if !ok {
+			continue
+		}
+		if name != author {
+			// blames may be shared with the cache, so they're copied
+			b := *line.blame
+			b.Author, b.FullAuthor = blame.TruncateAuthor(name), name
+			line.blame = &b
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
@@ -0,0 +1,197 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mathpn/listme/i18n"
+	"github.com/mathpn/listme/pretty"
+)
+
+// maximum number of mixed files listed in the co-occurrence report
+const maxMixedListed = 10
+
+// addMixed records the matches of a file with more than one tag, by path relative
+// to the searched path.
+func (s *Stats) addMixed(r *searchResult) {
+	tags := make(map[string]int)
+	for _, line := range r.lines {
+		tags[line.tag]++
+	}
+	if len(tags) < 2 {
+		return
+	}
+	path, err := filepath.Rel(r.rootPath, r.path)
+	if err != nil || strings.HasPrefix(path, "..") {
+		path = r.path
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mixed[filepath.ToSlash(path)] = tags
+}
+
+// TagPair is the number of files where two tags, in alphabetical order, appear together.
+type TagPair struct {
+	Tags  [2]string
+	Files int
+}
+
+// MixedFile is a file with more than one tag. Mix is the number of its matches
+// whose tag isn't the most common one, so files that mix tags evenly come first.
+type MixedFile struct {
+	Path string
+	Tags map[string]int
+	Mix  int
+}
+
+// CoOccurrence returns how many files have each pair of tags, from the most common
+// pair, if co-occurrence was tracked.
+func (s *Stats) CoOccurrence() []TagPair {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[[2]string]int)
+	for _, tags := range s.mixed {
+		names := make([]string, 0, len(tags))
+		for tag := range tags {
+			names = append(names, tag)
+		}
+		sort.Strings(names)
+		for i := range names {
+			for j := i + 1; j < len(names); j++ {
+				counts[[2]string{names[i], names[j]}]++
+			}
+		}
+	}
+	out := make([]TagPair, 0, len(counts))
+	for pair, files := range counts {
+		out = append(out, TagPair{Tags: pair, Files: files})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Files != out[j].Files {
+			return out[i].Files > out[j].Files
+		}
+		return out[i].name() < out[j].name()
+	})
+	return out
+}
+
+func (p TagPair) name() string {
+	return p.Tags[0] + "+" + p.Tags[1]
+}
+
+// MixedFiles returns the files with more than one tag, from the most mixed one, if
+// co-occurrence was tracked.
+func (s *Stats) MixedFiles() []MixedFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]MixedFile, 0, len(s.mixed))
+	for path, tags := range s.mixed {
+		f := MixedFile{Path: path, Tags: make(map[string]int, len(tags))}
+		var total, top int
+		for tag, count := range tags {
+			f.Tags[tag] = count
+			total += count
+			top = max(top, count)
+		}
+		f.Mix = total - top
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Mix != out[j].Mix {
+			return out[i].Mix > out[j].Mix
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+func jsonCoOccurrence(pairs []TagPair) map[string]int {
+	if len(pairs) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(pairs))
+	for _, p := range pairs {
+		out[p.name()] = p.Files
+	}
+	return out
+}
+
+func jsonMixedFiles(files []MixedFile) []JSONMixedFile {
+	if len(files) == 0 {
+		return nil
+	}
+	out := make([]JSONMixedFile, 0, len(files))
+	for _, f := range files {
+		out = append(out, JSONMixedFile{Path: f.Path, Tags: f.Tags, Mix: f.Mix})
+	}
+	return out
+}
+
+// renderCoOccurrence writes the number of files per pair of tags, then the most mixed
+// files. The plain style uses one line per pair, then one per file, with the format
+//
+//	pair=BUG+TODO files=3
+//	file=search/search.go mix=4 BUG=4 TODO=6
+func (s *Stats) renderCoOccurrence(w io.Writer, style pretty.Style) {
+	pairs := s.CoOccurrence()
+	if len(pairs) == 0 {
+		return
+	}
+	files := s.MixedFiles()
+	if style != pretty.PlainStyle && len(files) > maxMixedListed {
+		files = files[:maxMixedListed]
+	}
+
+	maxPairLen := 0
+	for _, p := range pairs {
+		maxPairLen = max(maxPairLen, len(p.Tags[0])+len(p.Tags[1])+3)
+	}
+	if style != pretty.PlainStyle {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, pretty.Bold(i18n.T("Tag co-occurrence")))
+	}
+	for _, p := range pairs {
+		if style == pretty.PlainStyle {
+			fmt.Fprintf(w, "pair=%s files=%d\n", p.name(), p.Files)
+			continue
+		}
+		files := i18n.Sprintf("%d files", p.Files)
+		if p.Files == 1 {
+			files = i18n.T("1 file")
+		}
+		fmt.Fprintf(w, "  %-*s %s\n", maxPairLen, p.Tags[0]+" + "+p.Tags[1], files)
+	}
+
+	maxPathLen := 0
+	for _, f := range files {
+		maxPathLen = max(maxPathLen, len(f.Path))
+	}
+	if style != pretty.PlainStyle {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, pretty.Bold(i18n.T("Mixed files")))
+	}
+	for _, f := range files {
+		tags := make([]string, 0, len(f.Tags))
+		for tag := range f.Tags {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		if style == pretty.PlainStyle {
+			fields := []string{"file=" + f.Path, fmt.Sprintf("mix=%d", f.Mix)}
+			for _, tag := range tags {
+				fields = append(fields, fmt.Sprintf("%s=%d", tag, f.Tags[tag]))
+			}
+			fmt.Fprintln(w, strings.Join(fields, " "))
+			continue
+		}
+		row := fmt.Sprintf("  %-*s ", maxPathLen, f.Path)
+		for _, tag := range tags {
+			tagStr := fmt.Sprintf(" %s %d ", pretty.Emojify(tag), f.Tags[tag])
+			row += pretty.Colorize(tagStr, tag, style)
+		}
+		fmt.Fprintln(w, row)
+	}
+}
@@ -0,0 +1,118 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mathpn/listme/matcher"
+)
+
+// Explanation tells whether a search would scan a file, and why not if skipped.
+//   - Skipped: the file would not be scanned, or only partially for binary files
+//   - Reason: why it's skipped, e.g. the .gitignore pattern that matches it
+//   - Lines: number of lines scanned
+//   - Matches: number of tagged comments found in a scanned file, before author and age filters
+type Explanation struct {
+	Skipped bool
+	Reason  string
+	Lines   int
+	Matches int
+}
+
+// Explain returns whether a search of root with the provided options would scan
+// the file or directory at opts.Path, applying the same checks as Search.
+func Explain(opts Options, root string) (*Explanation, error) {
+	path, err := filepath.Abs(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is not inside the searched path %s", opts.Path, root)
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s does not exist", opts.Path)
+	}
+	if err != nil {
+		return &Explanation{Skipped: true, Reason: skipDescriptions[readErrorReason(err)]}, nil
+	}
+
+	opts.Path = root
+	opts.NoGit = true
+	opts.Quiet = true
+	params, err := NewSearchParams(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// directories above the path are skipped along with everything inside them
+	var parts []string
+	if rel != "." {
+		parts = strings.Split(rel, string(filepath.Separator))
+	}
+	current := root
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+		isDir := i < len(parts)-1 || info.IsDir()
+		name := filepath.Join(parts[:i+1]...)
+
+		if matcher.MatchGit(current) {
+			return &Explanation{Skipped: true, Reason: fmt.Sprintf("%s is the git directory, which is never searched", name)}, nil
+		}
+		if params.defaultExcludes && isDir && slices.Contains(DefaultExcludes, part) {
+			return &Explanation{Skipped: true, Reason: fmt.Sprintf("%s is a dependency directory, skipped by default", name)}, nil
+		}
+		if isDir && slices.Contains(params.excludeDirs, part) {
+			return &Explanation{Skipped: true, Reason: fmt.Sprintf("%s is excluded in the configuration file", name)}, nil
+		}
+		switch t, reason := params.matcher.Explain(current); t {
+		case matcher.GitIgnore:
+			return &Explanation{Skipped: true, Reason: fmt.Sprintf("%s is ignored by %s", name, reason)}, nil
+		case matcher.GlobIgnore:
+			if !isDir {
+				return &Explanation{Skipped: true, Reason: fmt.Sprintf("%s doesn't match the %s", name, reason)}, nil
+			}
+		}
+	}
+	if info.IsDir() {
+		return &Explanation{Reason: "directory is searched"}, nil
+	}
+
+	if limit := params.maxFileSize(path); info.Size() > limit<<20 {
+		return &Explanation{
+			Skipped: true,
+			Reason:  fmt.Sprintf("file size of %d bytes exceeds the limit of %d MB", info.Size(), limit),
+		}, nil
+	}
+
+	if params.types != nil {
+		if language := Language(path); !params.types[language] {
+			if language == "" {
+				language = "unknown"
+			}
+			return &Explanation{Skipped: true, Reason: fmt.Sprintf("its language (%s) isn't one of the --type file types", language)}, nil
+		}
+	}
+
+	stats := newStats()
+	job := &searchJob{finder: params.regexes.forPath(path), malformed: params.regexes.malformedFor(path), path: path}
+	lines, nLines, skipReason := findLines(params, job, stats)
+	e := &Explanation{Lines: nLines, Matches: len(lines)}
+	if skipReason != "" {
+		// the line where the file is detected as binary isn't scanned
+		e.Skipped = true
+		e.Lines = nLines - 1
+		e.Reason = fmt.Sprintf("%s, detected at line %d", skipDescriptions[skipReason], nLines)
+	}
+	return e, nil
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Fingerprint returns a stable identifier of a tagged comment, which doesn't change
+// when its line number does. It's a hash of the path of the file, relative to the
+// repository root and with forward slashes, the tag and the comment text, ignoring
+// differences in whitespace. occurrence tells apart identical comments of the same
+// file, counting from zero in line order.
+func Fingerprint(path, tag, text string, occurrence int) string {
+	key := strings.Join([]string{filepath.ToSlash(path), tag, strings.Join(strings.Fields(text), " ")}, "\x00")
+	if occurrence > 0 {
+		key += fmt.Sprintf("\x00%d", occurrence)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// fingerprints returns the Fingerprint of each line of the result.
+func (r *searchResult) fingerprints(params *searchParams) []string {
+	path, err := filepath.Rel(params.repoRoot, r.path)
+	if err != nil {
+		path = r.path
+	}
+	seen := make(map[string]int, len(r.lines))
+	out := make([]string, 0, len(r.lines))
+	for _, line := range r.lines {
+		text := strings.Join(strings.Fields(line.text), " ")
+		key := line.tag + "\x00" + text
+		out = append(out, Fingerprint(path, line.tag, text, seen[key]))
+		seen[key]++
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var plainTagRegex = regexp.MustCompile(`^[A-Za-z]{3,}$`)
+
+// misspellings returns the common misspellings of a tag: other cases, e.g. Todo,
+// swapped adjacent letters, e.g. TOOD, and the uppercase tag split by a space, e.g.
+// FIX ME. Only tags of three letters or more, without regex syntax, have misspellings.
+func misspellings(tag string) (anyCase, upperCase []string) {
+	if !plainTagRegex.MatchString(tag) {
+		return nil, nil
+	}
+	anyCase = append(anyCase, tag)
+	for i := 0; i < len(tag)-1; i++ {
+		if tag[i] == tag[i+1] {
+			continue
+		}
+		swapped := []byte(tag)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		anyCase = append(anyCase, string(swapped))
+	}
+	// splits are only detected in uppercase, so prose such as "to do" isn't a TODO
+	upper := strings.ToUpper(tag)
+	for i := 1; i < len(upper); i++ {
+		upperCase = append(upperCase, upper[:i]+" "+upper[i:])
+	}
+	return anyCase, upperCase
+}
+
+// newMalformedRegex returns a regex that finds misspelled tags in code comments, see
+// misspellings, and the tags they stand for by lowercase misspelling. The regex is nil
+// if none of the tags has misspellings.
+func newMalformedRegex(tags []string) (*regexp.Regexp, map[string]string, error) {
+	canonical := make(map[string]string)
+	var anyCase, upperCase []string
+	for _, tag := range tags {
+		a, u := misspellings(tag)
+		for _, m := range append(a, u...) {
+			if _, ok := canonical[strings.ToLower(m)]; !ok {
+				canonical[strings.ToLower(m)] = tag
+			}
+		}
+		anyCase = append(anyCase, a...)
+		upperCase = append(upperCase, u...)
+	}
+	if len(anyCase) == 0 {
+		return nil, nil, nil
+	}
+	group := fmt.Sprintf(`\b(?:(?i:%s)|%s)`, strings.Join(anyCase, "|"), strings.Join(upperCase, "|"))
+	r, err := regexp.Compile(commentTagRegex(group))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compile malformed tag regex: %s", err)
+	}
+	return r, canonical, nil
+}
+
+// malformedFinder finds misspelled tags. The tag it returns is the misspelling, as written.
+type malformedFinder struct {
+	regexFinder
+	canonical map[string]string
+}
+
+// suggest returns the tag that a misspelling stands for.
+func (f *malformedFinder) suggest(misspelling string) string {
+	return f.canonical[strings.ToLower(misspelling)]
+}
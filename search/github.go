@@ -0,0 +1,55 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mathpn/listme/pretty"
+)
+
+// githubRenderer prints GitHub Actions workflow commands, so matches are shown as
+// annotations of the files. The annotation level follows the severity of the tag.
+// Skipped files and end-of-run totals are written to stderr, as in plain style.
+type githubRenderer struct {
+	params *searchParams
+	stdout io.Writer
+	stderr io.Writer
+}
+
+var annotationLevels = map[pretty.Severity]string{
+	pretty.SeverityInfo:    "notice",
+	pretty.SeverityWarning: "warning",
+	pretty.SeverityError:   "error",
+}
+
+func (g *githubRenderer) result(r *searchResult) {
+	var b strings.Builder
+	for _, m := range r.jsonMatches(g.params) {
+		level := annotationLevels[pretty.LookupTag(m.Tag).Severity]
+		fmt.Fprintf(
+			&b, "::%s file=%s,line=%d,col=%d,title=%s::%s\n",
+			level, escapeProperty(m.Path), m.Line, m.Column, escapeProperty(m.Tag), escapeData(m.Text),
+		)
+	}
+	io.WriteString(g.stdout, b.String())
+}
+
+func (g *githubRenderer) finish(stats *Stats) {
+	var b strings.Builder
+	renderSkipped(&b, stats.Skipped(), g.params.displayPath, g.params.skipReport)
+	if g.params.stats {
+		stats.render(&b, 0, pretty.PlainStyle)
+	}
+	io.WriteString(g.stderr, b.String())
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"html/template"
+	"io"
+	"log/slog"
+	"sort"
+
+	"github.com/mathpn/listme/pretty"
+)
+
+// htmlRenderer prints a self-contained HTML report at the end of the search.
+type htmlRenderer struct {
+	params *searchParams
+	w      io.Writer
+	files  []htmlFile
+}
+
+// htmlFile is a file of the report. Severity is the severity of its most severe tag,
+// if it's a warning or an error, which colors its header.
+type htmlFile struct {
+	Path     string
+	Severity string
+	Matches  []JSONMatch
+}
+
+type htmlTagCount struct {
+	Tag   string
+	Count int
+}
+
+type htmlReport struct {
+	Path    string
+	Total   int
+	Tags    []htmlTagCount
+	Files   []htmlFile
+	Skipped []JSONSkipped
+	Errors  []JSONError
+}
+
+func (h *htmlRenderer) result(r *searchResult) {
+	matches := r.jsonMatches(h.params)
+	if len(matches) == 0 {
+		return
+	}
+	file := htmlFile{Path: matches[0].Path, Matches: matches}
+	if severity := pretty.LookupTag(pretty.SeverestTag(r.tagCounts())).Severity; severity > pretty.SeverityInfo {
+		file.Severity = severity.String()
+	}
+	h.files = append(h.files, file)
+}
+
+func (h *htmlRenderer) finish(stats *Stats) {
+	sort.Slice(h.files, func(i, j int) bool { return h.files[i].Path < h.files[j].Path })
+
+	counts := make(map[string]int)
+	total := 0
+	for _, f := range h.files {
+		for _, m := range f.Matches {
+			counts[m.Tag]++
+			total++
+		}
+	}
+	tags := make([]htmlTagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, htmlTagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+
+	report := htmlReport{
+		Path:    h.params.rootPath,
+		Total:   total,
+		Tags:    tags,
+		Files:   h.files,
+		Skipped: jsonSkipped(stats, h.params),
+		Errors:  jsonErrors(stats, h.params),
+	}
+	if err := htmlTemplate.Execute(h.w, report); err != nil {
+		slog.Error("failed to write HTML report", "error", err)
+	}
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"emoji":    func(tag string) string { return pretty.LookupTag(tag).Emoji },
+	"severity": func(tag string) string { return pretty.LookupTag(tag).Severity.String() },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>listme: {{.Path}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem auto; max-width: 70rem; padding: 0 1rem; color: #222; }
+h1 { font-size: 1.4rem; }
+h2 { font-size: 1.1rem; font-family: monospace; margin-top: 2rem; }
+table { border-collapse: collapse; width: 100%; }
+td { padding: 0.2rem 0.6rem; vertical-align: top; border-bottom: 1px solid #eee; }
+td.line, td.commit { font-family: monospace; color: #777; white-space: nowrap; }
+td.author { color: #555; white-space: nowrap; }
+.tag { font-weight: bold; white-space: nowrap; padding: 0.1rem 0.4rem; border-radius: 0.3rem; }
+.info { background: #e3f2e1; color: #1b5e20; }
+.warning { background: #fff3cd; color: #7a5300; }
+.error { background: #fde2e1; color: #a01010; }
+.summary .tag { margin-right: 0.5rem; }
+h2.warning, h2.error { padding: 0.2rem 0.4rem; border-radius: 0.3rem; }
+</style>
+</head>
+<body>
+<h1>{{.Total}} tagged comments in {{.Path}}</h1>
+<p class="summary">{{range .Tags}}<span class="tag {{severity .Tag}}">{{emoji .Tag}} {{.Tag}}: {{.Count}}</span> {{end}}</p>
+{{range .Files}}
+<h2{{with .Severity}} class="{{.}}"{{end}}>{{.Path}}</h2>
+<table>
+{{- range .Matches}}
+<tr><td class="line">{{.Line}}</td><td><span class="tag {{severity .Tag}}">{{emoji .Tag}} {{.Tag}}</span></td><td>{{.Text}}</td><td class="author">{{.Author}}</td><td class="commit">{{.Commit}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+{{- if .Skipped}}
+<h2>Skipped files</h2>
+<ul>
+{{- range .Skipped}}
+<li>{{.Path}}: {{.Reason}}</li>
+{{- end}}
+</ul>
+{{end}}
+{{- if .Errors}}
+<h2>Errors</h2>
+<ul>
+{{- range .Errors}}
+<li>{{.Path}} ({{.Kind}}): {{.Message}}</li>
+{{- end}}
+</ul>
+{{end -}}
+</body>
+</html>
+`))
@@ -0,0 +1,257 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mathpn/listme/cache"
+	"github.com/mathpn/listme/matcher"
+)
+
+// indexFile is a file listed by the git index. Blob is the hash of its content, empty
+// for untracked files and files modified in the working tree, which must be read.
+type indexFile struct {
+	path string
+	blob string
+}
+
+// gitPathspec returns the directory to run git in and the pathspec matching path,
+// which may be a directory or a file.
+func gitPathspec(path string) (dir, spec string) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return filepath.Dir(path), filepath.Base(path)
+	}
+	return path, "."
+}
+
+// gitList runs a git command in dir, limited to spec, and splits its -z output.
+func gitList(dir, spec string, args ...string) ([][]byte, error) {
+	cmd := exec.Command("git", append(append([]string{"-C", dir}, args...), "--", spec)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %v - %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.Split(bytes.TrimSuffix(out, []byte{0}), []byte{0}), nil
+}
+
+// listTrackedFiles returns the absolute paths of the files under path tracked by git.
+func listTrackedFiles(path string) (map[string]bool, error) {
+	dir, spec := gitPathspec(path)
+	tracked, err := gitList(dir, spec, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool, len(tracked))
+	for _, p := range tracked {
+		if len(p) > 0 {
+			files[filepath.Join(dir, filepath.FromSlash(string(p)))] = true
+		}
+	}
+	return files, nil
+}
+
+// listIndexFiles returns the files of the git repository under path, tracked or
+// untracked and not ignored, using the git index instead of walking the filesystem.
+func listIndexFiles(path string) ([]indexFile, error) {
+	dir, spec := gitPathspec(path)
+	git := func(args ...string) ([][]byte, error) {
+		return gitList(dir, spec, args...)
+	}
+
+	staged, err := git("ls-files", "--stage", "-z")
+	if err != nil {
+		return nil, err
+	}
+	modified, err := git("diff-files", "--relative", "--name-only", "-z")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := git("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string]bool, len(modified))
+	for _, p := range modified {
+		changed[string(p)] = true
+	}
+	files := make([]indexFile, 0, len(staged)+len(untracked))
+	seen := make(map[string]bool, len(staged))
+	for _, entry := range staged {
+		// <mode> <blob> <stage>\t<path>
+		info, p, ok := bytes.Cut(entry, []byte{'\t'})
+		fields := strings.Fields(string(info))
+		if !ok || len(fields) != 3 || seen[string(p)] {
+			continue
+		}
+		seen[string(p)] = true
+		mode, blob, stage := fields[0], fields[1], fields[2]
+		if mode == "160000" {
+			// submodules are directories
+			continue
+		}
+		if stage != "0" || changed[string(p)] {
+			blob = ""
+		}
+		files = append(files, indexFile{path: filepath.Join(dir, filepath.FromSlash(string(p))), blob: blob})
+	}
+	for _, p := range untracked {
+		if len(p) > 0 {
+			files = append(files, indexFile{path: filepath.Join(dir, filepath.FromSlash(string(p)))})
+		}
+	}
+	return files, nil
+}
+
+// walkIndex is walkFiles for files listed by the git index, applying the same filters.
+// It reports false if the index can't be read, e.g. outside of a git repository.
+func walkIndex(params *searchParams, stats *Stats, visit func(path, blob string)) bool {
+	defer params.timings.since(phaseWalk, time.Now())
+	files, err := listIndexFiles(params.rootPath)
+	if err != nil {
+		slog.Warn("couldn't read the git index, walking the filesystem instead", "error", err)
+		return false
+	}
+
+	for _, file := range files {
+		if params.ctx.Err() != nil {
+			return true
+		}
+		path := file.path
+		if inExcludedDir(params, path) {
+			slog.Info("skipping file in excluded directory", "path", path)
+			continue
+		}
+		if params.matcher.Match(path) != matcher.Match {
+			slog.Info("skipping path due to .gitignore or glob pattern", "path", path)
+			stats.addSkipped()
+			continue
+		}
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// deleted from the working tree
+			continue
+		}
+		if err != nil {
+			slog.Debug("couldn't stat path", "path", path, "error", err)
+			stats.skipFile(path, readErrorReason(err))
+			continue
+		}
+		if info.IsDir() {
+			continue
+		}
+		if limit := params.maxFileSize(path); info.Size() > limit<<20 {
+			slog.Info("skipping large file", "path", path, "size", info.Size(), "limit_mb", limit)
+			stats.skipFile(path, SkipSize)
+			continue
+		}
+		visit(path, file.blob)
+	}
+	return true
+}
+
+// inExcludedDir reports whether a directory between the root of the search and path is excluded.
+func inExcludedDir(params *searchParams, path string) bool {
+	rel, err := filepath.Rel(params.rootPath, filepath.Dir(path))
+	if err != nil || rel == "." {
+		return false
+	}
+	return slices.ContainsFunc(strings.Split(rel, string(filepath.Separator)), params.excludedDir)
+}
+
+// openScanCache returns the cache of the git repository that contains path, which
+// stores the scan of files by the hash of their content.
+func openScanCache(path string) (*cache.Cache, error) {
+	root, err := matcher.RepoRoot(path)
+	if err != nil {
+		return nil, err
+	}
+	return cache.Open(root)
+}
+
+// scanEntry is the cached result of scanning a file, stored by the hash of its content.
+type scanEntry struct {
+	Lines      []scanLine `json:"lines"`
+	NLines     int        `json:"n_lines"`
+	SkipReason string     `json:"skip_reason,omitempty"`
+}
+
+type scanLine struct {
+	N         int    `json:"n"`
+	Col       int    `json:"col"`
+	Tag       string `json:"tag"`
+	Text      string `json:"text"`
+	Malformed string `json:"malformed,omitempty"`
+	Symbol    string `json:"symbol,omitempty"`
+	Assignee  string `json:"assignee,omitempty"`
+}
+
+// scanKey returns the key of the cached scan of the blob, which depends on every
+// setting that changes the tagged lines found in a file.
+func scanKey(params *searchParams, job *searchJob) string {
+	var finder string
+	switch f := job.finder.(type) {
+	case *regexFinder:
+		finder = f.regex.String()
+	case *taskFinder:
+		finder = "tasks\x00" + f.tags.(*regexFinder).regex.String()
+	}
+	if job.malformed != nil {
+		finder += "\x00malformed\x00" + job.malformed.regex.String()
+	}
+	if params.showSymbol {
+		finder += "\x00symbols"
+	}
+	// the version changes with the fields of scanLine
+	return strings.Join([]string{"scan-v4", job.blob, finder, fmt.Sprint(params.maxLineLength)}, "\x00")
+}
+
+// findLinesCached is findLines for files of the git index, whose scan is cached by the
+// hash of their content, so unchanged files are only read once across searches.
+func findLinesCached(params *searchParams, job *searchJob, stats *Stats) ([]*matchLine, int, string) {
+	if params.scanCache == nil || job.blob == "" {
+		return findLines(params, job, stats)
+	}
+	key := scanKey(params, job)
+	var entry scanEntry
+	if params.scanCache.Get(key, &entry) {
+		lines := make([]*matchLine, 0, len(entry.Lines))
+		for _, l := range entry.Lines {
+			lines = append(lines, &matchLine{n: l.N, col: l.Col, tag: l.Tag, text: l.Text, malformed: l.Malformed, symbol: l.Symbol, assignee: l.Assignee})
+		}
+		return lines, entry.NLines, entry.SkipReason
+	}
+
+	// files with read errors aren't cached, since their results may be incomplete,
+	// nor files that couldn't be opened, since their content is unknown
+	fileStats := newStats()
+	lines, nLines, skipReason := findLines(params, job, fileStats)
+	if skipReason == SkipPermission || skipReason == SkipUnreadable {
+		return lines, nLines, skipReason
+	}
+	if errs := fileStats.Errors(); len(errs) > 0 {
+		for _, e := range errs {
+			stats.addError(e.Path, e.Kind, errors.New(e.Message))
+		}
+		return lines, nLines, skipReason
+	}
+	entry = scanEntry{NLines: nLines, SkipReason: skipReason, Lines: make([]scanLine, 0, len(lines))}
+	for _, l := range lines {
+		entry.Lines = append(entry.Lines, scanLine{N: l.n, Col: l.col, Tag: l.tag, Text: l.text, Malformed: l.malformed, Symbol: l.symbol, Assignee: l.assignee})
+	}
+	if err := params.scanCache.Put(key, entry); err != nil {
+		slog.Debug("failed to cache scan", "path", job.path, "error", err)
+	}
+	return lines, nLines, skipReason
+}
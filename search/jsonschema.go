@@ -0,0 +1,98 @@
+package search
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// schemaEnums lists the accepted values of string fields, by type and JSON field name.
+var schemaEnums = map[string][]string{
+	"JSONLRecord.type":   {MatchRecord, SkippedRecord, ErrorRecord, StatsRecord},
+	"JSONSkipped.reason": skipReasons,
+	"JSONError.kind":     {ErrorBlame, ErrorRead},
+}
+
+// JSONSchema returns the JSON Schema (draft 2020-12) of the documents printed by
+// JSONFormat or of the records printed by JSONLFormat. It's generated from the
+// JSON* structs, so it always matches the output. Fields without omitempty are required.
+func JSONSchema(format Format) (map[string]any, error) {
+	var root reflect.Type
+	var title string
+	switch format {
+	case JSONFormat:
+		root, title = reflect.TypeOf(JSONOutput{}), "listme JSON output"
+	case JSONLFormat:
+		root, title = reflect.TypeOf(JSONLRecord{}), "listme JSONL record"
+	default:
+		return nil, fmt.Errorf("only the json and jsonl formats have a schema")
+	}
+
+	defs := make(map[string]any)
+	schema := structSchema(root, defs)
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = title
+	schema["$defs"] = defs
+	return schema, nil
+}
+
+// typeSchema returns the schema of a Go type, adding the schema of named structs to defs.
+func typeSchema(t reflect.Type, defs map[string]any) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), defs)
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			// reserve the name first, in case the struct refers to itself
+			defs[t.Name()] = nil
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	}
+	panic(fmt.Sprintf("no JSON schema for type %s", t))
+}
+
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	properties := make(map[string]any, t.NumField())
+	required := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := typeSchema(field.Type, defs)
+		if name == "schema_version" {
+			property["const"] = SchemaVersion
+		}
+		if values, ok := schemaEnums[t.Name()+"."+name]; ok {
+			property["enum"] = values
+		}
+		properties[name] = property
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
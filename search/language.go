@@ -0,0 +1,204 @@
+package search
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// languageByExt maps lowercase file extensions to language names, following the
+// names used by GitHub Linguist.
+var languageByExt = map[string]string{
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hh":     "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".clj":    "Clojure",
+	".cljs":   "Clojure",
+	".css":    "CSS",
+	".dart":   "Dart",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".elm":    "Elm",
+	".erl":    "Erlang",
+	".fs":     "F#",
+	".go":     "Go",
+	".gradle": "Groovy",
+	".groovy": "Groovy",
+	".hs":     "Haskell",
+	".html":   "HTML",
+	".htm":    "HTML",
+	".java":   "Java",
+	".js":     "JavaScript",
+	".cjs":    "JavaScript",
+	".mjs":    "JavaScript",
+	".jsx":    "JavaScript",
+	".jl":     "Julia",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".lisp":   "Common Lisp",
+	".lua":    "Lua",
+	".md":     "Markdown",
+	".mdown":  "Markdown",
+	".mkd":    "Markdown",
+	".m":      "Objective-C",
+	".mm":     "Objective-C++",
+	".ml":     "OCaml",
+	".mli":    "OCaml",
+	".php":    "PHP",
+	".pl":     "Perl",
+	".pm":     "Perl",
+	".ps1":    "PowerShell",
+	".proto":  "Protocol Buffer",
+	".py":     "Python",
+	".pyi":    "Python",
+	".r":      "R",
+	".rb":     "Ruby",
+	".rs":     "Rust",
+	".rst":    "reStructuredText",
+	".sass":   "Sass",
+	".scala":  "Scala",
+	".scss":   "SCSS",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".sql":    "SQL",
+	".svelte": "Svelte",
+	".swift":  "Swift",
+	".tex":    "TeX",
+	".tf":     "HCL",
+	".toml":   "TOML",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".txt":    "Text",
+	".vim":    "Vim Script",
+	".vue":    "Vue",
+	".xml":    "XML",
+	".yaml":   "YAML",
+	".yml":    "YAML",
+	".zig":    "Zig",
+}
+
+// languageByName maps the names of files without a telling extension to their language.
+var languageByName = map[string]string{
+	"Dockerfile":     "Dockerfile",
+	"Makefile":       "Makefile",
+	"GNUmakefile":    "Makefile",
+	"CMakeLists.txt": "CMake",
+	"Gemfile":        "Ruby",
+	"Rakefile":       "Ruby",
+	"Jenkinsfile":    "Groovy",
+}
+
+// languageByInterpreter maps the interpreters of shebang lines to their language.
+var languageByInterpreter = map[string]string{
+	"sh":      "Shell",
+	"bash":    "Shell",
+	"zsh":     "Shell",
+	"ksh":     "Shell",
+	"dash":    "Shell",
+	"fish":    "fish",
+	"python":  "Python",
+	"python2": "Python",
+	"python3": "Python",
+	"node":    "JavaScript",
+	"deno":    "TypeScript",
+	"ruby":    "Ruby",
+	"perl":    "Perl",
+	"php":     "PHP",
+	"lua":     "Lua",
+	"Rscript": "R",
+	"julia":   "Julia",
+}
+
+// typeAliases maps short file type names, as in --type sh,py, to languages. Any
+// language can also be named in lowercase, e.g. python or javascript.
+var typeAliases = map[string]string{
+	"sh":   "Shell",
+	"bash": "Shell",
+	"zsh":  "Shell",
+	"py":   "Python",
+	"js":   "JavaScript",
+	"ts":   "TypeScript",
+	"rb":   "Ruby",
+	"rs":   "Rust",
+	"md":   "Markdown",
+	"cpp":  "C++",
+	"cs":   "C#",
+	"kt":   "Kotlin",
+	"pl":   "Perl",
+	"yml":  "YAML",
+}
+
+// ParseFileType returns the language of a file type name, such as sh, py or rust.
+func ParseFileType(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if lang, ok := typeAliases[name]; ok {
+		return lang, nil
+	}
+	for _, languages := range []map[string]string{languageByExt, languageByName, languageByInterpreter} {
+		for _, lang := range languages {
+			if strings.ToLower(lang) == name {
+				return lang, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unknown file type: %s", name)
+}
+
+// Language returns the programming language of a file from its name or extension
+// or, failing that, from the interpreter of its shebang line, e.g. #!/usr/bin/env python3.
+// It returns an empty string if the language is unknown.
+func Language(path string) string {
+	name := filepath.Base(path)
+	if lang, ok := languageByName[name]; ok {
+		return lang
+	}
+	if lang, ok := languageByExt[strings.ToLower(filepath.Ext(name))]; ok {
+		return lang
+	}
+	return shebangLanguage(path)
+}
+
+func shebangLanguage(path string) string {
+	f, err := os.Open(filepath.FromSlash(path))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	line, _ := bufio.NewReaderSize(f, 256).ReadSlice('\n')
+	return interpreterLanguage(string(line))
+}
+
+// interpreterLanguage returns the language of the interpreter of a shebang line.
+func interpreterLanguage(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	// with env, the interpreter is the first argument that isn't an option, e.g. env -S python3 -u
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = field
+				break
+			}
+		}
+	}
+	if lang, ok := languageByInterpreter[interpreter]; ok {
+		return lang
+	}
+	// versioned interpreters, e.g. python3.12 or ruby2.7
+	return languageByInterpreter[strings.TrimRight(interpreter, "0123456789.")]
+}
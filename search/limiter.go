@@ -0,0 +1,27 @@
+package search
+
+// ioLimiter bounds the number of files read at the same time, independently from
+// the number of workers, so network filesystems and spinning disks aren't flooded
+// with concurrent reads. A nil ioLimiter doesn't limit.
+type ioLimiter chan struct{}
+
+func newIOLimiter(n int) ioLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(ioLimiter, n)
+}
+
+// acquire blocks until a file can be read.
+func (l ioLimiter) acquire() {
+	if l != nil {
+		l <- struct{}{}
+	}
+}
+
+// release frees the slot taken by acquire.
+func (l ioLimiter) release() {
+	if l != nil {
+		<-l
+	}
+}
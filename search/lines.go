@@ -0,0 +1,86 @@
+package search
+
+import (
+	"bufio"
+	"io"
+)
+
+// lineReader reads the lines of a file like bufio.Scanner, but lines longer than
+// the limit are skipped instead of stopping the scan, so the rest of the file is
+// still searched and line numbers stay correct.
+type lineReader struct {
+	r       *bufio.Reader
+	max     int
+	line    []byte
+	skipped bool
+	err     error
+}
+
+func newLineReader(r io.Reader, max int) *lineReader {
+	return &lineReader{r: bufio.NewReaderSize(r, bufio.MaxScanTokenSize), max: max}
+}
+
+// next advances to the next line, returning false at the end of the file or on
+// errors. If the line is longer than the limit, bytes is empty and skipped is true.
+func (l *lineReader) next() bool {
+	if l.err != nil {
+		return false
+	}
+	l.line = l.line[:0]
+	l.skipped = false
+	var read int
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		read += len(chunk)
+		if !l.skipped {
+			if len(l.line)+len(chunk) > l.max+2 {
+				// allow for the line ending, the length of a line doesn't include it
+				l.skipped = true
+				l.line = l.line[:0]
+			} else {
+				l.line = append(l.line, chunk...)
+			}
+		}
+		switch err {
+		case nil:
+			l.trim()
+			return true
+		case bufio.ErrBufferFull:
+			continue
+		default:
+			l.err = err
+			if read == 0 {
+				return false
+			}
+			l.trim()
+			return true
+		}
+	}
+}
+
+// trim drops the line ending, as bufio.ScanLines does.
+func (l *lineReader) trim() {
+	if n := len(l.line); n > 0 && l.line[n-1] == '\n' {
+		l.line = l.line[:n-1]
+	}
+	if n := len(l.line); n > 0 && l.line[n-1] == '\r' {
+		l.line = l.line[:n-1]
+	}
+	if len(l.line) > l.max {
+		l.skipped = true
+		l.line = l.line[:0]
+	}
+}
+
+// bytes returns the current line, valid until the next call to next.
+func (l *lineReader) bytes() []byte {
+	return l.line
+}
+
+// error returns the first error other than io.EOF.
+func (l *lineReader) error() error {
+	if l.err == io.EOF {
+		return nil
+	}
+	return l.err
+}
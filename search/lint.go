@@ -0,0 +1,112 @@
+package search
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mathpn/listme/i18n"
+)
+
+// LintRules flag low-quality tagged comments, which are reported with the issues they
+// have and counted separately in Stats. Comments without text are always flagged.
+//   - MinLength: minimum length of the comment text, in characters (0 disables it)
+//   - RequireAssignee: comments must name an assignee, as in TODO(alice) or @alice
+//   - RequireIssue: comments must reference an issue, as in #123, PROJ-123 or a link
+type LintRules struct {
+	MinLength       int
+	RequireAssignee bool
+	RequireIssue    bool
+}
+
+// Lint issues of tagged comments.
+//   - LintEmpty: the tag has no comment text
+//   - LintShort: the comment text is shorter than LintRules.MinLength
+//   - LintNoAssignee: the comment doesn't name an assignee
+//   - LintNoIssue: the comment doesn't reference an issue
+const (
+	LintEmpty      = "empty"
+	LintShort      = "short"
+	LintNoAssignee = "no_assignee"
+	LintNoIssue    = "no_issue"
+)
+
+// LintIssues lists the lint issues, in the order they're reported.
+var LintIssues = []string{LintEmpty, LintShort, LintNoAssignee, LintNoIssue}
+
+// lintLabels are the labels of lint issues in the human-readable styles.
+var lintLabels = map[string]string{
+	LintEmpty:      "no text",
+	LintShort:      "too short",
+	LintNoAssignee: "no assignee",
+	LintNoIssue:    "no issue",
+}
+
+// same conventions as the metadata highlighted by pretty.HighlightMetadata
+var (
+	assigneeRegex = regexp.MustCompile(`(?:^|[\s(\[,;:])@[\w.-]*\w\b`)
+	issueRegex    = regexp.MustCompile(`(?:^|[\s(\[,;:])(?:#\d+|[A-Z][A-Z0-9]+-\d+)\b|https?://\S+`)
+)
+
+// check returns the lint issues of the line.
+func (r *LintRules) check(line *matchLine) []string {
+	text := strings.TrimSpace(line.text)
+	if text == "" {
+		// the other rules would only repeat it
+		return []string{LintEmpty}
+	}
+	var issues []string
+	if r.MinLength > 0 && utf8.RuneCountInString(text) < r.MinLength {
+		issues = append(issues, LintShort)
+	}
+	if r.RequireAssignee && line.assignee == "" && !assigneeRegex.MatchString(text) {
+		issues = append(issues, LintNoAssignee)
+	}
+	if r.RequireIssue && !issueRegex.MatchString(text) {
+		issues = append(issues, LintNoIssue)
+	}
+	return issues
+}
+
+// apply sets the lint issues of the lines. It does nothing if r is nil, i.e. when
+// comments aren't linted.
+func (r *LintRules) apply(lines []*matchLine) {
+	if r == nil {
+		return
+	}
+	for _, line := range lines {
+		line.lint = r.check(line)
+	}
+}
+
+// lintLabel returns the label of the lint issues shown next to comments.
+func lintLabel(issues []string) string {
+	labels := make([]string, len(issues))
+	for i, issue := range issues {
+		labels[i] = i18n.T(lintLabels[issue])
+	}
+	return i18n.Sprintf("lint: %s", strings.Join(labels, ", "))
+}
+
+// taggedAssignee returns the assignee written in parentheses right after the tag, as
+// in TODO(alice), which is not part of the comment text. Issue references added by
+// listme sync --rewrite, as in TODO(alice, #123), are not part of the assignee.
+func taggedAssignee(line []byte, tag string) string {
+	i := bytes.Index(line, []byte(tag+"("))
+	if i < 0 {
+		return ""
+	}
+	rest := line[i+len(tag)+1:]
+	end := bytes.IndexByte(rest, ')')
+	if end < 0 {
+		return ""
+	}
+	var names []string
+	for _, item := range strings.Split(string(rest[:end]), ",") {
+		if item = strings.TrimSpace(item); item != "" && !issueRegex.MatchString(item) {
+			names = append(names, item)
+		}
+	}
+	return strings.Join(names, ", ")
+}
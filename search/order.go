@@ -0,0 +1,28 @@
+package search
+
+// sequencer releases search results in walk order. Results that arrive before
+// the ones of earlier files are held back until those are complete.
+// Every scanned file must send a result, even if empty, to release the next ones.
+type sequencer struct {
+	next    int
+	pending map[int]*searchResult
+}
+
+func newSequencer() *sequencer {
+	return &sequencer{pending: make(map[int]*searchResult)}
+}
+
+// push adds the result and returns the ones that can be released, in order.
+func (s *sequencer) push(r *searchResult) []*searchResult {
+	s.pending[r.seq] = r
+	var ready []*searchResult
+	for {
+		next, ok := s.pending[s.next]
+		if !ok {
+			return ready
+		}
+		delete(s.pending, s.next)
+		s.next++
+		ready = append(ready, next)
+	}
+}
@@ -0,0 +1,301 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mathpn/listme/pretty"
+)
+
+// Format of the search output.
+//   - TextFormat: human-readable output (or plain lines) according to the style
+//   - JSONFormat: a single JSON document printed at the end of the search
+//   - JSONLFormat: one JSON record per line, printed as results arrive
+//   - HTMLFormat: a self-contained HTML report printed at the end of the search
+//   - GitHubFormat: GitHub Actions workflow commands, shown as annotations of the files
+//   - LocationsFormat: path:line:column of each match, without decoration, for editor pickers
+//   - SARIFFormat: a SARIF 2.1.0 log printed at the end of the search, for code scanning platforms
+type Format int
+
+const (
+	TextFormat Format = iota
+	JSONFormat
+	JSONLFormat
+	HTMLFormat
+	GitHubFormat
+	LocationsFormat
+	SARIFFormat
+)
+
+// Formats lists the accepted names of the output formats.
+var Formats = []string{"text", "json", "jsonl", "html", "github", "locations", "sarif"}
+
+// ParseFormat returns the Format with the provided name.
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "", "text":
+		return TextFormat, nil
+	case "json":
+		return JSONFormat, nil
+	case "jsonl":
+		return JSONLFormat, nil
+	case "html":
+		return HTMLFormat, nil
+	case "github":
+		return GitHubFormat, nil
+	case "locations":
+		return LocationsFormat, nil
+	case "sarif":
+		return SARIFFormat, nil
+	default:
+		return TextFormat, fmt.Errorf("unknown output format: %s", name)
+	}
+}
+
+// renderer prints search results. It's the only writer of stdout, which carries
+// nothing but results in the selected format: diagnostics such as skipped files go
+// to stderr. Renderers are not safe for concurrent use, results must be sent from
+// a single goroutine.
+//   - result: prints the results of a file
+//   - finish: prints anything left once all results were sent, including the
+//     end-of-run totals if requested
+type renderer interface {
+	result(r *searchResult)
+	finish(stats *Stats)
+}
+
+// newRenderer returns the renderer for the selected format, writing results to stdout
+// and diagnostics to stderr. Quiet searches never write to stdout.
+func newRenderer(params *searchParams, stdout, stderr io.Writer) renderer {
+	if params.quiet {
+		return &quietRenderer{params: params, stderr: stderr}
+	}
+	switch params.format {
+	case JSONFormat:
+		return &jsonRenderer{params: params, enc: json.NewEncoder(stdout), matches: make([]JSONMatch, 0)}
+	case JSONLFormat:
+		return &jsonlRenderer{params: params, enc: json.NewEncoder(stdout)}
+	case HTMLFormat:
+		return &htmlRenderer{params: params, w: stdout}
+	case GitHubFormat:
+		return &githubRenderer{params: params, stdout: stdout, stderr: stderr}
+	case LocationsFormat:
+		return &locationsRenderer{params: params, stdout: stdout, stderr: stderr}
+	case SARIFFormat:
+		return &sarifRenderer{params: params, stdout: stdout, stderr: stderr}
+	default:
+		var width *terminalWidth
+		if params.style != pretty.PlainStyle {
+			width = newTerminalWidth()
+		}
+		return &textRenderer{params: params, width: width, stdout: stdout, stderr: stderr}
+	}
+}
+
+// textRenderer prints the human-readable report, or plain lines, according to the style.
+// Skipped files and end-of-run totals are diagnostics written to stderr, so each line of
+// plain output is a match.
+type textRenderer struct {
+	params *searchParams
+	width  *terminalWidth // nil in plain style
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (t *textRenderer) result(r *searchResult) {
+	var b strings.Builder
+	var width int
+	if t.width != nil {
+		width = t.width.get()
+	}
+	r.Render(&b, width, t.params)
+	io.WriteString(t.stdout, b.String())
+}
+
+func (t *textRenderer) finish(stats *Stats) {
+	var width int
+	if t.width != nil {
+		width = t.width.get()
+		t.width.close()
+	}
+	var b strings.Builder
+	renderSkipped(&b, stats.Skipped(), t.params.displayPath, t.params.skipReport)
+	if t.params.stats {
+		stats.render(&b, width, t.params.style)
+	} else if t.params.exitSummary && t.params.style == pretty.PlainStyle {
+		stats.renderSummary(&b)
+	}
+	io.WriteString(t.stderr, b.String())
+}
+
+// WritePlainLine writes a tagged comment with the plain style format
+//
+//	path:line:column:TAG:hash:timestamp:text
+//
+// where hash is the short commit hash and timestamp the commit date, in seconds since
+// the Unix epoch. Both are empty if they're unknown, i.e. hash is empty or date is zero.
+// The text is trimmed of surrounding whitespace, as in JSON output.
+func WritePlainLine(w io.Writer, path string, line, column int, tag, hash string, date time.Time, text string) {
+	var timestamp string
+	if !date.IsZero() {
+		timestamp = strconv.FormatInt(date.Unix(), 10)
+	}
+	fmt.Fprintf(w, "%s:%d:%d:%s:%s:%s:%s\n", path, line, column, tag, hash, timestamp, strings.TrimSpace(text))
+}
+
+// locationsRenderer prints the location of each match, path:line:column, as expected by
+// the file pickers of editors such as Helix and Kakoune. Diagnostics go to stderr, as in
+// plain text output.
+type locationsRenderer struct {
+	params *searchParams
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (l *locationsRenderer) result(r *searchResult) {
+	var b strings.Builder
+	path := l.params.displayPath(r.path)
+	for _, line := range r.lines {
+		fmt.Fprintf(&b, "%s:%d:%d\n", path, line.n, line.col)
+	}
+	io.WriteString(l.stdout, b.String())
+}
+
+func (l *locationsRenderer) finish(stats *Stats) {
+	var b strings.Builder
+	renderSkipped(&b, stats.Skipped(), l.params.displayPath, l.params.skipReport)
+	if l.params.stats {
+		stats.render(&b, 0, pretty.PlainStyle)
+	}
+	io.WriteString(l.stderr, b.String())
+}
+
+// jsonRenderer prints a single JSONOutput document at the end of the search.
+type jsonRenderer struct {
+	params  *searchParams
+	enc     *json.Encoder
+	matches []JSONMatch
+}
+
+func (j *jsonRenderer) result(r *searchResult) {
+	j.matches = append(j.matches, r.jsonMatches(j.params)...)
+}
+
+func (j *jsonRenderer) finish(stats *Stats) {
+	doc := JSONOutput{
+		SchemaVersion: SchemaVersion,
+		Matches:       j.matches,
+		Skipped:       jsonSkipped(stats, j.params),
+		Errors:        jsonErrors(stats, j.params),
+		Interrupted:   stats.Interrupted(),
+	}
+	if j.params.stats {
+		doc.Stats = stats.jsonStats()
+	}
+	encode(j.enc, doc)
+}
+
+// jsonlRenderer prints one JSONLRecord per line as results arrive.
+type jsonlRenderer struct {
+	params *searchParams
+	enc    *json.Encoder
+}
+
+func (j *jsonlRenderer) result(r *searchResult) {
+	for _, m := range r.jsonMatches(j.params) {
+		m := m
+		encode(j.enc, JSONLRecord{SchemaVersion: SchemaVersion, Type: MatchRecord, Match: &m})
+	}
+}
+
+func (j *jsonlRenderer) finish(stats *Stats) {
+	for _, skipped := range jsonSkipped(stats, j.params) {
+		skipped := skipped
+		encode(j.enc, JSONLRecord{SchemaVersion: SchemaVersion, Type: SkippedRecord, Skipped: &skipped})
+	}
+	for _, e := range jsonErrors(stats, j.params) {
+		e := e
+		encode(j.enc, JSONLRecord{SchemaVersion: SchemaVersion, Type: ErrorRecord, Error: &e})
+	}
+	if j.params.stats {
+		encode(j.enc, JSONLRecord{SchemaVersion: SchemaVersion, Type: StatsRecord, Stats: stats.jsonStats()})
+	}
+}
+
+// quietRenderer only reports skipped files to stderr, leaving stdout to the caller.
+type quietRenderer struct {
+	params *searchParams
+	stderr io.Writer
+}
+
+func (q *quietRenderer) result(r *searchResult) {}
+
+func (q *quietRenderer) finish(stats *Stats) {
+	var b strings.Builder
+	renderSkipped(&b, stats.Skipped(), q.params.displayPath, q.params.skipReport)
+	io.WriteString(q.stderr, b.String())
+}
+
+func jsonSkipped(stats *Stats, params *searchParams) []JSONSkipped {
+	skipped := stats.Skipped()
+	out := make([]JSONSkipped, 0, len(skipped))
+	for _, f := range skipped {
+		out = append(out, JSONSkipped{Path: params.displayPath(f.Path), Reason: f.Reason, Line: f.Line})
+	}
+	return out
+}
+
+func jsonErrors(stats *Stats, params *searchParams) []JSONError {
+	errs := stats.Errors()
+	out := make([]JSONError, 0, len(errs))
+	for _, e := range errs {
+		out = append(out, JSONError{Path: params.displayPath(e.Path), Kind: e.Kind, Message: e.Message})
+	}
+	return out
+}
+
+func encode(enc *json.Encoder, v any) {
+	if err := enc.Encode(v); err != nil {
+		slog.Error("failed to encode JSON output", "error", err)
+	}
+}
+
+func (r *searchResult) jsonMatches(params *searchParams) []JSONMatch {
+	path := params.displayPath(r.path)
+	owners := params.owners.Of(r.path)
+	fingerprints := r.fingerprints(params)
+	matches := make([]JSONMatch, 0, len(r.lines))
+	for i, line := range r.lines {
+		m := JSONMatch{
+			Path:        path,
+			Line:        line.n,
+			Column:      line.col,
+			Tag:         line.tag,
+			Text:        strings.TrimSpace(line.text),
+			Fingerprint: fingerprints[i],
+			Language:    r.language,
+			Owners:      owners,
+			Malformed:   line.malformed,
+			Symbol:      line.symbol,
+			Lint:        line.lint,
+		}
+		if line.blame != nil {
+			if params.showAuthor {
+				m.Author = line.blame.Author
+			}
+			m.Commit = line.blame.ShortHash()
+			if !line.blame.Time.IsZero() {
+				date := line.blame.Time
+				m.Date = &date
+				m.Timestamp = date.Unix()
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
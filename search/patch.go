@@ -0,0 +1,106 @@
+package search
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mathpn/listme/matcher"
+)
+
+// Patch holds the lines added by a diff, such as the output of git diff, by path
+// relative to the repository root (or to the searched directory, outside of
+// repositories) and by line number in the new version of the file.
+type Patch map[string]map[int]string
+
+// Add records an added line.
+func (p Patch) Add(path string, n int, text string) {
+	lines, ok := p[path]
+	if !ok {
+		lines = make(map[int]string)
+		p[path] = lines
+	}
+	lines[n] = text
+}
+
+// resolve returns the patch keyed by the paths of the files as they're walked.
+func (p Patch) resolve(root string) Patch {
+	resolved := make(Patch, len(p))
+	for path, lines := range p {
+		resolved[filepath.Join(root, filepath.FromSlash(path))] = lines
+	}
+	return resolved
+}
+
+// filter returns the tagged lines of the file that were added by the patch. The files
+// are expected to be at the new version of the diff, so lines whose tag is not in the
+// added text are dropped, in case they aren't.
+func (p Patch) filter(path string, lines []*matchLine) []*matchLine {
+	added := p[path]
+	kept := lines[:0]
+	for _, line := range lines {
+		text, ok := added[line.n]
+		if !ok {
+			continue
+		}
+		if !strings.Contains(text, line.tag) {
+			slog.Debug("skipping line that differs from the patch", "path", path, "line", line.n)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// walkPatch calls visit with every file changed by the patch under the root of the
+// search that would be scanned, in path order, instead of walking the whole tree. The
+// other files aren't part of the search, so they aren't counted as skipped.
+func walkPatch(params *searchParams, stats *Stats, visit func(path string)) {
+	defer params.timings.since(phaseWalk, time.Now())
+	paths := make([]string, 0, len(params.patch))
+	for path := range params.patch {
+		if rel, err := filepath.Rel(params.rootPath, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if params.ctx.Err() != nil {
+			return
+		}
+		if inExcludedDir(params, path) {
+			slog.Info("skipping file in excluded directory", "path", path)
+			continue
+		}
+		if params.matcher.Match(path) != matcher.Match {
+			slog.Info("skipping path due to .gitignore or glob pattern", "path", path)
+			stats.addSkipped()
+			continue
+		}
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// deleted since the diff
+			continue
+		}
+		if err != nil {
+			slog.Debug("couldn't stat path", "path", path, "error", err)
+			stats.skipFile(path, readErrorReason(err))
+			continue
+		}
+		if info.IsDir() {
+			continue
+		}
+		if limit := params.maxFileSize(path); info.Size() > limit<<20 {
+			slog.Info("skipping large file", "path", path, "size", info.Size(), "limit_mb", limit)
+			stats.skipFile(path, SkipSize)
+			continue
+		}
+		visit(path)
+	}
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"sync"
+
+	"github.com/mathpn/listme/blame"
+)
+
+// blameFuture is the git blame of a whole file, started as soon as the first tagged
+// line of the file is found, so it runs while the rest of the file is scanned.
+type blameFuture struct {
+	once    sync.Once
+	started bool // only read and written by the worker scanning the file
+	done    chan struct{}
+	gb      *blame.GitBlame
+	err     error
+}
+
+func newBlameFuture() *blameFuture {
+	return &blameFuture{done: make(chan struct{})}
+}
+
+// start runs git blame in the background, only the first time it's called.
+func (f *blameFuture) start(params *searchParams, path string) {
+	f.once.Do(func() {
+		f.started = true
+		go func() {
+			defer close(f.done)
+			params.openFiles.acquire()
+			defer params.openFiles.release()
+			f.gb, f.err = params.blameFile(path, nil)
+		}()
+	})
+}
+
+// wait blocks until the blame started by start is done.
+func (f *blameFuture) wait() (*blame.GitBlame, error) {
+	<-f.done
+	return f.gb, f.err
+}
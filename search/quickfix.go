@@ -0,0 +1,58 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mathpn/listme/pretty"
+)
+
+// QuickfixFormat of the jump list written to Options.Quickfix. Each match is a
+// path:line:column: severity: TAG text line, with absolute paths, as in GNU compiler messages.
+//   - VimQuickfix: read by the default errorformat of Vim and Neovim, e.g. with :cfile
+//   - EmacsQuickfix: the same lines after a mode line, so Emacs opens the file in compilation-mode
+type QuickfixFormat int
+
+const (
+	VimQuickfix QuickfixFormat = iota
+	EmacsQuickfix
+)
+
+// QuickfixFormats lists the accepted names of the quickfix formats.
+var QuickfixFormats = []string{"vim", "emacs"}
+
+// ParseQuickfixFormat returns the QuickfixFormat with the provided name.
+func ParseQuickfixFormat(name string) (QuickfixFormat, error) {
+	switch name {
+	case "", "vim":
+		return VimQuickfix, nil
+	case "emacs":
+		return EmacsQuickfix, nil
+	default:
+		return VimQuickfix, fmt.Errorf("unknown quickfix format: %s", name)
+	}
+}
+
+// quickfixRenderer writes the matches of every result to the quickfix file, then
+// passes the result on to the renderer of the selected format.
+type quickfixRenderer struct {
+	renderer
+	w io.Writer
+}
+
+func newQuickfixRenderer(next renderer, w io.Writer, format QuickfixFormat) *quickfixRenderer {
+	if format == EmacsQuickfix {
+		io.WriteString(w, "-*- mode: compilation -*-\n")
+	}
+	return &quickfixRenderer{renderer: next, w: w}
+}
+
+func (q *quickfixRenderer) result(r *searchResult) {
+	var b strings.Builder
+	for _, line := range r.lines {
+		fmt.Fprintf(&b, "%s:%d:%d: %s: %s %s\n", r.path, line.n, line.col, pretty.LookupTag(line.tag).Severity, line.tag, strings.TrimSpace(line.text))
+	}
+	io.WriteString(q.w, b.String())
+	q.renderer.result(r)
+}
@@ -0,0 +1,315 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TaskTag is the synthetic tag given to unchecked Markdown task items.
+const TaskTag = "TASK"
+
+var taskRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+\[ \]\s+(.*?)\s*$`)
+
+var markdownExts = map[string]bool{".md": true, ".markdown": true, ".mdown": true, ".mkd": true}
+
+// Documentation rules define how tags are found in files of a given extension.
+//   - ProseRule: tags are found anywhere in plain paragraphs, no comment prefix is required
+//   - CommentRule: tags must be in a comment, as in code files
+const (
+	ProseRule   = "prose"
+	CommentRule = "comment"
+)
+
+// DefaultDocumentationRules are the rules used for prose file formats unless overridden.
+var DefaultDocumentationRules = map[string]string{
+	".md":       ProseRule,
+	".markdown": ProseRule,
+	".rst":      ProseRule,
+	".adoc":     ProseRule,
+	".asciidoc": ProseRule,
+}
+
+// tagFinder finds a tagged comment in a line of text.
+// If found, it returns the tag, the comment text and the 1-based column of the tag,
+// in characters.
+type tagFinder interface {
+	find(line []byte) (tag, text string, col int, ok bool)
+}
+
+// regexFinder uses a regex with two groups: the tag and the comment text.
+type regexFinder struct {
+	regex *regexp.Regexp
+}
+
+func (f *regexFinder) find(line []byte) (string, string, int, bool) {
+	// tags embedded in a word are skipped, looking for another tag further in the line
+	for offset := 0; offset < len(line); {
+		match := f.regex.FindSubmatchIndex(line[offset:])
+		if len(match) < 6 || match[2] < 0 {
+			return "", "", 0, false
+		}
+		start, end := offset+match[2], offset+match[3]
+		if embedded(line, start, end) {
+			offset = end
+			continue
+		}
+		var text string
+		if match[4] >= 0 {
+			text = string(line[offset+match[4] : offset+match[5]])
+		}
+		return string(line[start:end]), text, column(line, start), true
+	}
+	return "", "", 0, false
+}
+
+// commentMarkers can precede a tag in the same word, as in //TODO or #FIXME.
+var commentMarkers = [][]byte{
+	[]byte("//"), []byte("#"), []byte("--"), []byte("/*"), []byte("<!--"), []byte(`"""`), []byte("'''"), []byte(";"),
+}
+
+// embedded reports whether the tag at line[start:end] is part of a larger word,
+// such as a URL (https://example.com/TODO), a path (docs/TODO) or an identifier
+// (FOO-TODO-BAR), rather than the start of a comment. Words are separated by spaces.
+func embedded(line []byte, start, end int) bool {
+	wordStart := bytes.LastIndexFunc(line[:start], unicode.IsSpace) + 1
+	wordEnd := len(line)
+	if i := bytes.IndexFunc(line[end:], unicode.IsSpace); i >= 0 {
+		wordEnd = end + i
+	}
+	if bytes.Contains(line[wordStart:wordEnd], []byte("://")) {
+		return true
+	}
+
+	before := line[wordStart:start]
+	marked := false
+	for _, marker := range commentMarkers {
+		if bytes.HasSuffix(before, marker) {
+			marked = true
+			break
+		}
+	}
+	if !marked && bytes.ContainsFunc(before, isWordChar) {
+		return true
+	}
+
+	after := line[end:wordEnd]
+	return len(after) > 1 && after[0] == '-' && (unicode.IsLetter(rune(after[1])) || after[1] == '_')
+}
+
+func isWordChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// column returns the 1-based column, in characters, of the byte at offset i of line.
+func column(line []byte, i int) int {
+	return utf8.RuneCount(line[:i]) + 1
+}
+
+// taskFinder finds tagged comments and, failing that, unchecked Markdown task items.
+type taskFinder struct {
+	tags tagFinder
+}
+
+func (f *taskFinder) find(line []byte) (string, string, int, bool) {
+	if tag, text, col, ok := f.tags.find(line); ok {
+		return tag, text, col, ok
+	}
+	match := taskRegex.FindSubmatchIndex(line)
+	if match == nil {
+		return "", "", 0, false
+	}
+	// the column of the checkbox
+	return TaskTag, string(line[match[2]:match[3]]), column(line, bytes.Index(line, []byte("[ ]"))), true
+}
+
+// tagRegexes holds the compiled regular expressions used to find tags.
+// Files whose extension has custom comment prefixes or extension tags use a
+// dedicated regex, as do prose files according to the documentation rules.
+// Custom comment prefixes take precedence over documentation rules.
+// Misspelled tags are only found in files with the default comment syntax.
+type tagRegexes struct {
+	defaultRegex *regexp.Regexp
+	byExt        map[string]*regexp.Regexp
+	tasks        bool
+	malformed    *malformedFinder
+	noMalformed  map[string]bool
+}
+
+func newTagRegexes(
+	tags []string,
+	extensionTags map[string][]string,
+	commentPrefixes map[string][]string,
+	documentationRules map[string]string,
+	tasks bool,
+) (*tagRegexes, error) {
+	r, err := TagRegex(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make(map[string]string, len(DefaultDocumentationRules))
+	for ext, rule := range DefaultDocumentationRules {
+		rules[ext] = rule
+	}
+	for ext, rule := range documentationRules {
+		if rule != ProseRule && rule != CommentRule {
+			return nil, fmt.Errorf("unknown documentation rule for %s files: %s", ext, rule)
+		}
+		rules[ext] = rule
+	}
+
+	exts := make(map[string]bool, len(rules)+len(commentPrefixes)+len(extensionTags))
+	for ext := range rules {
+		exts[ext] = true
+	}
+	for ext := range commentPrefixes {
+		exts[ext] = true
+	}
+	for ext := range extensionTags {
+		exts[ext] = true
+	}
+
+	byExt := make(map[string]*regexp.Regexp, len(exts))
+	noMalformed := make(map[string]bool, len(exts))
+	var proseRegex *regexp.Regexp
+	for ext := range exts {
+		noMalformed[ext] = len(commentPrefixes[ext]) > 0 || rules[ext] == ProseRule
+		// extension tags are matched literally, so they may contain spaces or punctuation
+		extTags := tags
+		for _, tag := range extensionTags[ext] {
+			if tag == "" {
+				continue
+			}
+			extTags = append(extTags[:len(extTags):len(extTags)], regexp.QuoteMeta(tag))
+		}
+		hasExtTags := len(extTags) > len(tags)
+
+		var expr string
+		switch {
+		case len(commentPrefixes[ext]) > 0:
+			expr = getPrefixedTagRegex(extTags, commentPrefixes[ext])
+		case rules[ext] == ProseRule && !hasExtTags:
+			// files without extension tags share the same prose regex
+			if proseRegex == nil {
+				proseRegex, err = regexp.Compile(getProseTagRegex(tags))
+				if err != nil {
+					return nil, fmt.Errorf("failed to compile prose regex: %s", err)
+				}
+			}
+			byExt[ext] = proseRegex
+			continue
+		case rules[ext] == ProseRule:
+			expr = getProseTagRegex(extTags)
+		case hasExtTags:
+			expr = getTagRegex(extTags)
+		default:
+			continue
+		}
+		r, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile regex for %s files: %s", ext, err)
+		}
+		byExt[ext] = r
+	}
+	return &tagRegexes{defaultRegex: r, byExt: byExt, tasks: tasks, noMalformed: noMalformed}, nil
+}
+
+// findMalformed also finds misspellings of the tags, see misspellings.
+func (t *tagRegexes) findMalformed(tags []string) error {
+	r, canonical, err := newMalformedRegex(tags)
+	if err != nil || r == nil {
+		return err
+	}
+	t.malformed = &malformedFinder{regexFinder: regexFinder{regex: r}, canonical: canonical}
+	return nil
+}
+
+// malformedFor returns the finder of misspelled tags in the file, or nil if they
+// aren't searched.
+func (t *tagRegexes) malformedFor(path string) *malformedFinder {
+	if t.malformed == nil || t.noMalformed[filepath.Ext(path)] {
+		return nil
+	}
+	return t.malformed
+}
+
+// regexFor returns the regex that should be used to find tags in the file.
+func (t *tagRegexes) regexFor(path string) *regexp.Regexp {
+	if r, ok := t.byExt[filepath.Ext(path)]; ok {
+		return r
+	}
+	return t.defaultRegex
+}
+
+// forPath returns the tagFinder that should be used to search the file.
+func (t *tagRegexes) forPath(path string) tagFinder {
+	var finder tagFinder = &regexFinder{regex: t.regexFor(path)}
+	if t.tasks && markdownExts[strings.ToLower(filepath.Ext(path))] {
+		finder = &taskFinder{tags: finder}
+	}
+	return finder
+}
+
+// TagRegex returns the default regex used to find the provided tags in code comments.
+// The first group captures the tag and the second one the comment text.
+func TagRegex(tags []string) (*regexp.Regexp, error) {
+	r, err := regexp.Compile(getTagRegex(tags))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile regex: %s", err)
+	}
+	return r, nil
+}
+
+func getTagRegex(tags []string) string {
+	// tags must not start in the middle of a word, so XTODO isn't a TODO and foo@deprecated isn't a @deprecated
+	bounded := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag == "" {
+			continue
+		}
+		// \b and \B only consider ASCII word characters
+		if c := tag[0]; c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' {
+			bounded = append(bounded, `\b`+tag)
+		} else {
+			bounded = append(bounded, `\B`+tag)
+		}
+	}
+	return commentTagRegex(strings.Join(bounded, "|"))
+}
+
+// commentTagRegex returns the regex that matches a tag in a code comment, where
+// group is the expression of the tags.
+func commentTagRegex(group string) string {
+	return fmt.Sprintf(
+		`(?m)(?:^|\s*(?:(?:#+|//+|<!--|--|/*|"""|''')+\s*)+)\s*(%s)(?:\([^)]*\))?(?:[\s:;-]|$)(.*?)(?:$|-->|#}}|\*/|--}}|}}|#+|#}|"""|''')*$`,
+		group,
+	)
+}
+
+// getProseTagRegex returns a regex that matches tags anywhere in plain text,
+// as long as they're separate words. Markdown and HTML comment closers are removed.
+func getProseTagRegex(tags []string) string {
+	return fmt.Sprintf(
+		`(?m)(?:^|[\s(\[*_>])(%s)(?:[\s:;-]+|$)(.*?)\s*(?:-->)?\s*$`,
+		strings.Join(tags, "|"),
+	)
+}
+
+// getPrefixedTagRegex returns a regex that only matches tags following one of
+// the provided comment prefixes, which are matched literally.
+func getPrefixedTagRegex(tags []string, prefixes []string) string {
+	quoted := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		quoted = append(quoted, regexp.QuoteMeta(prefix))
+	}
+	return fmt.Sprintf(
+		`(?m)(?:^|\s)(?:%s)+\s*(%s)(?:[\s:;-]+|$)(.*?)\s*$`,
+		strings.Join(quoted, "|"),
+		strings.Join(tags, "|"),
+	)
+}
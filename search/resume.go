@@ -0,0 +1,266 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mathpn/listme/blame"
+)
+
+// checkpointInterval is how often the state of a resumable search is saved.
+const checkpointInterval = 10 * time.Second
+
+// checkpoint records the files scanned by a search, with their results, in a state
+// file, so an interrupted search can continue where it stopped. The state is saved
+// periodically and when the search is interrupted, and removed once it completes.
+// A nil *checkpoint doesn't record anything.
+//
+// The state file has a JSON line with the checkpointHeader, followed by a JSON line per
+// scanned file, which are appended as files are scanned instead of rewriting the whole
+// state. A line cut short by an interruption while saving is ignored.
+type checkpoint struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	files   map[string]checkpointFile // recorded by previous searches
+	pending bytes.Buffer              // lines not written yet
+	saved   time.Time
+}
+
+// checkpointHeader identifies the search that saved the state. Fingerprint is a hash
+// of the options that change which files are scanned and their results, see fingerprint.
+type checkpointHeader struct {
+	Root        string `json:"root"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// checkpointFile is the result of scanning a file, replayed when the search is resumed
+// if the size and modification time of the file didn't change since.
+type checkpointFile struct {
+	Path       string           `json:"path"`
+	Size       int64            `json:"size"`
+	ModTime    time.Time        `json:"mtime"`
+	Lines      []checkpointLine `json:"lines,omitempty"`
+	NLines     int              `json:"n_lines"`
+	SkipReason string           `json:"skip_reason,omitempty"`
+}
+
+type checkpointLine struct {
+	N         int              `json:"n"`
+	Col       int              `json:"col"`
+	Tag       string           `json:"tag"`
+	Text      string           `json:"text"`
+	Malformed string           `json:"malformed,omitempty"`
+	Symbol    string           `json:"symbol,omitempty"`
+	Assignee  string           `json:"assignee,omitempty"`
+	Blame     *blame.LineBlame `json:"blame,omitempty"`
+}
+
+// fileStamp identifies the version of a file by its size and modification time.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// fingerprint returns a hash of the options that change which files are scanned and
+// their results, so a search state is only resumed by the same search.
+func fingerprint(opts Options) string {
+	rules := make([]string, 0, len(opts.AuthorRules))
+	for _, rule := range opts.AuthorRules {
+		rules = append(rules, fmt.Sprintf("%s\x00%s\x00%t", rule.Match, rule.Name, rule.Exclude))
+	}
+	data, _ := json.Marshal(map[string]any{
+		"tags":                opts.Tags,
+		"extension_tags":      opts.ExtensionTags,
+		"comment_prefixes":    opts.CommentPrefixes,
+		"documentation_rules": opts.DocumentationRules,
+		"tasks":               opts.Tasks,
+		"fuzzy_tags":          opts.FuzzyTags,
+		"symbols":             opts.ShowSymbol,
+		"glob":                opts.Glob,
+		"types":               opts.Types,
+		"owner":               opts.Owner,
+		"author":              opts.Author,
+		"author_rules":        rules,
+		"commit_age_filter":   opts.CommitAgeFilter,
+		"max_file_size":       opts.MaxFileSize,
+		"max_file_sizes":      opts.MaxFileSizes,
+		"max_line_length":     opts.MaxLineLength,
+		"no_default_excludes": opts.NoDefaultExcludes,
+		"exclude_dirs":        opts.ExcludeDirs,
+		"no_git":              opts.NoGit || opts.NoGitEverything,
+		"no_author":           opts.NoAuthor,
+		"tracked_only":        opts.TrackedOnly,
+		"age_mode":            opts.AgeMode,
+		"blame_options":       opts.BlameOptions,
+		"local_author":        opts.LocalAuthor,
+		"patch":               opts.Patch,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadCheckpoint returns the checkpoint stored at path, creating the state file if it
+// doesn't exist. It fails if the state was saved by a search of another path. A state
+// saved by a search with other options is discarded, and the search starts over.
+func loadCheckpoint(path, root, fingerprint string) (*checkpoint, error) {
+	header := checkpointHeader{Root: root, Fingerprint: fingerprint}
+	c := &checkpoint{path: path, files: make(map[string]checkpointFile), saved: time.Now()}
+
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return c, c.create(header)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read the search state: %s", err)
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	// lines hold the comments of a file, which are limited by the maximum line length
+	s.Buffer(nil, 64<<20)
+	var saved checkpointHeader
+	if !s.Scan() || json.Unmarshal(s.Bytes(), &saved) != nil || saved.Root == "" {
+		return nil, fmt.Errorf("invalid search state %s", path)
+	}
+	if saved.Root != root {
+		return nil, fmt.Errorf("search state %s belongs to a search of %s", path, saved.Root)
+	}
+	if saved.Fingerprint != fingerprint {
+		slog.Warn("the search state was saved by a search with other options, starting over", "state", path)
+		return c, c.create(header)
+	}
+	for s.Scan() {
+		var file checkpointFile
+		if err := json.Unmarshal(s.Bytes(), &file); err != nil {
+			slog.Debug("ignoring invalid line of the search state", "state", path, "error", err)
+			continue
+		}
+		c.files[file.Path] = file
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the search state: %s", err)
+	}
+
+	if c.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to open the search state: %s", err)
+	}
+	slog.Info("resuming search", "state", path, "files", len(c.files))
+	return c, nil
+}
+
+// create starts a new state file with the header.
+func (c *checkpoint) create(header checkpointHeader) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	if c.file, err = os.Create(c.path); err != nil {
+		return fmt.Errorf("failed to create the search state: %s", err)
+	}
+	if _, err := c.file.Write(append(data, '\n')); err != nil {
+		c.file.Close()
+		return fmt.Errorf("failed to save the search state: %s", err)
+	}
+	return nil
+}
+
+// get returns the recorded result of the file, if it was already scanned and it didn't
+// change since. The returned stamp identifies the current version of the file, which
+// is recorded with its result by add.
+func (c *checkpoint) get(path string) (checkpointFile, fileStamp, bool) {
+	if c == nil {
+		return checkpointFile{}, fileStamp{}, false
+	}
+	var stamp fileStamp
+	if info, err := os.Stat(path); err == nil {
+		stamp = fileStamp{size: info.Size(), modTime: info.ModTime()}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	file, ok := c.files[path]
+	if ok && (file.Size != stamp.size || !file.ModTime.Equal(stamp.modTime)) {
+		slog.Debug("file changed since the search state was saved", "path", path)
+		return checkpointFile{}, stamp, false
+	}
+	return file, stamp, ok
+}
+
+// add records the result of scanning the version of a file given by stamp, saving the
+// state if the last save is older than checkpointInterval.
+func (c *checkpoint) add(path string, stamp fileStamp, lines []*matchLine, nLines int, skipReason string) {
+	if c == nil {
+		return
+	}
+	file := checkpointFile{Path: path, Size: stamp.size, ModTime: stamp.modTime, NLines: nLines, SkipReason: skipReason}
+	for _, l := range lines {
+		file.Lines = append(file.Lines, checkpointLine{N: l.n, Col: l.col, Tag: l.tag, Text: l.text, Malformed: l.malformed, Symbol: l.symbol, Assignee: l.assignee, Blame: l.blame})
+	}
+	data, err := json.Marshal(file)
+	if err != nil {
+		slog.Warn("failed to save the search state", "path", path, "error", err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending.Write(data)
+	c.pending.WriteByte('\n')
+	if time.Since(c.saved) >= checkpointInterval {
+		c.save()
+	}
+}
+
+// finish saves the state of an interrupted search, or removes it once the search completes.
+func (c *checkpoint) finish(interrupted bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if interrupted {
+		c.save()
+	}
+	if err := c.file.Close(); err != nil {
+		slog.Warn("failed to save the search state", "path", c.path, "error", err)
+	}
+	if interrupted {
+		return
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("failed to remove the search state", "path", c.path, "error", err)
+	}
+}
+
+// save appends the files scanned since the last save to the state file. It must be
+// called with c.mu held.
+func (c *checkpoint) save() {
+	c.saved = time.Now()
+	if c.pending.Len() == 0 {
+		return
+	}
+	if _, err := c.file.Write(c.pending.Bytes()); err != nil {
+		slog.Warn("failed to save the search state", "path", c.path, "error", err)
+		return
+	}
+	c.pending.Reset()
+	slog.Debug("saved search state", "path", c.path)
+}
+
+// matchLines returns the lines of a file recorded by a previous search.
+func (f checkpointFile) matchLines() []*matchLine {
+	lines := make([]*matchLine, 0, len(f.Lines))
+	for _, l := range f.Lines {
+		lines = append(lines, &matchLine{n: l.N, col: l.Col, tag: l.Tag, text: l.Text, malformed: l.Malformed, symbol: l.Symbol, assignee: l.Assignee, blame: l.Blame})
+	}
+	return lines
+}
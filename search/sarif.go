@@ -0,0 +1,198 @@
+package search
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mathpn/listme/pretty"
+)
+
+// sarifRenderer prints a SARIF 2.1.0 log at the end of the search, to upload the
+// results to GitHub Code Scanning and other static analysis dashboards. Each tag is
+// a rule, whose level follows the severity of the tag, and paths are relative to the
+// repository root. Skipped files and end-of-run totals are written to stderr.
+type sarifRenderer struct {
+	params  *searchParams
+	stdout  io.Writer
+	stderr  io.Writer
+	results []sarifResult
+	tags    map[string]bool
+}
+
+var sarifLevels = map[pretty.Severity]string{
+	pretty.SeverityInfo:    "note",
+	pretty.SeverityWarning: "warning",
+	pretty.SeverityError:   "error",
+}
+
+// Key of the fingerprint of each result in partialFingerprints, so code scanning
+// platforms track comments across runs as JSON consumers do.
+const sarifFingerprint = "listme/v1"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifInvocation reports files that couldn't be searched as notifications, so
+// incomplete results aren't mistaken for the absence of tags.
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifArtifact returns the location of a file relative to the repository root.
+func (s *sarifRenderer) sarifArtifact(path string) sarifArtifactLocation {
+	rel, err := filepath.Rel(s.params.repoRoot, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = path
+	}
+	return sarifArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: "%SRCROOT%"}
+}
+
+func (s *sarifRenderer) result(r *searchResult) {
+	artifact := s.sarifArtifact(r.path)
+	for _, m := range r.jsonMatches(s.params) {
+		if s.tags == nil {
+			s.tags = make(map[string]bool)
+		}
+		s.tags[m.Tag] = true
+		text := m.Text
+		if text == "" {
+			text = m.Tag + " comment"
+		}
+		s.results = append(s.results, sarifResult{
+			RuleID:  m.Tag,
+			Level:   sarifLevels[pretty.LookupTag(m.Tag).Severity],
+			Message: sarifMessage{Text: text},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: artifact,
+				Region:           &sarifRegion{StartLine: m.Line, StartColumn: m.Column},
+			}}},
+			PartialFingerprints: map[string]string{sarifFingerprint: m.Fingerprint},
+		})
+	}
+}
+
+func (s *sarifRenderer) finish(stats *Stats) {
+	tags := make([]string, 0, len(s.tags))
+	for tag := range s.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	rules := make([]sarifRule, 0, len(tags))
+	for _, tag := range tags {
+		rules = append(rules, sarifRule{
+			ID:                   tag,
+			ShortDescription:     sarifMessage{Text: tag + " comment"},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevels[pretty.LookupTag(tag).Severity]},
+		})
+	}
+
+	invocation := sarifInvocation{ExecutionSuccessful: !stats.Interrupted()}
+	for _, e := range stats.Errors() {
+		invocation.ToolExecutionNotifications = append(invocation.ToolExecutionNotifications, sarifNotification{
+			Level:     "warning",
+			Message:   sarifMessage{Text: e.Kind + ": " + e.Message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: s.sarifArtifact(e.Path)}}},
+		})
+	}
+
+	results := s.results
+	if results == nil {
+		results = []sarifResult{}
+	}
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "listme",
+				InformationURI: "https://github.com/mathpn/listme",
+				Rules:          rules,
+			}},
+			Invocations: []sarifInvocation{invocation},
+			Results:     results,
+		}},
+	}
+	enc := json.NewEncoder(s.stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(log); err != nil {
+		slog.Error("failed to write SARIF log", "error", err)
+	}
+
+	var b strings.Builder
+	renderSkipped(&b, stats.Skipped(), s.params.displayPath, s.params.skipReport)
+	if s.params.stats {
+		stats.render(&b, 0, pretty.PlainStyle)
+	}
+	io.WriteString(s.stderr, b.String())
+}
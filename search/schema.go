@@ -0,0 +1,141 @@
+package search
+
+import "time"
+
+// SchemaVersion is the version of the JSON and JSONL output schema.
+//
+// Adding optional fields doesn't change the version. Removing or renaming a field,
+// or changing its meaning, requires bumping it so downstream consumers can
+// detect the incompatible change.
+const SchemaVersion = 1
+
+// Record types used in the "type" field of JSONL output.
+const (
+	MatchRecord   = "match"
+	SkippedRecord = "skipped"
+	StatsRecord   = "stats"
+	ErrorRecord   = "error"
+)
+
+// JSONMatch is a tagged comment in machine-readable output.
+//   - Path: file path, relative to the searched path unless full paths are requested
+//   - Line: 1-based line number
+//   - Column: 1-based column where the tag starts, in characters
+//   - Tag: matched tag, e.g. TODO
+//   - Text: comment text following the tag
+//   - Fingerprint: stable identifier of the comment across runs, even if its line changes, see Fingerprint
+//   - Author: git author of the line, if available
+//   - Commit: short hash of the commit of the line, if available
+//   - Date: date of the commit of the line as an RFC 3339 string, if available
+//   - Timestamp: date of the commit of the line in seconds since the Unix epoch, if available
+//   - Language: programming language of the file, from its extension or shebang line, if known
+//   - Owners: owners of the file in the CODEOWNERS file of the repository, if any
+//   - Malformed: the tag as written if it's a misspelling of Tag, e.g. TOOD, found with fuzzy tags
+//   - Symbol: function or type enclosing the comment, e.g. func Search(...), if requested and found
+//   - Lint: lint issues of the comment, e.g. short or no_issue, if linted, see LintIssues
+type JSONMatch struct {
+	Path        string     `json:"path"`
+	Line        int        `json:"line"`
+	Column      int        `json:"column,omitempty"`
+	Tag         string     `json:"tag"`
+	Text        string     `json:"text"`
+	Fingerprint string     `json:"fingerprint"`
+	Author      string     `json:"author,omitempty"`
+	Commit      string     `json:"commit,omitempty"`
+	Date        *time.Time `json:"date,omitempty"`
+	Timestamp   int64      `json:"timestamp,omitempty"`
+	Language    string     `json:"language,omitempty"`
+	Owners      []string   `json:"owners,omitempty"`
+	Malformed   string     `json:"malformed,omitempty"`
+	Symbol      string     `json:"symbol,omitempty"`
+	Lint        []string   `json:"lint,omitempty"`
+}
+
+// JSONSkipped is a file that couldn't be scanned.
+// Reason is one of permission, unreadable, size, binary or encoding. Line is where
+// the text detection gave up, for binary files and unsupported encodings.
+type JSONSkipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	Line   int    `json:"line,omitempty"`
+}
+
+// JSONError is an error found while searching a file, so automation can tell
+// missing results apart from files without tags.
+//   - Kind: blame if author information is missing, or read if results may be incomplete.
+//     Files that couldn't be scanned at all are reported as JSONSkipped instead
+//   - Message: description of the error
+type JSONError struct {
+	Path    string `json:"path"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+// JSONStats holds the end-of-run totals in machine-readable output.
+//   - Tags: number of matches per tag
+//   - Extensions: number of matches per tag for each file extension
+//   - ElapsedMs: duration of the search in milliseconds
+//   - ExtensionDensity: matches per 1000 scanned lines for each file extension with matches
+//   - DirectoryDensity: matches per 1000 scanned lines for each directory with matches
+//   - Lint: number of comments with each lint issue, if any was flagged
+//   - LintFlagged: number of comments with at least one lint issue
+type JSONStats struct {
+	FilesScanned     int                       `json:"files_scanned"`
+	FilesSkipped     int                       `json:"files_skipped"`
+	ElapsedMs        int64                     `json:"elapsed_ms"`
+	Tags             map[string]int            `json:"tags"`
+	Extensions       map[string]map[string]int `json:"extensions"`
+	ExtensionDensity map[string]JSONDensity    `json:"extension_density,omitempty"`
+	DirectoryDensity map[string]JSONDensity    `json:"directory_density,omitempty"`
+	Owners           map[string]JSONOwner      `json:"owners,omitempty"`
+	CoOccurrence     map[string]int            `json:"co_occurrence,omitempty"`
+	MixedFiles       []JSONMixedFile           `json:"mixed_files,omitempty"`
+	Lint             map[string]int            `json:"lint,omitempty"`
+	LintFlagged      int                       `json:"lint_flagged,omitempty"`
+}
+
+// JSONMixedFile is a file with more than one tag. Mix is the number of its matches
+// whose tag isn't the most common one.
+type JSONMixedFile struct {
+	Path string         `json:"path"`
+	Tags map[string]int `json:"tags"`
+	Mix  int            `json:"mix"`
+}
+
+// JSONOwner is the number of matches in the files of a CODEOWNERS owner, and their
+// average age in days, zero if the commit dates are unknown.
+type JSONOwner struct {
+	Matches        int            `json:"matches"`
+	Tags           map[string]int `json:"tags"`
+	AverageAgeDays float64        `json:"average_age_days"`
+}
+
+// JSONDensity is the number of matches per 1000 scanned lines of a group of files.
+type JSONDensity struct {
+	Matches int     `json:"matches"`
+	Lines   int     `json:"lines"`
+	PerKLOC float64 `json:"per_kloc"`
+}
+
+// JSONOutput is the document printed by the JSON format.
+// Stats is only present if end-of-run totals were requested.
+// Interrupted is true if the search was stopped early, by SIGINT or a timeout, so matches are incomplete.
+type JSONOutput struct {
+	SchemaVersion int           `json:"schema_version"`
+	Matches       []JSONMatch   `json:"matches"`
+	Skipped       []JSONSkipped `json:"skipped,omitempty"`
+	Errors        []JSONError   `json:"errors,omitempty"`
+	Stats         *JSONStats    `json:"stats,omitempty"`
+	Interrupted   bool          `json:"interrupted,omitempty"`
+}
+
+// JSONLRecord is a single line printed by the JSONL format.
+// Type is one of MatchRecord, SkippedRecord, ErrorRecord or StatsRecord, and only the corresponding field is set.
+type JSONLRecord struct {
+	SchemaVersion int          `json:"schema_version"`
+	Type          string       `json:"type"`
+	Match         *JSONMatch   `json:"match,omitempty"`
+	Skipped       *JSONSkipped `json:"skipped,omitempty"`
+	Error         *JSONError   `json:"error,omitempty"`
+	Stats         *JSONStats   `json:"stats,omitempty"`
+}
@@ -1,120 +1,519 @@
 package search
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
 	tsize "github.com/kopoli/go-terminal-size"
-	logging "github.com/op/go-logging"
+	"github.com/mattn/go-runewidth"
 
 	"github.com/mathpn/listme/blame"
+	"github.com/mathpn/listme/cache"
+	"github.com/mathpn/listme/codeowners"
+	"github.com/mathpn/listme/i18n"
 	"github.com/mathpn/listme/matcher"
 	"github.com/mathpn/listme/pretty"
 )
 
-var log = logging.MustGetLogger("listme")
 var ansiRegex = regexp.MustCompile("\x1b(\\[[0-9;]*[A-Za-z])")
 var zeroTime = time.Unix(0, 0)
 
 // limiting the width improves readability of git author info
 const maxWidth = 120
 const defaultWidth = 75
-const noComment = "\x1b[3m[no comment]\x1b[23m" // italic
+
+// DefaultTags lists the tags searched by default.
+var DefaultTags = []string{"BUG", "FIXME", "XXX", "TODO", "HACK", "OPTIMIZE", "NOTE"}
+
+// DefaultExcludes lists the names of dependency and build directories, which are
+// skipped even if not ignored by git, since their tags belong to third-party code.
+var DefaultExcludes = []string{"vendor", "node_modules", ".venv", "target", "dist"}
+
+// defaultMaxLineLength is the length in bytes above which lines are skipped, unless configured.
+const defaultMaxLineLength = 64 << 10
 
 type searchParams struct {
-	oldCommitTime time.Time
-	commitAgeTime time.Time
-	matcher       matcher.Matcher
-	regex         *regexp.Regexp
-	rootPath      string
-	author        string
-	style         pretty.Style
-	workers       int
-	maxFs         int64
-	fullPath      bool
-	summary       bool
-	showAuthor    bool
+	ageTiers        *pretty.AgeTiers
+	dateFormat      pretty.DateFormat
+	now             time.Time
+	commitAgeTime   time.Time
+	matcher         matcher.Matcher
+	regexes         *tagRegexes
+	rootPath        string
+	author          string
+	style           pretty.Style
+	format          Format
+	workers         int
+	maxFs           int64
+	maxFsByExt      map[string]int64
+	maxLineLength   int
+	defaultExcludes bool
+	excludeDirs     []string
+	fullPath        bool
+	summary         bool
+	severitySummary bool
+	maxPerFile      int
+	wrapIndent      string
+	wrapMarker      string
+	showAuthor      bool
+	showHash        bool
+	useGit          bool
+	localAuthor     string
+	blameCache      *blame.Cache
+	blameSettings   blame.Settings
+	cloneState      blame.CloneState
+	stats           bool
+	skipReport      bool
+	quiet           bool
+	ordered         bool
+	collect         func(JSONMatch)
+	diagnostics     io.Writer
+	output          io.Writer
+	quickfix        io.Writer
+	quickfixFormat  QuickfixFormat
+	timings         *timings
+	ctx             context.Context
+	timeout         time.Duration
+	blameTimeout    time.Duration
+	openFiles       ioLimiter
+	useIndex        bool
+	prefetchBlame   bool
+	tracked         map[string]bool // nil unless only tracked files are scanned
+	repoRoot        string
+	owners          *codeowners.Owners
+	owner           string
+	showOwner       bool
+	showLanguage    bool
+	showSymbol      bool
+	exitSummary     bool
+	authorRules     []AuthorRule
+	types           map[string]bool // nil unless filtering by file type, by language
+	patch           Patch           // nil unless only the lines added by a diff are reported
+	lint            *LintRules      // nil unless comments are linted
+	showOldest      bool
+	byOwner         bool
+	coOccurrence    bool
+	scanCache       *cache.Cache
+	checkpoint      *checkpoint
+}
+
+// Alignment of wrapped continuation lines in the human-readable styles.
+//   - TagIndent: under the tag
+//   - TextIndent: under the comment text, after the tag
+const (
+	TagIndent  = "tag"
+	TextIndent = "text"
+)
+
+// WrapIndents lists the accepted alignments of wrapped continuation lines.
+var WrapIndents = []string{TagIndent, TextIndent}
+
+// Options holds the user-provided settings of a search.
+//   - OldCommitLimit: age in days after which commits are marked as old, unless AgeTiers are provided
+//   - AgeTiers: badges marking lines by commit age, replacing the single OLD badge
+//   - DateFormat: format of the commit date column in the human-readable styles, hidden if NoDate
+//   - ShowOldest: show the age of the oldest line of each file next to its name in the human-readable styles
+//   - ShowHash: show the short commit hash column in the human-readable styles, it's always part of plain and JSON output
+//   - CommitAgeFilter: only lines committed within this number of days are kept (-1 disables it)
+//   - MaxFileSize: maximum file size to scan (in MB)
+//   - MaxLineLength: lines longer than this (in KB) are skipped, 64 KB if not provided
+//   - MaxOpenFiles: maximum number of files read at the same time, including by git blame (0 is one per worker)
+//   - MaxFileSizes: maximum file size to scan per file extension (in MB), overriding MaxFileSize
+//   - ExtensionTags: additional tags per file extension, matched literally, e.g. "type: ignore" for .py files
+//   - CommentPrefixes: comment markers per file extension, replacing the default ones
+//   - DocumentationRules: ProseRule or CommentRule per file extension, merged with DefaultDocumentationRules
+//   - Tasks: also report unchecked Markdown task items with the TASK tag
+//   - FuzzyTags: also report misspelled tags in code comments, e.g. TOOD or FIX ME, as the tag they stand for
+//   - BySeverity: also count lines by tag severity in the summary boxes and Stats
+//   - MaxPerFile: maximum number of lines rendered per file in the human-readable styles (0 shows all)
+//   - WrapIndent: alignment of wrapped continuation lines in the human-readable styles, TagIndent if not provided
+//   - WrapMarker: prefix of wrapped continuation lines, e.g. "↳ "
+//   - NoDefaultExcludes: also search the DefaultExcludes directories
+//   - ExcludeDirs: names of directories that are never searched, regardless of NoDefaultExcludes
+//   - NoGit: do not use git blame, as if the path was outside of a git repository
+//   - NoGitEverything: do not look for a git repository at all, so .gitignore files are not respected either
+//   - PrefetchBlame: blame whole files as soon as their first tagged line is found, while the rest is scanned
+//   - FetchBlame: let git blame fetch the objects missing from partial clones
+//   - AgeMode: whether lines are dated by the commit that last changed them or the one that introduced them
+//   - LocalAuthor: attribute the lines that can't be blamed, outside of repositories or not committed
+//     yet, to the local user, see blame.LocalUser
+//   - BlameOptions: git blame options that ignore whitespace changes or follow moved lines, see blame.ParseOptions
+//   - NoCache: blame every file again instead of reusing the blame cache of the repository
+//   - TrackedOnly: only scan files tracked by git, skipping untracked files that aren't ignored either
+//   - UseIndex: list files from the git index and reuse the scan of files whose content didn't change
+//   - Stats: print end-of-run totals
+//   - SkipReport: list every file skipped as binary or with an unsupported encoding, instead of the first ones
+//   - Quiet: do not print matching lines, only collect Stats
+//   - Collect: called with every match, from a single goroutine. Matches are blamed for
+//     their authors, unless NoAuthor is set, even if they aren't printed
+//   - Output: where results are written instead of stdout, if provided
+//   - Diagnostics: where skipped files, notices and totals are written instead of stderr, if provided
+//   - Quickfix: where matches are also written as a jump list for editors, in QuickfixFormat, if provided
+//   - Ordered: print results in walk order, as soon as all earlier files are scanned
+//   - Timings: print per-phase durations and the slowest files to stderr
+//   - Context: stops the search when done, e.g. on SIGINT, keeping the results found so far
+//   - Timeout: stops the search after this duration, keeping the results found so far (0 disables it)
+//   - BlameTimeout: stops git blame of a file after this duration, leaving its comments without author (0 disables it)
+//   - Owner: only search files owned by this owner in the CODEOWNERS file, e.g. @org/team
+//   - ShowOwner: show the owners of each file next to its name in the human-readable styles
+//   - Types: only search files of these types, by language, see ParseFileType and Language
+//   - AuthorRules: rename or exclude commit authors in the display, filters and stats, see AuthorRule
+//   - ExitSummary: print a one-line summary to stderr at the end of plain text output,
+//     unless Stats are printed, for humans piping the output
+//   - ShowSymbol: find the function or type enclosing each line, shown after the comment text
+//     in the human-readable styles and in machine-readable output, see symbolPatterns
+//   - ShowLanguage: show the language of each file next to its name in the human-readable styles, see Language
+//   - ByOwner: break Stats down by CODEOWNERS owner, see Stats.Owners
+//   - CoOccurrence: track the files with more than one tag in Stats, see Stats.CoOccurrence
+//   - Patch: only search the files changed by a diff, reporting the tagged lines it added, see Patch
+//   - Lint: flag low-quality comments, shown with their issues and counted in Stats, see LintRules
+//   - Resume: state file of a resumable search, which continues from the files already scanned if it exists
+type Options struct {
+	Path               string
+	Tags               []string
+	ExtensionTags      map[string][]string
+	CommentPrefixes    map[string][]string
+	DocumentationRules map[string]string
+	Tasks              bool
+	FuzzyTags          bool
+	Workers            int
+	Style              pretty.Style
+	Format             Format
+	OldCommitLimit     int
+	AgeTiers           []pretty.AgeTier
+	DateFormat         pretty.DateFormat
+	ShowHash           bool
+	CommitAgeFilter    int
+	MaxFileSize        int64
+	MaxFileSizes       map[string]int64
+	MaxOpenFiles       int
+	MaxLineLength      int
+	FullPath           bool
+	NoSummary          bool
+	BySeverity         bool
+	MaxPerFile         int
+	WrapIndent         string
+	WrapMarker         string
+	NoAuthor           bool
+	NoDefaultExcludes  bool
+	ExcludeDirs        []string
+	NoGit              bool
+	NoGitEverything    bool
+	NoCache            bool
+	UseIndex           bool
+	TrackedOnly        bool
+	FetchBlame         bool
+	PrefetchBlame      bool
+	AgeMode            blame.AgeMode
+	BlameOptions       []string
+	LocalAuthor        bool
+	Stats              bool
+	SkipReport         bool
+	Quiet              bool
+	Collect            func(JSONMatch)
+	Output             io.Writer
+	Diagnostics        io.Writer
+	Quickfix           io.Writer
+	QuickfixFormat     QuickfixFormat
+	Ordered            bool
+	Timings            bool
+	Context            context.Context
+	Timeout            time.Duration
+	BlameTimeout       time.Duration
+	Resume             string
+	Patch              Patch
+	Lint               *LintRules
+	Owner              string
+	ShowOwner          bool
+	ShowLanguage       bool
+	ShowSymbol         bool
+	ExitSummary        bool
+	AuthorRules        []AuthorRule
+	Types              []string
+	ShowOldest         bool
+	ByOwner            bool
+	CoOccurrence       bool
+	Glob               string
+	Author             string
 }
 
 // NewSearchParams creates a searchParams struct with all the information required
 // to inspect a file or directory.
-func NewSearchParams(
-	path string,
-	tags []string,
-	workers int,
-	style pretty.Style,
-	oldCommitLimit, commitAgeFilter int,
-	maxFileSize int64,
-	fullPath, noSummary, noAuthor bool,
-	glob, author string,
-) (*searchParams, error) {
-	absPath, err := filepath.Abs(filepath.ToSlash(path))
+func NewSearchParams(opts Options) (*searchParams, error) {
+	absPath, err := filepath.Abs(filepath.ToSlash(opts.Path))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path for %s: %s", path, err)
+		return nil, fmt.Errorf("failed to get absolute path for %s: %s", opts.Path, err)
+	}
+
+	var t *timings
+	if opts.Timings {
+		t = newTimings()
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	var m matcher.Matcher
+	if opts.NoGitEverything {
+		m = matcher.NewGlobMatcher(absPath, opts.Glob)
+	} else {
+		m = matcher.NewMatcher(absPath, opts.Glob)
+	}
+	t.since(phaseGitignore, start)
+
+	useGit := !opts.NoGit && m.InGitRepo()
+	if !useGit {
+		if !opts.NoGit && !opts.NoGitEverything {
+			slog.Info("no git repository found, git author information is disabled", "path", absPath)
+		}
+		if (opts.Author != "" && !opts.LocalAuthor) || opts.CommitAgeFilter != -1 {
+			slog.Warn("author and commit age filters require git, no lines will match")
+		}
+	}
+	var localAuthor string
+	if opts.LocalAuthor {
+		if localAuthor = blame.LocalUser(absPath, !opts.NoGitEverything); localAuthor == "" {
+			slog.Warn("the local user name is unknown, lines that can't be blamed have no author")
+		}
+	}
+	var cloneState blame.CloneState
+	blameSettings := blame.Settings{AgeMode: opts.AgeMode, LazyFetch: opts.FetchBlame}
+	if useGit {
+		cloneState = blame.DetectCloneState(absPath)
+		if blameSettings.Options, err = blame.ParseOptions(opts.BlameOptions); err != nil {
+			return nil, err
+		}
+		if cloneState.Partial && !opts.FetchBlame {
+			slog.Warn("partial clone: lines whose history wasn't fetched have an unknown author, use --fetch-blame to fetch it")
+		}
+		if cloneState.Shallow {
+			slog.Info("shallow clone: lines older than the clone depth are attributed to its oldest commit")
+		}
 	}
 
-	matcher := matcher.NewMatcher(absPath, glob)
-	regex := getTagRegex(tags)
+	var blameCache *blame.Cache
+	if useGit && !opts.NoCache {
+		blameCache, err = blame.NewCache(absPath)
+		if err != nil {
+			slog.Info("git blame cache is disabled", "error", err)
+		}
+	}
 
-	r, err := regexp.Compile(regex)
+	var scanCache *cache.Cache
+	if opts.UseIndex && useGit && !opts.NoCache {
+		scanCache, err = openScanCache(absPath)
+		if err != nil {
+			slog.Info("scan cache is disabled", "error", err)
+		}
+	}
+
+	// files are identified by their path relative to the repository root, or
+	// to the searched directory outside of repositories
+	root := absPath
+	if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+		root = filepath.Dir(absPath)
+	}
+	var owners *codeowners.Owners
+	if useGit {
+		var gitRoot string
+		if gitRoot, err = matcher.RepoRoot(absPath); err == nil {
+			root = gitRoot
+			owners, err = codeowners.Load(root)
+		}
+		if err != nil {
+			slog.Debug("no code owners", "error", err)
+		}
+	}
+	if opts.Owner != "" && owners == nil {
+		return nil, fmt.Errorf("filtering by owner requires a CODEOWNERS file in a git repository")
+	}
+	if opts.ByOwner && owners == nil {
+		return nil, fmt.Errorf("grouping by owner requires a CODEOWNERS file in a git repository")
+	}
+
+	var patch Patch
+	if opts.Patch != nil {
+		patch = opts.Patch.resolve(root)
+	}
+
+	var tracked map[string]bool
+	if opts.TrackedOnly {
+		if opts.NoGitEverything {
+			return nil, fmt.Errorf("listing only tracked files requires git")
+		}
+		if tracked, err = listTrackedFiles(absPath); err != nil {
+			return nil, fmt.Errorf("listing only tracked files requires a git repository: %s", err)
+		}
+	}
+
+	var types map[string]bool
+	if len(opts.Types) > 0 {
+		types = make(map[string]bool, len(opts.Types))
+		for _, name := range opts.Types {
+			lang, err := ParseFileType(name)
+			if err != nil {
+				return nil, err
+			}
+			types[lang] = true
+		}
+	}
+
+	regexes, err := newTagRegexes(opts.Tags, opts.ExtensionTags, opts.CommentPrefixes, opts.DocumentationRules, opts.Tasks)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compile regex: %s", err)
+		return nil, err
+	}
+	if opts.FuzzyTags {
+		if err := regexes.findMalformed(opts.Tags); err != nil {
+			return nil, err
+		}
 	}
 
 	currentTime := time.Now()
-	maxAge := time.Duration(oldCommitLimit) * 24 * time.Hour
-	oldCommitTime := currentTime.Add(-maxAge)
+	tiers := opts.AgeTiers
+	if len(tiers) == 0 {
+		tiers = []pretty.AgeTier{{Label: pretty.DefaultAgeTierLabel, Days: opts.OldCommitLimit}}
+	}
 
 	commitAgeTime := zeroTime
-	if commitAgeFilter != -1 {
-		maxAge = time.Duration(commitAgeFilter) * 24 * time.Hour
+	if opts.CommitAgeFilter != -1 {
+		maxAge := time.Duration(opts.CommitAgeFilter) * 24 * time.Hour
 		commitAgeTime = currentTime.Add(-maxAge)
 	}
 
+	maxLineLength := opts.MaxLineLength << 10
+	if maxLineLength <= 0 {
+		maxLineLength = defaultMaxLineLength
+	}
+
+	// last, since the state file is created if it doesn't exist
+	var resume *checkpoint
+	if opts.Resume != "" {
+		if resume, err = loadCheckpoint(opts.Resume, absPath, fingerprint(opts)); err != nil {
+			return nil, err
+		}
+	}
+
 	return &searchParams{
-		rootPath:      absPath,
-		regex:         r,
-		matcher:       matcher,
-		workers:       workers,
-		style:         style,
-		oldCommitTime: oldCommitTime,
-		maxFs:         maxFileSize,
-		fullPath:      fullPath,
-		summary:       !noSummary,
-		showAuthor:    !noAuthor,
-		author:        author,
-		commitAgeTime: commitAgeTime,
+		rootPath:        absPath,
+		regexes:         regexes,
+		matcher:         m,
+		workers:         opts.Workers,
+		style:           opts.Style,
+		format:          opts.Format,
+		ageTiers:        pretty.NewAgeTiers(tiers, currentTime),
+		dateFormat:      opts.DateFormat,
+		now:             currentTime,
+		maxFs:           opts.MaxFileSize,
+		maxFsByExt:      opts.MaxFileSizes,
+		maxLineLength:   maxLineLength,
+		defaultExcludes: !opts.NoDefaultExcludes,
+		excludeDirs:     opts.ExcludeDirs,
+		fullPath:        opts.FullPath,
+		summary:         !opts.NoSummary,
+		severitySummary: opts.BySeverity,
+		maxPerFile:      opts.MaxPerFile,
+		wrapIndent:      opts.WrapIndent,
+		wrapMarker:      opts.WrapMarker,
+		showAuthor:      !opts.NoAuthor && (useGit || localAuthor != ""),
+		showHash:        opts.ShowHash && useGit,
+		useGit:          useGit,
+		localAuthor:     localAuthor,
+		blameCache:      blameCache,
+		blameSettings:   blameSettings,
+		cloneState:      cloneState,
+		author:          opts.Author,
+		commitAgeTime:   commitAgeTime,
+		stats:           opts.Stats,
+		skipReport:      opts.SkipReport,
+		quiet:           opts.Quiet,
+		collect:         opts.Collect,
+		diagnostics:     opts.Diagnostics,
+		output:          opts.Output,
+		quickfix:        opts.Quickfix,
+		quickfixFormat:  opts.QuickfixFormat,
+		ordered:         opts.Ordered,
+		timings:         t,
+		ctx:             ctx,
+		timeout:         opts.Timeout,
+		blameTimeout:    opts.BlameTimeout,
+		openFiles:       newIOLimiter(opts.MaxOpenFiles),
+		useIndex:        opts.UseIndex && useGit,
+		prefetchBlame:   opts.PrefetchBlame,
+		tracked:         tracked,
+		scanCache:       scanCache,
+		checkpoint:      resume,
+		repoRoot:        root,
+		owners:          owners,
+		owner:           opts.Owner,
+		showOwner:       opts.ShowOwner,
+		showLanguage:    opts.ShowLanguage,
+		showSymbol:      opts.ShowSymbol,
+		exitSummary:     opts.ExitSummary,
+		authorRules:     opts.AuthorRules,
+		types:           types,
+		patch:           patch,
+		lint:            opts.Lint,
+		showOldest:      opts.ShowOldest,
+		byOwner:         opts.ByOwner,
+		coOccurrence:    opts.CoOccurrence,
 	}, nil
 }
 
-func getTagRegex(tags []string) string {
-	tagsRegex := fmt.Sprintf(
-		`(?m)(?:^|\s*(?:(?:#+|//+|<!--|--|/*|"""|''')+\s*)+)\s*(?:^|\b)(%s)(?:[\s:;-]|$)(.*?)(?:$|-->|#}}|\*/|--}}|}}|#+|#}|"""|''')*$`,
-		strings.Join(tags, "|"),
-	)
-	return tagsRegex
+// excludedDir reports whether directories with this name are skipped, either as
+// DefaultExcludes or as ExcludeDirs.
+func (p *searchParams) excludedDir(name string) bool {
+	return (p.defaultExcludes && slices.Contains(DefaultExcludes, name)) || slices.Contains(p.excludeDirs, name)
+}
+
+// maxFileSize returns the maximum size of the file in MB, according to its extension.
+func (p *searchParams) maxFileSize(path string) int64 {
+	if limit, ok := p.maxFsByExt[filepath.Ext(path)]; ok {
+		return limit
+	}
+	return p.maxFs
 }
 
+// searchJob is a file to be scanned. seq is its position in the walk, and blob the
+// hash of its content in the git index, if known. malformed is nil unless misspelled
+// tags are searched.
 type searchJob struct {
-	regex *regexp.Regexp
-	path  string
+	finder    tagFinder
+	malformed *malformedFinder
+	prefetch  *blameFuture // nil unless blame is prefetched
+	path      string
+	blob      string
+	seq       int
 }
 
+// matchLine is a tagged line. If the tag is misspelled, malformed is the tag as written.
+// Symbol is the enclosing function or type, if requested and found.
 type matchLine struct {
-	blame *blame.LineBlame
-	tag   string
-	text  string
-	n     int
+	blame     *blame.LineBlame
+	tag       string
+	text      string
+	malformed string
+	symbol    string
+	assignee  string
+	lint      []string
+	n         int
+	col       int
 }
 
 // Wraps a long string on words with a max lineWidth.
@@ -161,32 +560,63 @@ func removeANSIEscapeCodes(input string) string {
 	return cleaned
 }
 
-// Render the line and print it to stdout using the provided style.
-// Depending on the width of the terminal, multiple lines may be printed.
+// Render the line and write it to w using the provided style.
+// Depending on the width of the terminal, multiple lines may be written.
 func (l *matchLine) Render(
+	w io.Writer,
 	width int,
 	maxLineNumber int,
-	oldCommitTime time.Time,
-	showAuthor bool,
-	style pretty.Style,
+	params *searchParams,
 ) {
+	style := params.style
 	maxDigits := len(fmt.Sprint(maxLineNumber))
-	lnSize := maxDigits + 9
-	maxTextWidth := width - lnSize - (blame.MaxAuthorLength + 7)
+	lnSize := pretty.LineNumberWidth(maxDigits) - 1
+	maxTextWidth := width - lnSize
+	if params.showAuthor {
+		maxTextWidth -= pretty.BlameWidth(params.ageTiers)
+	}
+	if params.dateFormat != pretty.NoDate {
+		maxTextWidth -= pretty.DateWidth(params.dateFormat) + 1
+	}
+	if params.showHash {
+		maxTextWidth -= pretty.HashWidth() + 1
+	}
 
 	lenTag := len(l.tag) + 3
 	if maxTextWidth < lenTag {
-		log.Fatal("terminal is too narrow")
+		slog.Error("terminal is too narrow", "width", width)
+		os.Exit(2)
 	}
 
-	text := strings.TrimSpace(l.text)
+	text := pretty.HighlightMetadata(strings.TrimSpace(l.text), style)
 	if text == "" {
-		text = noComment
+		text = pretty.Italic("[" + i18n.T("no comment") + "]")
+	}
+	if l.malformed != "" {
+		text = pretty.Italic("["+i18n.Sprintf("malformed: %s", l.malformed)+"]") + " " + text
+	}
+	if l.symbol != "" {
+		text += " " + pretty.Italic(i18n.Sprintf("in %s", l.symbol))
+	}
+	if len(l.lint) > 0 {
+		text += " " + pretty.Underline("["+lintLabel(l.lint)+"]")
 	}
 
 	line := pretty.Bold(pretty.Emojify(l.tag)) + " " + text
-	wrapLine := wordWrap(line, maxTextWidth)
-	for i, chunk := range strings.Split(wrapLine, "\n") {
+	chunks := strings.Split(wordWrap(line, maxTextWidth), "\n")
+	contPrefix := strings.Repeat(" ", pretty.LineNumberWidth(maxDigits))
+	if len(chunks) > 1 && (params.wrapIndent == TextIndent || params.wrapMarker != "") {
+		// continuation lines are narrower than the first one, so they're wrapped again
+		var indent int
+		if params.wrapIndent == TextIndent {
+			indent = runewidth.StringWidth(pretty.Emojify(l.tag)) + 1
+		}
+		contWidth := max(maxTextWidth-indent-runewidth.StringWidth(params.wrapMarker), lenTag)
+		contPrefix += strings.Repeat(" ", indent) + params.wrapMarker
+		rest := wordWrap(strings.Join(chunks[1:], " "), contWidth)
+		chunks = append(chunks[:1], strings.Split(rest, "\n")...)
+	}
+	for i, chunk := range chunks {
 		if i == 0 {
 			// Print lineNumber + tag + text + author info
 			cl := utf8.RuneCountInString(removeANSIEscapeCodes(chunk))
@@ -194,29 +624,66 @@ func (l *matchLine) Render(
 			lineNumber := pretty.PrettyLineNumber(l.n, maxDigits)
 			pad := strings.Repeat(" ", maxTextWidth-cl)
 			chunk = chunk + pad
+			var hashStr string
+			if params.showHash {
+				var hash string
+				if l.blame != nil {
+					hash = l.blame.ShortHash()
+				}
+				hashStr = " " + pretty.PrettyHash(hash)
+			}
+			var dateStr string
+			if params.dateFormat != pretty.NoDate {
+				var date time.Time
+				if l.blame != nil {
+					date = l.blame.Time
+				}
+				dateStr = " " + pretty.PrettyDate(date, params.dateFormat, params.now)
+			}
 			var blameStr string
-			if showAuthor && l.blame != nil {
-				blameStr = " " + pretty.PrettyBlame(l.blame, oldCommitTime, style)
+			if params.showAuthor && l.blame != nil {
+				blameStr = " " + pretty.PrettyBlame(l.blame, params.ageTiers, style)
 			}
-			fmt.Println(lineNumber + chunk + blameStr)
+			fmt.Fprintln(w, lineNumber+chunk+hashStr+dateStr+blameStr)
 		} else {
 			// Print only the rest of the text
 			chunk = pretty.Colorize(chunk, l.tag, style)
-			lineNumber := strings.Repeat(" ", len(fmt.Sprint(maxLineNumber))+10)
-			fmt.Println(lineNumber + chunk)
+			fmt.Fprintln(w, contPrefix+chunk)
 		}
 	}
 }
 
-// Render the line and print it to stdout using the plain style format.
-func (l *matchLine) PlainRender(path string) {
-	fmt.Printf("%s:%d:%s:%s\n", path, l.n, l.tag, l.text)
+// Render the line and write it to w using the plain style format, see WritePlainLine.
+func (l *matchLine) PlainRender(w io.Writer, path string) {
+	var hash string
+	var date time.Time
+	if l.blame != nil {
+		hash, date = l.blame.ShortHash(), l.blame.Time
+	}
+	WritePlainLine(w, path, l.n, l.col, l.tag, hash, date, l.text)
 }
 
 type searchResult struct {
 	rootPath string
 	path     string
+	language string
 	lines    []*matchLine
+	seq      int
+}
+
+// oldestAge returns the age of the oldest line, see pretty.ShortAge, or an empty
+// string if the commits of the lines are unknown.
+func (r *searchResult) oldestAge(now time.Time) string {
+	var oldest time.Time
+	for _, line := range r.lines {
+		if line.blame != nil && !line.blame.Time.IsZero() && (oldest.IsZero() || line.blame.Time.Before(oldest)) {
+			oldest = line.blame.Time
+		}
+	}
+	if oldest.IsZero() {
+		return ""
+	}
+	return pretty.ShortAge(oldest, now)
 }
 
 func (r *searchResult) maxLineNumber() int {
@@ -229,38 +696,73 @@ func (r *searchResult) maxLineNumber() int {
 	return max
 }
 
-func (r *searchResult) printSummary(style pretty.Style) {
+// tagCounts returns the number of lines of each tag.
+func (r *searchResult) tagCounts() map[string]int {
 	counter := make(map[string]int, 10)
 	for i := 0; i < len(r.lines); i++ {
 		counter[r.lines[i].tag]++
 	}
+	return counter
+}
+
+func (r *searchResult) printSummary(w io.Writer, width int, counter map[string]int, params *searchParams) {
 	if len(counter) < 2 {
 		return
 	}
-	fmt.Println(pretty.PrettySummary(counter, style))
+	if params.severitySummary {
+		pretty.RenderSeveritySummary(w, width, counter, params.style)
+		return
+	}
+	pretty.RenderSummary(w, width, counter, params.style)
 }
 
-// Render and print the filename and all matching lines to stdout.
-func (r *searchResult) Render(width int, params *searchParams) {
-	path := r.path
-	if !params.fullPath {
-		path = shortenFilepath(path, r.rootPath)
+// displayPath returns the path of a file as it should be printed.
+func (p *searchParams) displayPath(path string) string {
+	if p.fullPath {
+		return path
 	}
+	return shortenFilepath(path, p.rootPath)
+}
+
+// Render the filename and all matching lines and write them to w.
+func (r *searchResult) Render(w io.Writer, width int, params *searchParams) {
+	path := params.displayPath(r.path)
 	switch params.style {
 	case pretty.PlainStyle:
 		for _, line := range r.lines {
-			line.PlainRender(path)
+			line.PlainRender(w, path)
 		}
 	default:
-		fmt.Println(pretty.PrettyFilename(path, len(r.lines), params.style))
+		var owners string
+		if params.showOwner {
+			owners = strings.Join(params.owners.Of(r.path), " ")
+		}
+		var oldest string
+		if params.showOldest {
+			oldest = r.oldestAge(params.now)
+		}
+		var language string
+		if params.showLanguage {
+			language = r.language
+		}
+		counter := r.tagCounts()
+		pretty.RenderFilename(w, width, path, len(r.lines), pretty.SeverestTag(counter), oldest, language, owners, params.style)
 		if params.summary {
-			r.printSummary(params.style)
+			r.printSummary(w, width, counter, params)
 		}
 		maxLineNumber := r.maxLineNumber()
-		for _, line := range r.lines {
-			line.Render(width, maxLineNumber, params.oldCommitTime, params.showAuthor, params.style)
+		lines := r.lines
+		if params.maxPerFile > 0 && len(lines) > params.maxPerFile {
+			lines = lines[:params.maxPerFile]
 		}
-		fmt.Println()
+		for _, line := range lines {
+			line.Render(w, width, maxLineNumber, params)
+		}
+		if hidden := len(r.lines) - len(lines); hidden > 0 {
+			pad := strings.Repeat(" ", pretty.LineNumberWidth(len(fmt.Sprint(maxLineNumber))))
+			fmt.Fprintln(w, pad+pretty.Italic(i18n.Sprintf("… and %d more (use --all to expand)", hidden)))
+		}
+		fmt.Fprintln(w)
 	}
 }
 
@@ -274,39 +776,182 @@ func shortenFilepath(path string, rootPath string) string {
 
 // Search a file or folder for the specified tags.
 // Use the function NewSearchParams to create the required struct.
-func Search(params *searchParams) {
+// The returned Stats hold the end-of-run totals, which are also printed if requested.
+func Search(params *searchParams) *Stats {
+	if params.timeout > 0 {
+		// the deadline only applies to this search, params may be reused
+		withTimeout := *params
+		params = &withTimeout
+		var cancel context.CancelFunc
+		params.ctx, cancel = context.WithTimeout(params.ctx, params.timeout)
+		defer cancel()
+	}
+	stats := newStats()
+	stats.bySeverity = params.severitySummary
+	if params.byOwner {
+		stats.owners = make(map[string]*ownerTotals)
+	}
+	if params.coOccurrence {
+		stats.mixed = make(map[string]map[string]int)
+	}
 	searchJobs := make(chan *searchJob)
 	searchResults := make(chan *searchResult)
 
 	var wg sync.WaitGroup
 	var wgResult sync.WaitGroup
 	for w := 0; w < params.workers; w++ {
-		go searchWorker(params, searchJobs, searchResults, &wg, &wgResult)
+		go searchWorker(params, searchJobs, searchResults, stats, &wg, &wgResult)
+	}
+
+	var w io.Writer = stdout
+	if params.output != nil {
+		w = params.output
 	}
+	errW := stderr
+	if params.diagnostics != nil {
+		errW = params.diagnostics
+	}
+	var out renderer = newRenderer(params, w, errW)
+	if params.quickfix != nil {
+		out = newQuickfixRenderer(out, params.quickfix, params.quickfixFormat)
+	}
+	go printResult(params, searchResults, &wgResult, out, stats)
+
+	var seq int
+	params.walk(stats, func(path, blob string) {
+		wg.Add(1)
+		// do not account for the time waiting for a free worker
+		sendStart := time.Now()
+		job := &searchJob{
+			finder:    params.regexes.forPath(path),
+			malformed: params.regexes.malformedFor(path),
+			path:      path,
+			blob:      blob,
+			seq:       seq,
+		}
+		if params.prefetchBlame && params.requiresBlame() {
+			job.prefetch = newBlameFuture()
+		}
+		searchJobs <- job
+		seq++
+		params.timings.add(phaseWalk, -time.Since(sendStart))
+	})
+	wg.Wait()
+	wgResult.Wait()
+	stats.finish()
+	if params.ctx.Err() != nil {
+		stats.interrupt()
+	}
+	params.checkpoint.finish(stats.Interrupted())
+
+	out.finish(stats)
+	switch {
+	case errors.Is(params.ctx.Err(), context.DeadlineExceeded):
+		fmt.Fprintln(errW, i18n.Sprintf("scan timed out after %d files, results are incomplete", stats.filesScanned))
+	case stats.Interrupted():
+		fmt.Fprintln(errW, i18n.Sprintf("scan interrupted after %d files, results are incomplete", stats.filesScanned))
+	}
+	if n := stats.blameFailures(); n > 0 {
+		fmt.Fprintln(errW, i18n.Sprintf("git blame failed for %d files, their comments have no author", n))
+	}
+	var b strings.Builder
+	params.timings.Render(&b, stats.elapsed)
+	io.WriteString(errW, b.String())
+	return stats
+}
 
-	go printResult(searchResults, &wgResult, params)
+// walk calls visit with every file that would be scanned and the hash of its content,
+// if known, listing files from the git index with useIndex. With a patch, only the
+// files changed by the patch are listed.
+func (p *searchParams) walk(stats *Stats, visit func(path, blob string)) {
+	if p.tracked != nil {
+		visitTracked := visit
+		visit = func(path, blob string) {
+			if !p.tracked[path] {
+				slog.Info("skipping untracked file", "path", path)
+				stats.addSkipped()
+				return
+			}
+			visitTracked(path, blob)
+		}
+	}
+	if p.owner != "" {
+		visitOwned := visit
+		visit = func(path, blob string) {
+			if !codeowners.Owns(p.owners.Of(path), p.owner) {
+				slog.Info("skipping file owned by others", "path", path)
+				stats.addSkipped()
+				return
+			}
+			visitOwned(path, blob)
+		}
+	}
+	if p.types != nil {
+		visitTyped := visit
+		visit = func(path, blob string) {
+			// a shebang line may have to be read
+			p.openFiles.acquire()
+			language := Language(path)
+			p.openFiles.release()
+			if !p.types[language] {
+				slog.Info("skipping file of another type", "path", path, "language", language)
+				stats.addSkipped()
+				return
+			}
+			visitTyped(path, blob)
+		}
+	}
+	if p.patch != nil {
+		walkPatch(p, stats, func(path string) { visit(path, "") })
+		return
+	}
+	if p.useIndex && walkIndex(p, stats, visit) {
+		return
+	}
+	walkFiles(p, stats, func(path string) { visit(path, "") })
+}
 
+// walkFiles calls visit with every file that would be scanned, in walk order. Ignored
+// paths are skipped, as are files that can't be scanned, which are recorded in stats.
+func walkFiles(params *searchParams, stats *Stats, visit func(path string)) {
 	walk := func(path string, d fs.DirEntry, err error) error {
+		defer params.timings.since(phaseWalk, time.Now())
+		if params.ctx.Err() != nil {
+			return filepath.SkipAll
+		}
 		if err != nil {
-			log.Errorf("file walk error: %s", err)
-			return err
+			slog.Debug("file walk error", "path", path, "error", err)
+			stats.skipFile(path, readErrorReason(err))
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		if matcher.MatchGit(path) {
-			log.Infof("skipping .git directory: %s", path)
+			slog.Info("skipping .git directory", "path", path)
+			return filepath.SkipDir
+		}
+
+		if d.IsDir() && path != params.rootPath && params.excludedDir(d.Name()) {
+			slog.Info("skipping excluded directory", "path", path)
 			return filepath.SkipDir
 		}
 
 		isDir := d.IsDir()
 		switch params.matcher.Match(path) {
 		case matcher.GitIgnore:
-			log.Infof("skipping %s due to .gitignore", path)
+			slog.Info("skipping path due to .gitignore", "path", path)
 			if isDir {
 				return filepath.SkipDir
 			}
+			stats.addSkipped()
 			return nil
 		case matcher.GlobIgnore:
-			log.Infof("skipping %s due to glob pattern", path)
+			slog.Info("skipping path due to glob pattern", "path", path)
+			if !isDir {
+				stats.addSkipped()
+			}
 			return nil
 		}
 
@@ -316,132 +961,339 @@ func Search(params *searchParams) {
 
 		info, err := d.Info()
 		if err != nil {
-			log.Errorf("error getting file info for %s: %s", path, err)
+			slog.Debug("error getting file info", "path", path, "error", err)
+			stats.skipFile(path, readErrorReason(err))
 			return nil
 		}
-		if info.Size() > params.maxFs<<20 {
-			log.Warningf("skipping file larger than %dMB: %s", params.maxFs, path)
+		if limit := params.maxFileSize(path); info.Size() > limit<<20 {
+			slog.Info("skipping large file", "path", path, "size", info.Size(), "limit_mb", limit)
+			stats.skipFile(path, SkipSize)
 			return nil
 		}
-		wg.Add(1)
-		searchJobs <- &searchJob{regex: params.regex, path: path}
+		visit(path)
 		return nil
 	}
 
 	filepath.WalkDir(params.rootPath, walk)
-	wg.Wait()
-	wgResult.Wait()
+}
+
+// ListFiles prints the files that a search would scan, one per line, without scanning them.
+// Files that can't be scanned, e.g. due to the size limit, are left out and counted in the returned Stats.
+func ListFiles(params *searchParams) *Stats {
+	stats := newStats()
+	var w io.Writer = stdout
+	if params.output != nil {
+		w = params.output
+	}
+	params.walk(stats, func(path, _ string) {
+		fmt.Fprintln(w, params.displayPath(path))
+	})
+	stats.finish()
+	return stats
 }
 
 func searchWorker(
 	params *searchParams,
 	jobs chan *searchJob,
 	searchResults chan *searchResult,
+	stats *Stats,
 	wg, wgResult *sync.WaitGroup,
 ) {
 	for job := range jobs {
-		lines := scanFile(params, job)
-		if len(lines) > 0 {
+		var lines []*matchLine
+		// once interrupted, the remaining files are not scanned, and files being
+		// scanned are discarded since their blame may be incomplete
+		if params.ctx.Err() == nil {
+			// files scanned before a resumed search was interrupted aren't read again
+			file, stamp, resumed := params.checkpoint.get(job.path)
+			scanned, nLines, skipReason := file.matchLines(), file.NLines, file.SkipReason
+			if !resumed {
+				start := time.Now()
+				scanned, nLines, skipReason = scanFile(params, job, stats)
+				params.timings.addFile(job.path, time.Since(start))
+			}
+			switch {
+			case params.ctx.Err() != nil:
+			case skipReason != "":
+				stats.skipFileAt(job.path, skipReason, nLines)
+				lines = scanned
+			default:
+				stats.addScanned(params.rootPath, job.path, nLines)
+				lines = scanned
+			}
+			params.lint.apply(lines)
+			if !resumed && params.ctx.Err() == nil {
+				params.checkpoint.add(job.path, stamp, scanned, nLines, skipReason)
+			}
+		}
+		// ordered output waits for every file, even without matches
+		if len(lines) > 0 || params.ordered {
+			var language string
+			if len(lines) > 0 {
+				// a shebang line may have to be read
+				params.openFiles.acquire()
+				language = Language(job.path)
+				params.openFiles.release()
+			}
 			wgResult.Add(1)
-			searchResults <- &searchResult{rootPath: params.rootPath, path: job.path, lines: lines}
+			searchResults <- &searchResult{rootPath: params.rootPath, path: job.path, language: language, lines: lines, seq: job.seq}
 		}
 		wg.Done()
 	}
 }
 
+// scanFile returns the matching lines and the number of lines of a file. If the file
+// can't be scanned (e.g. it isn't a text file), skipReason is one of the Skip* reasons.
+// Once the file is scanned, the matching lines are blamed with a single git blame
+// restricted to them, and filtered by author and commit age.
 func scanFile(
 	params *searchParams,
 	job *searchJob,
-) []*matchLine {
-	log.Debugf("scanning file %s", job.path)
+	stats *Stats,
+) (lines []*matchLine, nLines int, skipReason string) {
+	slog.Debug("scanning file", "path", job.path)
+	start := time.Now()
+	lines, nLines, skipReason = findLinesCached(params, job, stats)
+	params.timings.add(phaseScan, time.Since(start))
+	if params.patch != nil {
+		lines = params.patch.filter(job.path, lines)
+	}
+	if len(lines) == 0 {
+		return lines, nLines, skipReason
+	}
 
-	var lines []*matchLine
+	if params.requiresBlame() {
+		start = time.Now()
+		blameLines(params, job, lines, stats)
+		params.timings.add(phaseBlame, time.Since(start))
+	}
+	if params.localAuthor != "" && params.requiresAuthor() {
+		setLocalAuthor(params.localAuthor, lines)
+	}
+	if len(params.authorRules) > 0 {
+		lines = normalizeAuthors(params.authorRules, lines)
+	}
+
+	valid := lines[:0]
+	for _, line := range lines {
+		if validLine(job.path, line, params) {
+			valid = append(valid, line)
+		}
+	}
+	return valid, nLines, skipReason
+}
+
+// findLines returns the tagged lines and the number of lines of a file, see scanFile.
+func findLines(params *searchParams, job *searchJob, stats *Stats) (lines []*matchLine, nLines int, skipReason string) {
+	params.openFiles.acquire()
+	defer params.openFiles.release()
 	f, err := os.Open(filepath.FromSlash(job.path))
 	if err != nil {
-		log.Fatalf("couldn't open path %s: %s", job.path, err)
-		return lines
+		slog.Debug("couldn't open path", "path", job.path, "error", err)
+		return nil, 0, readErrorReason(err)
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-
-	var gb *blame.GitBlame
-	var triedBlame bool
-	var lineBlame *blame.LineBlame
-
-	showAuthor := params.showAuthor && params.style != pretty.PlainStyle
-	requiresBlame := params.author != "" || !params.oldCommitTime.Equal(zeroTime) || showAuthor
+	reader := newLineReader(f, params.maxLineLength)
+	var longLines int
+	var symbols *symbolFinder
+	if params.showSymbol {
+		symbols = newSymbolFinder(Language(job.path))
+	}
 
-	for lineNumber := 1; scanner.Scan(); lineNumber++ {
-		text := scanner.Bytes()
+	for lineNumber := 1; reader.next(); lineNumber++ {
+		nLines = lineNumber
+		if reader.skipped {
+			longLines++
+			continue
+		}
+		text := reader.bytes()
 
 		mimeType := http.DetectContentType(text)
-		if !strings.HasPrefix(strings.SplitN(mimeType, ";", 1)[0], "text") {
-			log.Infof("skipping non-text file of type %s: %s", mimeType, job.path)
-			break
+		if !strings.HasPrefix(mimeType, "text") {
+			slog.Info("skipping non-text file", "path", job.path, "mime_type", mimeType)
+			// lines found before a non-text chunk are still reported
+			return lines, nLines, SkipBinary
 		}
-
-		match := job.regex.FindSubmatch(text)
-		if len(match) < 3 {
-			continue
+		if strings.Contains(mimeType, "charset=utf-16") {
+			slog.Info("skipping file with unsupported encoding", "path", job.path, "mime_type", mimeType)
+			return lines, nLines, SkipEncoding
 		}
 
-		if requiresBlame && !triedBlame {
-			gb, _ = blame.BlameFile(job.path)
-			triedBlame = true
+		var symbol string
+		if symbols != nil {
+			symbols.add(text)
+			symbol = symbols.enclosing(text)
 		}
-
-		if requiresBlame && gb != nil {
-			lineBlame, _ = gb.BlameLine(lineNumber)
+		tag, comment, col, ok := job.finder.find(text)
+		if ok {
+			lines = append(lines, &matchLine{n: lineNumber, col: col, tag: tag, text: comment, symbol: symbol, assignee: taggedAssignee(text, tag)})
+		} else if job.malformed != nil {
+			if written, comment, col, ok := job.malformed.find(text); ok {
+				lines = append(lines, &matchLine{n: lineNumber, col: col, tag: job.malformed.suggest(written), text: comment, malformed: written, symbol: symbol})
+			}
 		}
+		if len(lines) > 0 && job.prefetch != nil {
+			job.prefetch.start(params, job.path)
+		}
+	}
 
-		line := &matchLine{blame: lineBlame, n: lineNumber, tag: string(match[1]), text: string(match[2])}
-		if validLine(job.path, line, params) {
-			lines = append(lines, line)
+	if longLines > 0 {
+		slog.Info(
+			"skipped lines exceeding the maximum length",
+			"path", job.path,
+			"lines", longLines,
+			"limit_kb", params.maxLineLength>>10,
+		)
+	}
+	if err = reader.error(); err != nil {
+		slog.Error("error while searching for tags", "path", job.path, "error", err)
+		stats.addError(job.path, ErrorRead, err)
+	}
+	return lines, nLines, ""
+}
+
+// requiresBlame reports whether tagged lines must be blamed, to be filtered or shown.
+func (p *searchParams) requiresBlame() bool {
+	return p.useGit && p.requiresAuthor()
+}
+
+// requiresAuthor reports whether the authors of tagged lines are filtered or shown.
+func (p *searchParams) requiresAuthor() bool {
+	showAuthor := p.showAuthor && (p.collect != nil || (!p.quiet && p.format != LocationsFormat &&
+		(p.style != pretty.PlainStyle || p.format != TextFormat)))
+	excludesAuthors := slices.ContainsFunc(p.authorRules, func(r AuthorRule) bool { return r.Exclude })
+	return p.author != "" || p.ageTiers != nil || showAuthor || p.showOldest || excludesAuthors
+}
+
+// blameFile blames the lines of the file, or every line if none are provided, stopping
+// git blame once the search is done or after the blame timeout, if any.
+func (p *searchParams) blameFile(path string, lines []int) (*blame.GitBlame, error) {
+	if p.blameTimeout <= 0 {
+		return p.blameCache.BlameFile(p.ctx, path, lines, p.blameSettings)
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, p.blameTimeout)
+	defer cancel()
+	gb, err := p.blameCache.BlameFile(ctx, path, lines, p.blameSettings)
+	if err != nil && p.ctx.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("git blame timed out after %s", p.blameTimeout)
+	}
+	return gb, err
+}
+
+// blameLines sets the blame of the lines, running git blame once for all of them,
+// unless the blame of the whole file was prefetched while it was scanned.
+func blameLines(params *searchParams, job *searchJob, lines []*matchLine, stats *Stats) {
+	path := job.path
+	var gb *blame.GitBlame
+	var err error
+	if job.prefetch != nil && job.prefetch.started {
+		gb, err = job.prefetch.wait()
+	} else {
+		numbers := make([]int, 0, len(lines))
+		for _, line := range lines {
+			numbers = append(numbers, line.n)
 		}
+		// git blame reads the file and its history
+		params.openFiles.acquire()
+		gb, err = params.blameFile(path, numbers)
+		params.openFiles.release()
+	}
+	if params.ctx.Err() != nil {
+		// the search was interrupted, the file is discarded
+		return
+	}
+	if err != nil && params.cloneState.Incomplete() {
+		// missing history is expected, the author is reported as unknown instead
+		slog.Debug("git blame failed in incomplete clone", "path", path, "error", err)
+		for _, line := range lines {
+			line.blame = &blame.LineBlame{Author: blame.UnknownAuthor}
+		}
+		return
+	}
+	if err != nil && params.localAuthor != "" {
+		// e.g. untracked files, whose lines are attributed to the local user instead
+		slog.Debug("git blame failed, using the local author", "path", path, "error", err)
+		return
+	}
+	if err != nil {
+		slog.Info("git blame failed", "path", path, "error", err)
+		stats.addError(path, ErrorBlame, err)
+		return
+	}
+	for _, line := range lines {
+		line.blame, _ = gb.BlameLine(line.n)
 	}
+}
 
-	if err = scanner.Err(); err != nil {
-		switch err {
-		case bufio.ErrTooLong:
-			log.Errorf(
-				"file %s has lines exceeding the maximum size of %dKB, results may be incomplete",
-				job.path,
-				bufio.MaxScanTokenSize>>10,
-			)
-		default:
-			log.Errorf("error while searching for tags in file %s - %s", job.path, err)
+// setLocalAuthor attributes the lines that weren't blamed, or aren't committed yet,
+// to the local user. Lines of unknown authors in incomplete clones are left as is.
+func setLocalAuthor(name string, lines []*matchLine) {
+	for _, line := range lines {
+		switch {
+		case line.blame == nil:
+			line.blame = blame.LocalBlame(name, time.Time{})
+		case line.blame.Hash == "" && line.blame.Author != blame.UnknownAuthor:
+			line.blame = blame.LocalBlame(name, line.blame.Time)
 		}
 	}
-	return lines
 }
 
 func validLine(path string, line *matchLine, params *searchParams) bool {
 	if params.author != "" && (line.blame == nil || line.blame.Author != params.author) {
-		log.Debugf("skipping %s line %d due to author filter", path, line.n)
+		slog.Debug("skipping line due to author filter", "path", path, "line", line.n)
 		return false
 	}
 	if !params.commitAgeTime.Equal(zeroTime) {
 		if line.blame == nil {
-			log.Debugf("skipping %s line %d due to commit age: no git blame", path, line)
+			slog.Debug("skipping line due to commit age: no git blame", "path", path, "line", line.n)
 			return false
 		}
 
 		if line.blame.Time.Before(params.commitAgeTime) {
-			log.Debugf("skipping %s line %d due to commit age", path, line)
+			slog.Debug("skipping line due to commit age", "path", path, "line", line.n)
 			return false
 		}
 	}
 	return true
 }
 
-func printResult(searchResults chan *searchResult, wgResult *sync.WaitGroup, params *searchParams) {
-	var width int
-	if params.style != pretty.PlainStyle {
-		width = getLimitedWidth()
+func printResult(
+	params *searchParams,
+	searchResults chan *searchResult,
+	wgResult *sync.WaitGroup,
+	out renderer,
+	stats *Stats,
+) {
+	var seq *sequencer
+	if params.ordered {
+		seq = newSequencer()
 	}
 	for result := range searchResults {
-		result.Render(width, params)
+		ready := []*searchResult{result}
+		if seq != nil {
+			ready = seq.push(result)
+		}
+		for _, r := range ready {
+			if len(r.lines) == 0 {
+				continue
+			}
+			stats.addResult(r)
+			if params.byOwner {
+				stats.addOwned(r, params.owners.Of(r.path))
+			}
+			if params.coOccurrence {
+				stats.addMixed(r)
+			}
+			if params.collect != nil {
+				for _, m := range r.jsonMatches(params) {
+					params.collect(m)
+				}
+			}
+			start := time.Now()
+			out.result(r)
+			params.timings.since(phaseRender, start)
+		}
 		wgResult.Done()
 	}
 }
@@ -450,7 +1302,7 @@ func getWidth() int {
 	s, err := tsize.GetSize()
 
 	if err != nil {
-		log.Warningf("couldn't read terminal size, using width %d: %s", defaultWidth, err)
+		slog.Warn("couldn't read terminal size, using default width", "width", defaultWidth, "error", err)
 		return defaultWidth
 	}
 
@@ -458,9 +1310,57 @@ func getWidth() int {
 }
 
 func getLimitedWidth() int {
-	width := getWidth()
+	return limitWidth(getWidth())
+}
+
+func limitWidth(width int) int {
 	if width > maxWidth {
 		width = maxWidth
 	}
 	return width
 }
+
+// terminalWidth tracks the limited width of the terminal, updated whenever
+// it's resized (SIGWINCH), so results rendered later use the new layout.
+type terminalWidth struct {
+	width    atomic.Int64
+	listener *tsize.SizeListener
+	done     chan struct{}
+}
+
+func newTerminalWidth() *terminalWidth {
+	w := &terminalWidth{done: make(chan struct{})}
+	w.width.Store(int64(getLimitedWidth()))
+
+	listener, err := tsize.NewSizeListener()
+	if err != nil {
+		slog.Debug("couldn't listen to terminal size changes", "error", err)
+		return w
+	}
+	w.listener = listener
+	go func(change <-chan tsize.Size) {
+		for {
+			select {
+			case s := <-change:
+				slog.Debug("terminal resized", "width", s.Width)
+				w.width.Store(int64(limitWidth(s.Width)))
+			case <-w.done:
+				return
+			}
+		}
+	}(listener.Change)
+	return w
+}
+
+func (w *terminalWidth) get() int {
+	return int(w.width.Load())
+}
+
+// close stops listening to terminal size changes.
+func (w *terminalWidth) close() {
+	if w.listener != nil {
+		close(w.done)
+		w.listener.Close()
+		w.listener = nil
+	}
+}
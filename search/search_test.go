@@ -1,9 +1,21 @@
 package search
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/mathpn/listme/blame"
+	"github.com/mathpn/listme/pretty"
 )
 
 const baseStr = "this is a string with many "
@@ -21,3 +33,913 @@ func BenchmarkTextWrap(b *testing.B) {
 		})
 	}
 }
+
+func TestPrefixedTagRegex(t *testing.T) {
+	regexes, err := newTagRegexes([]string{"TODO", "FIXME"}, nil, map[string][]string{".lisp": {";;"}}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := regexes.regexFor("src/main.lisp")
+
+	cases := []struct {
+		line string
+		tag  string
+		text string
+	}{
+		{"(defun f () nil) ;; TODO: handle nil", "TODO", "handle nil"},
+		{";;;; FIXME broken", "FIXME", "broken"},
+		{"(setq TODO 1)", "", ""},
+	}
+	for _, c := range cases {
+		match := r.FindStringSubmatch(c.line)
+		if c.tag == "" {
+			if match != nil {
+				t.Errorf("%q: unexpected match %q", c.line, match)
+			}
+			continue
+		}
+		if match == nil || match[1] != c.tag || match[2] != c.text {
+			t.Errorf("%q: expected tag %q and text %q, got %q", c.line, c.tag, c.text, match)
+		}
+	}
+
+	if regexes.regexFor("main.go") != regexes.defaultRegex {
+		t.Error("files without custom prefixes must use the default regex")
+	}
+}
+
+func TestProseTagRegex(t *testing.T) {
+	regexes, err := newTagRegexes([]string{"TODO"}, nil, nil, map[string]string{".txt": ProseRule}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"README.md", "notes.txt"} {
+		r := regexes.regexFor(path)
+		match := r.FindStringSubmatch("Remember (TODO: update this paragraph) before the release.")
+		if match == nil || match[1] != "TODO" {
+			t.Errorf("%s: expected TODO in plain paragraph, got %q", path, match)
+		}
+		if match := r.FindStringSubmatch("See the TODO_LIST variable."); match != nil {
+			t.Errorf("%s: unexpected match inside identifier %q", path, match)
+		}
+	}
+
+	if _, err := newTagRegexes([]string{"TODO"}, nil, nil, map[string]string{".md": "loose"}, false); err == nil {
+		t.Error("expected error for unknown documentation rule")
+	}
+}
+
+func TestExtensionTags(t *testing.T) {
+	extTags := map[string][]string{".py": {"type: ignore"}, ".java": {"@deprecated"}, ".md": {"WIP"}}
+	regexes, err := newTagRegexes([]string{"TODO"}, extTags, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path string
+		line string
+		tag  string
+	}{
+		{"a.py", "x = f()  # type: ignore", "type: ignore"},
+		{"a.py", "# TODO: remove", "TODO"},
+		{"a.go", "x := f() // type: ignore", ""},
+		{"A.java", "   * @deprecated use g instead", "@deprecated"},
+		{"A.java", "// mail foo@deprecated.com", ""},
+		{"notes.md", "WIP: second draft", "WIP"},
+		{"notes.txt", "WIP: second draft", ""},
+	}
+	for _, c := range cases {
+		tag, _, _, ok := regexes.forPath(c.path).find([]byte(c.line))
+		if tag != c.tag || ok != (c.tag != "") {
+			t.Errorf("%s %q: expected tag %q, got %q", c.path, c.line, c.tag, tag)
+		}
+	}
+}
+
+func TestMalformedTags(t *testing.T) {
+	regexes, err := newTagRegexes([]string{"TODO", "FIXME"}, nil, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := regexes.findMalformed([]string{"TODO", "FIXME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path      string
+		line      string
+		malformed string
+		tag       string
+	}{
+		{"a.py", "# TOOD: fix this", "TOOD", "TODO"},
+		{"a.py", "# Todo fix this", "Todo", "TODO"},
+		{"a.go", "// FIX ME later", "FIX ME", "FIXME"},
+		{"a.go", "x := 1 // fixem", "fixem", "FIXME"},
+		{"a.py", "# to do this we need", "", ""},
+		{"a.py", "# todos are done", "", ""},
+		{"notes.md", "TOOD in prose", "", ""},
+	}
+	for _, c := range cases {
+		finder := regexes.malformedFor(c.path)
+		var written string
+		var ok bool
+		if finder != nil {
+			written, _, _, ok = finder.find([]byte(c.line))
+		}
+		if written != c.malformed || ok != (c.malformed != "") {
+			t.Errorf("%s %q: expected misspelling %q, got %q", c.path, c.line, c.malformed, written)
+		}
+		if ok && finder.suggest(written) != c.tag {
+			t.Errorf("%s %q: expected tag %q, got %q", c.path, c.line, c.tag, finder.suggest(written))
+		}
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	input := "first\r\n" + long + " TODO hidden\n" + "third\n\n" + long + "\nlast"
+	r := newLineReader(strings.NewReader(input), 50)
+
+	want := []string{"first", "", "third", "", "", "last"}
+	skipped := []bool{false, true, false, false, true, false}
+	var i int
+	for ; r.next(); i++ {
+		if i >= len(want) {
+			t.Fatalf("unexpected line %d: %q", i+1, r.bytes())
+		}
+		if string(r.bytes()) != want[i] || r.skipped != skipped[i] {
+			t.Errorf("line %d: got %q (skipped %v), want %q (skipped %v)", i+1, r.bytes(), r.skipped, want[i], skipped[i])
+		}
+	}
+	if i != len(want) {
+		t.Errorf("got %d lines, want %d", i, len(want))
+	}
+	if err := r.error(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSequencer(t *testing.T) {
+	s := newSequencer()
+	order := []int{2, 0, 3, 1, 4}
+	released := [][]int{nil, {0}, nil, {1, 2, 3}, {4}}
+	for i, seq := range order {
+		ready := s.push(&searchResult{seq: seq})
+		var got []int
+		for _, r := range ready {
+			got = append(got, r.seq)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(released[i]) {
+			t.Errorf("push %d: expected %v to be released, got %v", seq, released[i], got)
+		}
+	}
+}
+
+// writeFiles creates the files, by path relative to a new temporary directory, with
+// their parent directories, and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// TestOutputStreams checks that stdout carries only results and diagnostics go to stderr.
+func TestOutputStreams(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"code.py":  "# TODO: first\nx = 1\n# FIXME: second\n",
+		"blob.bin": "\x00\x01\x02\x03",
+	})
+
+	origOut, origErr := stdout, stderr
+	defer func() { stdout, stderr = origOut, origErr }()
+
+	for _, format := range []Format{TextFormat, JSONLFormat, SARIFFormat} {
+		var out, errOut bytes.Buffer
+		stdout, stderr = &out, &errOut
+		params, err := NewSearchParams(Options{
+			Path:            dir,
+			Tags:            []string{"TODO", "FIXME"},
+			Workers:         2,
+			Style:           pretty.PlainStyle,
+			Format:          format,
+			CommitAgeFilter: -1,
+			MaxFileSize:     1,
+			NoGit:           true,
+			Stats:           true,
+			Glob:            "*",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		Search(params)
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		switch format {
+		case TextFormat:
+			if len(lines) != 2 || !strings.HasPrefix(lines[0], "code.py:1:3:TODO:") {
+				t.Errorf("text: expected only the 2 matches on stdout, got %q", lines)
+			}
+			if !strings.Contains(errOut.String(), "# stats:") || !strings.Contains(errOut.String(), "blob.bin") {
+				t.Errorf("text: expected stats and skipped files on stderr, got %q", errOut.String())
+			}
+		case JSONLFormat:
+			for _, line := range lines {
+				var record JSONLRecord
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Errorf("jsonl: invalid record %q: %s", line, err)
+				}
+			}
+			if errOut.Len() != 0 {
+				t.Errorf("jsonl: expected empty stderr, got %q", errOut.String())
+			}
+		case SARIFFormat:
+			var log sarifLog
+			if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+				t.Fatalf("sarif: invalid log: %s", err)
+			}
+			run := log.Runs[0]
+			if log.Version != "2.1.0" || len(run.Tool.Driver.Rules) != 2 || len(run.Results) != 2 {
+				t.Fatalf("sarif: expected 2 rules and 2 results, got %+v", log)
+			}
+			loc := run.Results[1].Locations[0].PhysicalLocation
+			if run.Results[1].RuleID != "FIXME" || run.Results[1].Level != "error" || loc.ArtifactLocation.URI != "code.py" || loc.Region.StartLine != 3 {
+				t.Errorf("sarif: unexpected result %+v", run.Results[1])
+			}
+			if !strings.Contains(errOut.String(), "blob.bin") {
+				t.Errorf("sarif: expected skipped files on stderr, got %q", errOut.String())
+			}
+		}
+	}
+}
+
+// TestJSONSchema checks that JSONL records of a search are valid according to the generated schema.
+func TestJSONSchema(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"code.py":  "# TODO: first\n",
+		"blob.bin": "\x00\x01\x02\x03",
+	})
+
+	schema, err := JSONSchema(JSONLFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// round trip, so the schema holds the same types as the decoded records
+	b, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	origOut := stdout
+	defer func() { stdout = origOut }()
+	var out bytes.Buffer
+	stdout = &out
+	params, err := NewSearchParams(Options{
+		Path:            dir,
+		Tags:            []string{"TODO"},
+		Workers:         2,
+		Format:          JSONLFormat,
+		CommitAgeFilter: -1,
+		MaxFileSize:     1,
+		NoGit:           true,
+		Stats:           true,
+		Glob:            "*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	Search(params)
+
+	types := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		types[record["type"].(string)] = true
+		if err := validateSchema(decoded, decoded, record); err != nil {
+			t.Errorf("invalid record %s: %s", line, err)
+		}
+	}
+	for _, recordType := range []string{MatchRecord, SkippedRecord, StatsRecord} {
+		if !types[recordType] {
+			t.Errorf("expected a %s record, got %q", recordType, out.String())
+		}
+	}
+	if err := validateSchema(decoded, decoded, map[string]any{"schema_version": 1.0, "type": "unknown"}); err == nil {
+		t.Errorf("expected an unknown record type to be invalid")
+	}
+}
+
+// validateSchema validates value against the subset of JSON Schema used by JSONSchema.
+func validateSchema(root, schema map[string]any, value any) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		return validateSchema(root, root["$defs"].(map[string]any)[name].(map[string]any), value)
+	}
+	if c, ok := schema["const"]; ok && c != value {
+		return fmt.Errorf("expected %v, got %v", c, value)
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%v is not one of %v", value, enum)
+	}
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("expected an object, got %v", value)
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("missing required field %s", name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, v := range obj {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				property, ok = schema["additionalProperties"].(map[string]any)
+			}
+			if !ok {
+				return fmt.Errorf("unknown field %s", name)
+			}
+			if err := validateSchema(root, property, v); err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("expected an array, got %v", value)
+		}
+		for _, item := range items {
+			if err := validateSchema(root, schema["items"].(map[string]any), item); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected a string, got %v", value)
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok || schema["type"] == "integer" && n != float64(int64(n)) {
+			return fmt.Errorf("expected an %s, got %v", schema["type"], value)
+		}
+	}
+	return nil
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint("cmd/main.go", "TODO", "handle  errors ", 0)
+	if b := Fingerprint("cmd/main.go", "TODO", "handle errors", 0); a != b {
+		t.Errorf("expected whitespace to be ignored, got %s and %s", a, b)
+	}
+	for _, other := range []string{
+		Fingerprint("main.go", "TODO", "handle errors", 0),
+		Fingerprint("cmd/main.go", "FIXME", "handle errors", 0),
+		Fingerprint("cmd/main.go", "TODO", "handle errors", 1),
+	} {
+		if a == other {
+			t.Errorf("expected fingerprints to differ, got %s twice", a)
+		}
+	}
+}
+
+func TestFindColumn(t *testing.T) {
+	regexes, err := newTagRegexes([]string{"TODO"}, nil, nil, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path string
+		line string
+		col  int
+	}{
+		{"a.py", "# TODO: remove", 3},
+		{"a.py", "x = 1  # TODO: remove", 10},
+		{"a.py", "# ação TODO: remove", 8},
+		{"notes.md", "  - [ ] write docs", 5},
+	}
+	for _, c := range cases {
+		_, _, col, ok := regexes.forPath(c.path).find([]byte(c.line))
+		if !ok || col != c.col {
+			t.Errorf("%s %q: expected column %d, got %d", c.path, c.line, c.col, col)
+		}
+	}
+}
+
+func TestEmbeddedTags(t *testing.T) {
+	regexes, err := newTagRegexes([]string{"TODO", "FIXME"}, nil, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path string
+		line string
+		tag  string // empty if no tag should be found
+	}{
+		{"a.py", "# TODO: remove", "TODO"},
+		{"a.go", "x := 1 //TODO: remove", "TODO"},
+		{"a.sql", "SELECT 1; --FIXME slow", "FIXME"},
+		{"a.html", "<!--TODO: alt text-->", "TODO"},
+		{"a.py", "# see https://example.com/TODO", ""},
+		{"a.go", `url := "https://example.com/TODO/list"`, ""},
+		{"a.py", "# moved to docs/TODO", ""},
+		{"a.c", "#define FOO_TODO_BAR 1", ""},
+		{"a.js", "const x = FOO-TODO-BAR;", ""},
+		{"a.rb", "value = obj.TODO", ""},
+		{"a.py", "# https://example.com/TODO TODO: real one", "TODO"},
+		{"a.go", "// FIXME(john): race", "FIXME"},
+		{"a.py", "# TODO-123 tracked elsewhere", "TODO"},
+	}
+	for _, c := range cases {
+		tag, _, _, ok := regexes.forPath(c.path).find([]byte(c.line))
+		if ok != (c.tag != "") || tag != c.tag {
+			t.Errorf("%s %q: expected tag %q, got %q", c.path, c.line, c.tag, tag)
+		}
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	files := map[string]string{
+		"main.go":   "package main\n",
+		"Makefile":  "all:\n",
+		"deploy":    "#!/usr/bin/env -S python3.12 -u\n",
+		"build":     "#!/bin/bash\nset -e\n",
+		"notes":     "TODO: nothing\n",
+		"script.PY": "",
+		"CHANGELOG": "#!not a shebang\n",
+	}
+	want := map[string]string{
+		"main.go":   "Go",
+		"Makefile":  "Makefile",
+		"deploy":    "Python",
+		"build":     "Shell",
+		"notes":     "",
+		"script.PY": "Python",
+		"CHANGELOG": "",
+	}
+	dir := writeFiles(t, files)
+	for name := range files {
+		if got := Language(filepath.Join(dir, name)); got != want[name] {
+			t.Errorf("%s: expected language %q, got %q", name, want[name], got)
+		}
+	}
+}
+
+func TestSymbolFinder(t *testing.T) {
+	cases := []struct {
+		language string
+		source   string
+		want     []string // enclosing symbol of each line with a TODO
+	}{
+		{"Go", `package main
+
+// TODO: top level
+func (p *params) Search(
+	ctx context.Context,
+) error {
+	// TODO: inside
+	if ok {
+		// TODO: nested
+	}
+}
+
+// TODO: after
+type T struct {
+	A int // TODO: field
+}
+`, []string{"", "func Search(...)", "func Search(...)", "", "type T"}},
+		{"Python", `class Parser:
+    def parse(self):
+        # TODO: inside method
+        pass
+
+    # TODO: in class
+# TODO: module
+`, []string{"def parse(...)", "class Parser", ""}},
+		{"Rust", `impl Display for Point {
+    fn fmt(&self) {
+        // TODO: format
+    }
+}
+`, []string{"fn fmt(...)"}},
+	}
+	for _, c := range cases {
+		f := newSymbolFinder(c.language)
+		var got []string
+		for _, line := range strings.Split(c.source, "\n") {
+			f.add([]byte(line))
+			if strings.Contains(line, "TODO") {
+				got = append(got, f.enclosing([]byte(line)))
+			}
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: expected symbols %q, got %q", c.language, c.want, got)
+		}
+	}
+}
+
+func TestNormalizeAuthors(t *testing.T) {
+	rules := []AuthorRule{
+		{Match: regexp.MustCompile(`^(jdoe|John Doe \(contractor\))$`), Name: "John Doe"},
+		{Match: regexp.MustCompile(`\[bot\]$`), Exclude: true},
+	}
+	lines := []*matchLine{
+		{n: 1, blame: &blame.LineBlame{Author: "John Doe (", FullAuthor: "John Doe (contractor)"}},
+		{n: 2, blame: &blame.LineBlame{Author: "renovate[bot]", FullAuthor: "renovate[bot]"}},
+		{n: 3, blame: &blame.LineBlame{Author: "jdoe"}},
+		{n: 4, blame: &blame.LineBlame{Author: "Ann", FullAuthor: "Ann"}},
+		{n: 5},
+	}
+	shared := lines[0].blame
+	kept := normalizeAuthors(rules, lines)
+
+	var got []string
+	for _, line := range kept {
+		author := "-"
+		if line.blame != nil {
+			author = line.blame.Author
+		}
+		got = append(got, fmt.Sprintf("%d:%s", line.n, author))
+	}
+	want := []string{"1:John Doe", "3:John Doe", "4:Ann", "5:-"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected lines %q, got %q", want, got)
+	}
+	if shared.Author != "John Doe (" {
+		t.Errorf("the original blame was modified: %q", shared.Author)
+	}
+}
+
+func TestWritePlainLine(t *testing.T) {
+	line := &matchLine{n: 1, col: 3, tag: "TODO", text: "   spaced text   "}
+	var rendered, written strings.Builder
+	line.PlainRender(&rendered, "a.py")
+	// JSON matches, collected by --copy, have the text trimmed already
+	WritePlainLine(&written, "a.py", 1, 3, "TODO", "", time.Time{}, "spaced text")
+	expected := "a.py:1:3:TODO:::spaced text\n"
+	if rendered.String() != expected || written.String() != expected {
+		t.Errorf("expected %q, got %q and %q", expected, rendered.String(), written.String())
+	}
+}
+
+func TestPatchFilter(t *testing.T) {
+	patch := make(Patch)
+	patch.Add("src/main.go", 3, "\t// TODO: added")
+	patch.Add("src/main.go", 5, "\treturn nil")
+	patch = patch.resolve("/repo")
+
+	lines := []*matchLine{
+		{n: 1, tag: "FIXME"},
+		{n: 3, tag: "TODO"},
+		{n: 5, tag: "TODO"},
+	}
+	got := patch.filter(filepath.Join("/repo", "src", "main.go"), lines)
+	if len(got) != 1 || got[0].n != 3 {
+		t.Errorf("expected only the added tagged line, got %d lines", len(got))
+	}
+	if got := patch.filter(filepath.Join("/repo", "other.go"), []*matchLine{{n: 3, tag: "TODO"}}); len(got) != 0 {
+		t.Errorf("expected no lines of files outside of the patch, got %d", len(got))
+	}
+}
+
+func TestPatchWalk(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"changed.go":   "// TODO: fix\n",
+		"unchanged.go": "// TODO: fix\n",
+		"sub/other.go": "// TODO: fix\n",
+	})
+	patch := make(Patch)
+	patch.Add("changed.go", 1, "// TODO: fix")
+	patch.Add("deleted.go", 1, "// TODO: gone")
+	patch.Add("../outside.go", 1, "// TODO: elsewhere")
+
+	params, err := NewSearchParams(Options{
+		Path:            dir,
+		Tags:            []string{"TODO"},
+		Workers:         2,
+		Style:           pretty.PlainStyle,
+		CommitAgeFilter: -1,
+		MaxFileSize:     1,
+		NoGit:           true,
+		Quiet:           true,
+		Glob:            "*",
+		Patch:           patch,
+		Output:          io.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := Search(params)
+	if stats.Total() != 1 || stats.filesScanned != 1 {
+		t.Errorf("expected only changed.go to be scanned, got %d comments in %d files", stats.Total(), stats.filesScanned)
+	}
+	if stats.filesSkipped != 0 || len(stats.Skipped()) != 0 {
+		t.Errorf("expected the files outside of the patch not to be skipped, got %d skipped", stats.filesSkipped)
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	rules := &LintRules{MinLength: 10, RequireAssignee: true, RequireIssue: true}
+	tests := []struct {
+		line     *matchLine
+		expected []string
+	}{
+		{&matchLine{tag: "TODO", text: " "}, []string{LintEmpty}},
+		{&matchLine{tag: "TODO", text: "fix"}, []string{LintShort, LintNoAssignee, LintNoIssue}},
+		{&matchLine{tag: "TODO", text: "see #12 for @bob"}, nil},
+		{&matchLine{tag: "TODO", text: "handle PROJ-42 errors", assignee: "alice"}, nil},
+		{&matchLine{tag: "FIXME", text: "crash on https://example.com/issues/7"}, []string{LintNoAssignee}},
+	}
+	for _, tt := range tests {
+		if got := rules.check(tt.line); !slices.Equal(got, tt.expected) {
+			t.Errorf("check(%q) = %v, expected %v", tt.line.text, got, tt.expected)
+		}
+	}
+
+	if got := taggedAssignee([]byte("// TODO(alice): fix"), "TODO"); got != "alice" {
+		t.Errorf("expected the assignee alice, got %q", got)
+	}
+	if got := taggedAssignee([]byte("// TODO: fix(x)"), "TODO"); got != "" {
+		t.Errorf("expected no assignee, got %q", got)
+	}
+	if got := taggedAssignee([]byte("// TODO(alice, #12): fix"), "TODO"); got != "alice" {
+		t.Errorf("expected the assignee alice without the issue, got %q", got)
+	}
+}
+
+func TestMaxFileSizes(t *testing.T) {
+	big := "# TODO: big\n" + strings.Repeat("x = 1\n", 300000)
+	dir := writeFiles(t, map[string]string{
+		"app.log":  big,
+		"main.py":  big,
+		"small.py": "# TODO: small\n",
+	})
+
+	params, err := NewSearchParams(Options{
+		Path:            dir,
+		Tags:            []string{"TODO"},
+		Workers:         2,
+		Style:           pretty.PlainStyle,
+		CommitAgeFilter: -1,
+		MaxFileSize:     1,
+		MaxFileSizes:    map[string]int64{".log": 2},
+		NoGit:           true,
+		Quiet:           true,
+		Glob:            "*",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, expected := range map[string]int64{"app.log": 2, "main.py": 1, "notes": 1, "dir.log/notes.txt": 1} {
+		if got := params.maxFileSize(filepath.Join(dir, path)); got != expected {
+			t.Errorf("maxFileSize(%s) = %d, expected %d", path, got, expected)
+		}
+	}
+
+	stats := Search(params)
+	if stats.Total() != 2 {
+		t.Errorf("expected the comments of app.log and small.py, got %d", stats.Total())
+	}
+	skipped := stats.Skipped()
+	if len(skipped) != 1 || filepath.Base(skipped[0].Path) != "main.py" || skipped[0].Reason != SkipSize {
+		t.Errorf("expected main.py to be skipped for its size, got %+v", skipped)
+	}
+}
+
+func TestDefaultExcludes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.go":                       "// TODO: fix\n",
+		"vendor/lib/lib.go":             "// TODO: fix\n",
+		"web/node_modules/pkg/index.js": "// TODO: fix\n",
+		"build/out.js":                  "// TODO: fix\n",
+		"target/debug.rs":               "// TODO: fix\n",
+	})
+
+	tests := []struct {
+		noDefaultExcludes bool
+		excludeDirs       []string
+		expected          int
+	}{
+		{false, nil, 2},
+		{true, nil, 5},
+		{false, []string{"build"}, 1},
+		// ExcludeDirs apply regardless of NoDefaultExcludes
+		{true, []string{"build"}, 4},
+	}
+	for _, tt := range tests {
+		params, err := NewSearchParams(Options{
+			Path:              dir,
+			Tags:              []string{"TODO"},
+			Workers:           2,
+			Style:             pretty.PlainStyle,
+			CommitAgeFilter:   -1,
+			MaxFileSize:       1,
+			NoGit:             true,
+			Quiet:             true,
+			Glob:              "*",
+			NoDefaultExcludes: tt.noDefaultExcludes,
+			ExcludeDirs:       tt.excludeDirs,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Search(params).Total(); got != tt.expected {
+			t.Errorf("NoDefaultExcludes=%v ExcludeDirs=%v: expected %d comments, got %d", tt.noDefaultExcludes, tt.excludeDirs, tt.expected, got)
+		}
+	}
+
+	params, err := NewSearchParams(Options{Path: filepath.Join(dir, "vendor"), Tags: []string{"TODO"}, Glob: "*", NoGit: true, CommitAgeFilter: -1, MaxFileSize: 1, Workers: 1, Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Search(params).Total(); got != 1 {
+		t.Errorf("an excluded directory is searched when it's the searched path, got %d comments", got)
+	}
+}
+
+func TestStatsFailed(t *testing.T) {
+	stats := newStats()
+	stats.skipFile("big.log", SkipSize)
+	stats.skipFile("blob.bin", SkipBinary)
+	stats.addError("new.py", ErrorBlame, fmt.Errorf("no such path in HEAD"))
+	if stats.Failed() {
+		t.Error("skipped files and failed git blame must not fail the search")
+	}
+	stats.skipFile("secret.py", SkipPermission)
+	if !stats.Failed() {
+		t.Error("files that couldn't be read must fail the search")
+	}
+
+	stats = newStats()
+	stats.addError("code.py", ErrorRead, fmt.Errorf("input/output error"))
+	if !stats.Failed() {
+		t.Error("read errors must fail the search")
+	}
+}
+
+func TestBlameTimeout(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "code.py"), []byte("# TODO: fix\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "code.py"},
+		{"-c", "user.name=Ada", "-c", "user.email=ada@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+	}
+
+	var lines []JSONMatch
+	params, err := NewSearchParams(Options{
+		Path:            dir,
+		Tags:            []string{"TODO"},
+		Workers:         1,
+		Style:           pretty.PlainStyle,
+		CommitAgeFilter: -1,
+		MaxFileSize:     1,
+		Quiet:           true,
+		Diagnostics:     io.Discard,
+		Glob:            "*",
+		BlameTimeout:    time.Nanosecond,
+		Collect:         func(m JSONMatch) { lines = append(lines, m) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := Search(params)
+	if len(lines) != 1 || lines[0].Author != "" {
+		t.Errorf("expected the comment without author, got %+v", lines)
+	}
+	errs := stats.Errors()
+	if len(errs) != 1 || errs[0].Kind != ErrorBlame || !strings.Contains(errs[0].Message, "timed out") {
+		t.Errorf("expected a blame timeout error, got %+v", errs)
+	}
+	if stats.Interrupted() || stats.Failed() {
+		t.Error("a blame timeout must not stop or fail the search")
+	}
+}
+
+func TestTimeoutReuse(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"code.py": "# TODO: fix\n"})
+	params, err := NewSearchParams(Options{
+		Path:            dir,
+		Tags:            []string{"TODO"},
+		Workers:         1,
+		Style:           pretty.PlainStyle,
+		CommitAgeFilter: -1,
+		MaxFileSize:     1,
+		NoGit:           true,
+		Quiet:           true,
+		Diagnostics:     io.Discard,
+		Output:          io.Discard,
+		Glob:            "*",
+		Timeout:         time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the deadline of a search must not be left in params once it's done
+	for i := 0; i < 2; i++ {
+		if stats := Search(params); stats.Total() != 1 || stats.Interrupted() {
+			t.Errorf("search %d: expected 1 comment, got %d", i+1, stats.Total())
+		}
+	}
+}
+
+func TestResume(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"a.py": "# TODO: first\n", "b.py": "# TODO: second\n"})
+	state := filepath.Join(t.TempDir(), "state.jsonl")
+	opts := Options{
+		Path:            dir,
+		Tags:            []string{"TODO"},
+		Workers:         2,
+		Style:           pretty.PlainStyle,
+		CommitAgeFilter: -1,
+		MaxFileSize:     1,
+		NoGit:           true,
+		Quiet:           true,
+		Glob:            "*",
+		Resume:          state,
+	}
+	// the state of a search interrupted after scanning a.py, with a recorded text that
+	// tells replayed results apart
+	interrupt := func(opts Options) {
+		c, err := loadCheckpoint(state, dir, fingerprint(opts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, "a.py")
+		_, stamp, _ := c.get(path)
+		c.add(path, stamp, []*matchLine{{n: 1, col: 3, tag: "TODO", text: "recorded"}}, 1, "")
+		c.finish(true)
+	}
+	run := func(opts Options) map[string]string {
+		texts := make(map[string]string)
+		opts.Collect = func(m JSONMatch) { texts[filepath.Base(m.Path)] = m.Text }
+		params, err := NewSearchParams(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Search(params)
+		return texts
+	}
+
+	interrupt(opts)
+	data, err := os.ReadFile(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 {
+		t.Errorf("expected the header and a line per scanned file, got %q", lines)
+	}
+	if texts := run(opts); texts["a.py"] != "recorded" || texts["b.py"] != "second" {
+		t.Errorf("expected the recorded result of a.py and the scan of b.py, got %v", texts)
+	}
+	if _, err := os.Stat(state); !os.IsNotExist(err) {
+		t.Error("expected the state to be removed once the search completes")
+	}
+
+	// files changed since the state was saved are scanned again
+	interrupt(opts)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "a.py"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if texts := run(opts); texts["a.py"] != "first" {
+		t.Errorf("expected a.py to be scanned again, got %v", texts)
+	}
+
+	// states saved with other options are discarded
+	interrupt(opts)
+	other := opts
+	other.Tags = []string{"TODO", "FIXME"}
+	if texts := run(other); texts["a.py"] != "first" {
+		t.Errorf("expected the state of other tags to be discarded, got %v", texts)
+	}
+
+	interrupt(opts)
+	if _, err := loadCheckpoint(state, t.TempDir(), fingerprint(opts)); err == nil {
+		t.Error("expected error for the state of a search of another path")
+	}
+}
@@ -0,0 +1,96 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/mathpn/listme/i18n"
+)
+
+// Reasons why a file couldn't be scanned.
+const (
+	SkipPermission = "permission"
+	SkipUnreadable = "unreadable"
+	SkipSize       = "size"
+	SkipBinary     = "binary"
+	SkipEncoding   = "encoding"
+)
+
+var skipReasons = []string{SkipPermission, SkipUnreadable, SkipSize, SkipBinary, SkipEncoding}
+
+var skipDescriptions = map[string]string{
+	SkipPermission: "permission denied",
+	SkipUnreadable: "unreadable",
+	SkipSize:       "larger than the size limit",
+	SkipBinary:     "not a text file",
+	SkipEncoding:   "unsupported encoding",
+}
+
+// maximum number of paths listed per reason in the skipped files summary
+const maxSkippedListed = 10
+
+// SkippedFile is a file that couldn't be scanned. Line is the line where the text
+// detection gave up, for files skipped as binary or with an unsupported encoding.
+type SkippedFile struct {
+	Path   string
+	Reason string
+	Line   int
+}
+
+// Kinds of errors found while searching a file. Files that couldn't be scanned at all
+// are only recorded as skipped, see SkippedFile.
+//   - ErrorBlame: git blame failed, so author information is missing
+//   - ErrorRead: reading failed midway, so results may be incomplete
+const (
+	ErrorBlame = "blame"
+	ErrorRead  = "read"
+)
+
+// FileError is an error found while searching a file.
+type FileError struct {
+	Path    string
+	Kind    string
+	Message string
+}
+
+func readErrorReason(err error) string {
+	if errors.Is(err, fs.ErrPermission) {
+		return SkipPermission
+	}
+	return SkipUnreadable
+}
+
+// renderSkipped writes a summary of the skipped files grouped by reason to w.
+// The path of each file is formatted with displayPath. With the skip report, every
+// file skipped by the text detection is listed, with the line where it gave up.
+func renderSkipped(w io.Writer, skipped []SkippedFile, displayPath func(string) string, skipReport bool) {
+	if len(skipped) == 0 {
+		return
+	}
+	byReason := make(map[string][]string, len(skipReasons))
+	for _, f := range skipped {
+		path := displayPath(f.Path)
+		if skipReport && f.Line > 0 {
+			path += " " + i18n.Sprintf("(line %d)", f.Line)
+		}
+		byReason[f.Reason] = append(byReason[f.Reason], path)
+	}
+
+	fmt.Fprintln(w, i18n.Sprintf("skipped %d files:", len(skipped)))
+	for _, reason := range skipReasons {
+		paths := byReason[reason]
+		if len(paths) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  %s (%d):\n", i18n.T(skipDescriptions[reason]), len(paths))
+		for i, path := range paths {
+			if i == maxSkippedListed && !(skipReport && (reason == SkipBinary || reason == SkipEncoding)) {
+				fmt.Fprintln(w, i18n.Sprintf("    … and %d more", len(paths)-maxSkippedListed))
+				break
+			}
+			fmt.Fprintf(w, "    %s\n", path)
+		}
+	}
+}
@@ -0,0 +1,698 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mathpn/listme/i18n"
+	"github.com/mathpn/listme/pretty"
+)
+
+// Stats aggregates counters collected during a search.
+// It's safe for concurrent use.
+type Stats struct {
+	mu           sync.Mutex
+	start        time.Time
+	tags         map[string]int
+	extensions   map[string]map[string]int
+	dirs         map[string]int
+	extLines     map[string]int
+	dirLines     map[string]int
+	filesScanned int
+	filesSkipped int
+	filesMatched int
+	skipped      []SkippedFile
+	owners       map[string]*ownerTotals   // nil unless grouped by owner
+	mixed        map[string]map[string]int // nil unless co-occurrence is tracked
+	lint         map[string]int
+	lintFlagged  int
+	bySeverity   bool
+	errors       []FileError
+	elapsed      time.Duration
+	interrupted  bool
+}
+
+func newStats() *Stats {
+	return &Stats{
+		start:      time.Now(),
+		tags:       make(map[string]int, 10),
+		extensions: make(map[string]map[string]int),
+		dirs:       make(map[string]int),
+		extLines:   make(map[string]int),
+		dirLines:   make(map[string]int),
+		lint:       make(map[string]int),
+	}
+}
+
+// fileExtension returns the extension used to group statistics.
+// Files without extension are grouped under "(none)".
+func fileExtension(path string) string {
+	ext := filepath.Ext(path)
+	if ext == "" {
+		return "(none)"
+	}
+	return ext
+}
+
+// statsDir returns the directory of the file relative to the searched path,
+// used to group statistics. Files directly in the searched path are grouped under ".".
+func statsDir(rootPath, path string) string {
+	dir, err := filepath.Rel(rootPath, filepath.Dir(path))
+	if err != nil || strings.HasPrefix(dir, "..") {
+		return "."
+	}
+	return filepath.ToSlash(dir)
+}
+
+// addScanned records a scanned file and its number of lines.
+func (s *Stats) addScanned(rootPath, path string, lines int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filesScanned++
+	s.extLines[fileExtension(path)] += lines
+	s.dirLines[statsDir(rootPath, path)] += lines
+}
+
+func (s *Stats) addSkipped() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filesSkipped++
+}
+
+// skipFile records a file that couldn't be scanned.
+func (s *Stats) skipFile(path, reason string) {
+	s.skipFileAt(path, reason, 0)
+}
+
+// skipFileAt records a file that couldn't be scanned past a line, see SkippedFile.
+func (s *Stats) skipFileAt(path, reason string, line int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filesSkipped++
+	s.skipped = append(s.skipped, SkippedFile{Path: path, Reason: reason, Line: line})
+}
+
+// addError records an error found while searching a file.
+func (s *Stats) addError(path, kind string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, FileError{Path: path, Kind: kind, Message: err.Error()})
+}
+
+// Errors returns the errors found during the search, sorted by path.
+func (s *Stats) Errors() []FileError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := make([]FileError, len(s.errors))
+	copy(errs, s.errors)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+// Skipped returns the files that couldn't be scanned, sorted by path.
+// Files ignored due to .gitignore or glob patterns are not included.
+func (s *Stats) Skipped() []SkippedFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	skipped := make([]SkippedFile, len(s.skipped))
+	copy(skipped, s.skipped)
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Path < skipped[j].Path })
+	return skipped
+}
+
+func (s *Stats) addResult(r *searchResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ext := fileExtension(r.path)
+	counter, ok := s.extensions[ext]
+	if !ok {
+		counter = make(map[string]int)
+		s.extensions[ext] = counter
+	}
+	for _, line := range r.lines {
+		s.tags[line.tag]++
+		counter[line.tag]++
+		for _, issue := range line.lint {
+			s.lint[issue]++
+		}
+		if len(line.lint) > 0 {
+			s.lintFlagged++
+		}
+	}
+	if len(r.lines) > 0 {
+		s.filesMatched++
+	}
+	s.dirs[statsDir(r.rootPath, r.path)] += len(r.lines)
+}
+
+// ownerTotals are the matches of the files of an owner, with the sum of their ages
+// for the ones whose commit date is known.
+type ownerTotals struct {
+	tags  map[string]int
+	age   time.Duration
+	dated int
+}
+
+// addOwned records the matches of a file by owner, under "(none)" if it has no owners.
+func (s *Stats) addOwned(r *searchResult, owners []string) {
+	if len(owners) == 0 {
+		owners = []string{"(none)"}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, owner := range owners {
+		totals, ok := s.owners[owner]
+		if !ok {
+			totals = &ownerTotals{tags: make(map[string]int)}
+			s.owners[owner] = totals
+		}
+		for _, line := range r.lines {
+			totals.tags[line.tag]++
+			if line.blame != nil && !line.blame.Time.IsZero() {
+				totals.age += s.start.Sub(line.blame.Time)
+				totals.dated++
+			}
+		}
+	}
+}
+
+// OwnerStats are the matches of the files of a CODEOWNERS owner. AverageAge is the
+// average age of the matches whose commit date is known, zero if none is known.
+type OwnerStats struct {
+	Owner      string
+	Tags       map[string]int
+	Matches    int
+	AverageAge time.Duration
+}
+
+// Owners returns the matches by owner, from the owner with most matches, if they
+// were grouped by owner. Files without owners are grouped under "(none)".
+func (s *Stats) Owners() []OwnerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]OwnerStats, 0, len(s.owners))
+	for owner, totals := range s.owners {
+		o := OwnerStats{Owner: owner, Tags: make(map[string]int, len(totals.tags))}
+		for tag, count := range totals.tags {
+			o.Tags[tag] = count
+			o.Matches += count
+		}
+		if totals.dated > 0 {
+			o.AverageAge = totals.age / time.Duration(totals.dated)
+		}
+		out = append(out, o)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Matches != out[j].Matches {
+			return out[i].Matches > out[j].Matches
+		}
+		return out[i].Owner < out[j].Owner
+	})
+	return out
+}
+
+func (s *Stats) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.elapsed = time.Since(s.start)
+}
+
+// interrupt records that the search was stopped before every file was scanned.
+func (s *Stats) interrupt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interrupted = true
+}
+
+// Interrupted reports whether the search was stopped before every file was scanned,
+// so the results are incomplete.
+func (s *Stats) Interrupted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.interrupted
+}
+
+// Failed reports whether files couldn't be read, due to permissions or I/O errors, so
+// the results are incomplete. Files skipped on purpose, e.g. above the size limit or
+// binary, and failed git blame, which only leaves comments without author, are not failures.
+func (s *Stats) Failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.errors {
+		if e.Kind == ErrorRead {
+			return true
+		}
+	}
+	for _, f := range s.skipped {
+		if f.Reason == SkipPermission || f.Reason == SkipUnreadable {
+			return true
+		}
+	}
+	return false
+}
+
+// blameFailures returns the number of files that git blame failed for.
+func (s *Stats) blameFailures() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for _, e := range s.errors {
+		if e.Kind == ErrorBlame {
+			n++
+		}
+	}
+	return n
+}
+
+// Tags returns a copy of the number of matches per tag.
+func (s *Stats) Tags() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tags := make(map[string]int, len(s.tags))
+	for tag, count := range s.tags {
+		tags[tag] = count
+	}
+	return tags
+}
+
+// Total returns the total number of matches.
+func (s *Stats) Total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for _, count := range s.tags {
+		total += count
+	}
+	return total
+}
+
+// Lint returns a copy of the number of comments with each lint issue, see LintRules,
+// and the number of comments with at least one.
+func (s *Stats) Lint() (issues map[string]int, flagged int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issues = make(map[string]int, len(s.lint))
+	for issue, count := range s.lint {
+		issues[issue] = count
+	}
+	return issues, s.lintFlagged
+}
+
+// Extensions returns a copy of the number of matches per tag for each file extension.
+func (s *Stats) Extensions() map[string]map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	extensions := make(map[string]map[string]int, len(s.extensions))
+	for ext, counter := range s.extensions {
+		extensions[ext] = make(map[string]int, len(counter))
+		for tag, count := range counter {
+			extensions[ext][tag] = count
+		}
+	}
+	return extensions
+}
+
+// Density is the number of matches per 1000 scanned lines of a group of files.
+type Density struct {
+	Name    string
+	Matches int
+	Lines   int
+}
+
+// PerKLOC returns the number of matches per 1000 lines.
+func (d Density) PerKLOC() float64 {
+	if d.Lines == 0 {
+		return 0
+	}
+	return 1000 * float64(d.Matches) / float64(d.Lines)
+}
+
+// ExtensionDensity returns the density of matches of every file extension with matches,
+// from the densest one.
+func (s *Stats) ExtensionDensity() []Density {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matches := make(map[string]int, len(s.extensions))
+	for ext, counter := range s.extensions {
+		for _, count := range counter {
+			matches[ext] += count
+		}
+	}
+	return densities(matches, s.extLines)
+}
+
+// DirectoryDensity returns the density of matches of every directory with matches,
+// relative to the searched path, from the densest one. Subdirectories are not included
+// in the numbers of their parents.
+func (s *Stats) DirectoryDensity() []Density {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return densities(s.dirs, s.dirLines)
+}
+
+func densities(matches, lines map[string]int) []Density {
+	out := make([]Density, 0, len(matches))
+	for name, count := range matches {
+		if count == 0 {
+			continue
+		}
+		out = append(out, Density{Name: name, Matches: count, Lines: lines[name]})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].PerKLOC() != out[j].PerKLOC() {
+			return out[i].PerKLOC() > out[j].PerKLOC()
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func jsonDensity(densities []Density) map[string]JSONDensity {
+	out := make(map[string]JSONDensity, len(densities))
+	for _, d := range densities {
+		out[d.Name] = JSONDensity{Matches: d.Matches, Lines: d.Lines, PerKLOC: d.PerKLOC()}
+	}
+	return out
+}
+
+func jsonOwners(owners []OwnerStats) map[string]JSONOwner {
+	if len(owners) == 0 {
+		return nil
+	}
+	out := make(map[string]JSONOwner, len(owners))
+	for _, o := range owners {
+		out[o.Owner] = JSONOwner{Matches: o.Matches, Tags: o.Tags, AverageAgeDays: o.AverageAge.Hours() / 24}
+	}
+	return out
+}
+
+func (s *Stats) jsonStats() *JSONStats {
+	s.mu.Lock()
+	elapsed := s.elapsed
+	scanned, skipped := s.filesScanned, s.filesSkipped
+	s.mu.Unlock()
+	lint, flagged := s.Lint()
+	if flagged == 0 {
+		lint = nil
+	}
+	return &JSONStats{
+		FilesScanned:     scanned,
+		FilesSkipped:     skipped,
+		ElapsedMs:        elapsed.Milliseconds(),
+		Tags:             s.Tags(),
+		Extensions:       s.Extensions(),
+		ExtensionDensity: jsonDensity(s.ExtensionDensity()),
+		DirectoryDensity: jsonDensity(s.DirectoryDensity()),
+		Owners:           jsonOwners(s.Owners()),
+		CoOccurrence:     jsonCoOccurrence(s.CoOccurrence()),
+		MixedFiles:       jsonMixedFiles(s.MixedFiles()),
+		Lint:             lint,
+		LintFlagged:      flagged,
+	}
+}
+
+// renderSummary writes a one-line summary of the search for humans, such as
+//
+//	listme: 142 comments in 37 files, 3 errors
+func (s *Stats) renderSummary(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for _, count := range s.tags {
+		total += count
+	}
+	comments := i18n.Sprintf("%d comments", total)
+	if total == 1 {
+		comments = i18n.T("1 comment")
+	}
+	files := i18n.Sprintf("%d files", s.filesMatched)
+	if s.filesMatched == 1 {
+		files = i18n.T("1 file")
+	}
+	summary := i18n.Sprintf("listme: %s in %s", comments, files)
+	switch len(s.errors) {
+	case 0:
+	case 1:
+		summary += i18n.T(", 1 error")
+	default:
+		summary += i18n.Sprintf(", %d errors", len(s.errors))
+	}
+	fmt.Fprintln(w, summary)
+}
+
+// render writes the end-of-run totals to w using the provided style.
+//
+// The plain style uses a single line with the format
+//
+//	# stats: files_scanned=12 files_skipped=3 elapsed=1.234s BUG=1 TODO=4
+//
+// followed by lint=N and lint_<issue>=N fields if comments were flagged by lint rules.
+func (s *Stats) render(w io.Writer, width int, style pretty.Style) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags := make([]string, 0, len(s.tags))
+	for tag := range s.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	elapsed := s.elapsed.Round(time.Millisecond)
+	switch style {
+	case pretty.PlainStyle:
+		fields := []string{
+			fmt.Sprintf("files_scanned=%d", s.filesScanned),
+			fmt.Sprintf("files_skipped=%d", s.filesSkipped),
+			fmt.Sprintf("elapsed=%s", elapsed),
+		}
+		for _, tag := range tags {
+			fields = append(fields, fmt.Sprintf("%s=%d", tag, s.tags[tag]))
+		}
+		if s.bySeverity {
+			severities := pretty.CountSeverities(s.tags)
+			for severity := pretty.SeverityError; severity >= pretty.SeverityInfo; severity-- {
+				fields = append(fields, fmt.Sprintf("%s=%d", severity, severities[severity]))
+			}
+		}
+		if s.lintFlagged > 0 {
+			fields = append(fields, fmt.Sprintf("lint=%d", s.lintFlagged))
+			for _, issue := range LintIssues {
+				if count := s.lint[issue]; count > 0 {
+					fields = append(fields, fmt.Sprintf("lint_%s=%d", issue, count))
+				}
+			}
+		}
+		fmt.Fprintf(w, "# stats: %s\n", strings.Join(fields, " "))
+	default:
+		fmt.Fprintln(w, pretty.Bold(i18n.Sprintf(
+			"Scanned %d files (%d skipped) in %s", s.filesScanned, s.filesSkipped, elapsed,
+		)))
+		switch {
+		case len(s.tags) > 0 && s.bySeverity:
+			pretty.RenderSeveritySummary(w, width, s.tags, style)
+		case len(s.tags) > 0:
+			pretty.RenderSummary(w, width, s.tags, style)
+		}
+		if s.lintFlagged > 0 {
+			counts := make([]string, 0, len(LintIssues))
+			for _, issue := range LintIssues {
+				if count := s.lint[issue]; count > 0 {
+					counts = append(counts, fmt.Sprintf("%d %s", count, i18n.T(lintLabels[issue])))
+				}
+			}
+			fmt.Fprintln(w, pretty.Underline(i18n.Sprintf("%d comments flagged by lint rules", s.lintFlagged))+" ("+strings.Join(counts, ", ")+")")
+		}
+	}
+}
+
+// RenderReport prints the totals followed by a breakdown of matches per file extension
+// and per directory, or per owner or pair of tags if they were tracked instead.
+// Extensions are sorted by number of matches. The share of each tag found in every
+// extension is shown in parentheses. Densities are matches per 1000 scanned lines
+// (kLOC), so large packages can be compared with small ones.
+//
+// The plain style uses one line per extension, then one per directory, with the format
+//
+//	.go total=12 lines=3400 density=3.53 BUG=1 TODO=11
+//	dir=search total=8 lines=2000 density=4.00
+//
+// Machine-readable formats print a single JSONLRecord of type StatsRecord.
+func (s *Stats) RenderReport(style pretty.Style, format Format) {
+	if format != TextFormat {
+		record := JSONLRecord{SchemaVersion: SchemaVersion, Type: StatsRecord, Stats: s.jsonStats()}
+		if err := json.NewEncoder(stdout).Encode(record); err != nil {
+			slog.Error("failed to encode JSON output", "error", err)
+		}
+		return
+	}
+	var width int
+	if style != pretty.PlainStyle {
+		width = getLimitedWidth()
+	}
+	var b strings.Builder
+	s.render(&b, width, style)
+	switch {
+	case s.owners != nil:
+		s.renderOwners(&b, style)
+	case s.mixed != nil:
+		s.renderCoOccurrence(&b, style)
+	default:
+		s.renderExtensions(&b, style)
+		s.renderDirectories(&b, style)
+	}
+	io.WriteString(stdout, b.String())
+}
+
+func (s *Stats) renderOwners(w io.Writer, style pretty.Style) {
+	owners := s.Owners()
+	if len(owners) == 0 {
+		return
+	}
+
+	maxOwnerLen := 0
+	for _, o := range owners {
+		maxOwnerLen = max(maxOwnerLen, len(o.Owner))
+	}
+
+	if style != pretty.PlainStyle {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, pretty.Bold(i18n.T("By owner")))
+	}
+	for _, o := range owners {
+		tags := make([]string, 0, len(o.Tags))
+		for tag := range o.Tags {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		days := int(o.AverageAge.Hours() / 24)
+
+		switch style {
+		case pretty.PlainStyle:
+			fields := []string{
+				"owner=" + o.Owner,
+				fmt.Sprintf("total=%d", o.Matches),
+				fmt.Sprintf("average_age_days=%d", days),
+			}
+			for _, tag := range tags {
+				fields = append(fields, fmt.Sprintf("%s=%d", tag, o.Tags[tag]))
+			}
+			fmt.Fprintln(w, strings.Join(fields, " "))
+		default:
+			row := fmt.Sprintf("  %-*s %5d  %s ", maxOwnerLen, o.Owner, o.Matches, i18n.Sprintf("average age: %d days", days))
+			for _, tag := range tags {
+				tagStr := fmt.Sprintf(" %s %d ", pretty.Emojify(tag), o.Tags[tag])
+				row += pretty.Colorize(tagStr, tag, style)
+			}
+			fmt.Fprintln(w, row)
+		}
+	}
+}
+
+func (s *Stats) renderExtensions(w io.Writer, style pretty.Style) {
+	extensions := s.Extensions()
+	density := make(map[string]float64, len(extensions))
+	lines := make(map[string]int, len(extensions))
+	for _, d := range s.ExtensionDensity() {
+		density[d.Name] = d.PerKLOC()
+		lines[d.Name] = d.Lines
+	}
+	tagTotals := s.Tags()
+	total := s.Total()
+	if total == 0 {
+		return
+	}
+
+	extTotals := make(map[string]int, len(extensions))
+	exts := make([]string, 0, len(extensions))
+	for ext, counter := range extensions {
+		for _, count := range counter {
+			extTotals[ext] += count
+		}
+		exts = append(exts, ext)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if extTotals[exts[i]] == extTotals[exts[j]] {
+			return exts[i] < exts[j]
+		}
+		return extTotals[exts[i]] > extTotals[exts[j]]
+	})
+
+	maxExtLen := 0
+	for _, ext := range exts {
+		if len(ext) > maxExtLen {
+			maxExtLen = len(ext)
+		}
+	}
+
+	if style != pretty.PlainStyle {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, pretty.Bold(i18n.T("By extension")))
+	}
+	for _, ext := range exts {
+		counter := extensions[ext]
+		tags := make([]string, 0, len(counter))
+		for tag := range counter {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+
+		switch style {
+		case pretty.PlainStyle:
+			fields := []string{
+				ext,
+				fmt.Sprintf("total=%d", extTotals[ext]),
+				fmt.Sprintf("lines=%d", lines[ext]),
+				fmt.Sprintf("density=%.2f", density[ext]),
+			}
+			for _, tag := range tags {
+				fields = append(fields, fmt.Sprintf("%s=%d", tag, counter[tag]))
+			}
+			fmt.Fprintln(w, strings.Join(fields, " "))
+		default:
+			share := 100 * float64(extTotals[ext]) / float64(total)
+			row := fmt.Sprintf(
+				"  %-*s %5d (%5.1f%%) %6.1f/kLOC ", maxExtLen, ext, extTotals[ext], share, density[ext],
+			)
+			for _, tag := range tags {
+				tagShare := 100 * float64(counter[tag]) / float64(tagTotals[tag])
+				tagStr := fmt.Sprintf(" %s %d (%.0f%%) ", pretty.Emojify(tag), counter[tag], tagShare)
+				row += pretty.Colorize(tagStr, tag, style)
+			}
+			fmt.Fprintln(w, row)
+		}
+	}
+}
+
+func (s *Stats) renderDirectories(w io.Writer, style pretty.Style) {
+	dirs := s.DirectoryDensity()
+	if len(dirs) == 0 {
+		return
+	}
+
+	maxDirLen := 0
+	for _, d := range dirs {
+		if len(d.Name) > maxDirLen {
+			maxDirLen = len(d.Name)
+		}
+	}
+
+	if style != pretty.PlainStyle {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, pretty.Bold(i18n.T("By directory")))
+	}
+	for _, d := range dirs {
+		switch style {
+		case pretty.PlainStyle:
+			fmt.Fprintf(w, "dir=%s total=%d lines=%d density=%.2f\n", d.Name, d.Matches, d.Lines, d.PerKLOC())
+		default:
+			fmt.Fprintf(w, "  %-*s %5d %6.1f/kLOC\n", maxDirLen, d.Name, d.Matches, d.PerKLOC())
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package search
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// symbolPattern finds a definition in a line. The first group of regex captures
+// the name of the symbol, which replaces %s in format.
+type symbolPattern struct {
+	regex  *regexp.Regexp
+	format string
+}
+
+func symbol(expr, format string) symbolPattern {
+	return symbolPattern{regex: regexp.MustCompile(expr), format: format}
+}
+
+var (
+	classPattern    = symbol(`^\s*(?:(?:export|public|private|protected|internal|abstract|final|sealed|static|data|open)\s+)*class\s+(\w+)`, "class %s")
+	functionPattern = symbol(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)`, "function %s(...)")
+)
+
+// symbolPatterns are cheap heuristics that find the definitions of functions and
+// types, by language. They only look at one line at a time, so multi-line signatures
+// are found by their first line.
+var symbolPatterns = map[string][]symbolPattern{
+	"Go": {
+		symbol(`^\s*func\s+(?:\([^)]*\)\s*)?(\w+)`, "func %s(...)"),
+		symbol(`^\s*type\s+(\w+)\s+(?:struct|interface)\b`, "type %s"),
+	},
+	"Python": {
+		symbol(`^\s*(?:async\s+)?def\s+(\w+)`, "def %s(...)"),
+		symbol(`^\s*class\s+(\w+)`, "class %s"),
+	},
+	"JavaScript": {
+		functionPattern,
+		classPattern,
+		symbol(`^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*=>|\w+\s*=>)`, "%s(...)"),
+	},
+	"TypeScript": {
+		functionPattern,
+		classPattern,
+		symbol(`^\s*(?:export\s+)?(?:interface|enum)\s+(\w+)`, "%s"),
+		symbol(`^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|\w+\s*=>)`, "%s(...)"),
+	},
+	"Java": {
+		classPattern,
+		symbol(`^\s*(?:(?:public|private|protected|static|final|abstract|synchronized)\s+)+[\w<>\[\],.? ]+\s+(\w+)\s*\([^;]*$`, "%s(...)"),
+	},
+	"C#": {
+		classPattern,
+		symbol(`^\s*(?:(?:public|private|protected|internal|static|virtual|override|async|sealed)\s+)+[\w<>\[\],.? ]+\s+(\w+)\s*\([^;]*$`, "%s(...)"),
+	},
+	"Kotlin": {
+		classPattern,
+		symbol(`^\s*(?:\w+\s+)*fun\s+(?:<[^>]*>\s*)?(?:\w+\.)?(\w+)`, "fun %s(...)"),
+	},
+	"Scala": {
+		symbol(`^\s*(?:\w+\s+)*(?:class|object|trait)\s+(\w+)`, "%s"),
+		symbol(`^\s*(?:\w+\s+)*def\s+(\w+)`, "def %s(...)"),
+	},
+	"Swift": {
+		symbol(`^\s*(?:\w+\s+)*(?:class|struct|enum|protocol|extension)\s+(\w+)`, "%s"),
+		symbol(`^\s*(?:\w+\s+)*func\s+(\w+)`, "func %s(...)"),
+	},
+	"Rust": {
+		symbol(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(\w+)`, "fn %s(...)"),
+		symbol(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:struct|enum|trait)\s+(\w+)`, "%s"),
+		symbol(`^\s*impl(?:<[^>]*>)?\s+(?:\w+\s+for\s+)?(\w+)`, "impl %s"),
+	},
+	"C": {
+		symbol(`^(?:[\w*]+\s+)+\**(\w+)\s*\([^;]*$`, "%s(...)"),
+	},
+	"C++": {
+		classPattern,
+		symbol(`^\s*(?:struct|namespace)\s+(\w+)\s*\{?\s*$`, "%s"),
+		symbol(`^(?:[\w*&:<>,]+\s+)+[*&]*((?:\w+::)*~?\w+)\s*\([^;]*$`, "%s(...)"),
+	},
+	"PHP": {
+		classPattern,
+		symbol(`^\s*(?:(?:public|private|protected|static|abstract|final)\s+)*function\s+(\w+)`, "function %s(...)"),
+	},
+	"Ruby": {
+		symbol(`^\s*(?:class|module)\s+([\w:]+)`, "%s"),
+		symbol(`^\s*def\s+((?:self\.)?\w+[?!=]?)`, "def %s"),
+	},
+	"Shell": {
+		symbol(`^\s*(?:function\s+)?([\w-]+)\s*\(\)`, "%s()"),
+		symbol(`^\s*function\s+([\w-]+)`, "%s()"),
+	},
+	"Lua": {
+		symbol(`^\s*(?:local\s+)?function\s+([\w.:]+)`, "function %s(...)"),
+	},
+	"Elixir": {
+		symbol(`^\s*defmodule\s+([\w.]+)`, "%s"),
+		symbol(`^\s*defp?\s+(\w+[?!]?)`, "def %s"),
+	},
+}
+
+// scope is a definition and the indentation of its line.
+type scope struct {
+	indent int
+	name   string
+}
+
+// symbolFinder tracks the definitions of a file as it's read, line by line, to
+// find the function or type enclosing each tagged comment. A definition encloses
+// the following lines that are more indented than itself, up to the first line that
+// isn't, which holds for both indentation-based and brace-based languages as
+// commonly formatted.
+type symbolFinder struct {
+	patterns []symbolPattern
+	scopes   []scope
+}
+
+// newSymbolFinder returns a symbolFinder for the language, or nil if there are no
+// heuristics for it.
+func newSymbolFinder(language string) *symbolFinder {
+	patterns, ok := symbolPatterns[language]
+	if !ok {
+		return nil
+	}
+	return &symbolFinder{patterns: patterns}
+}
+
+// add reads the next line of the file, closing the scopes it ends and recording
+// its definition, if any.
+func (f *symbolFinder) add(line []byte) {
+	trimmed := bytes.TrimSpace(line)
+	// the closing parentheses of multi-line signatures don't end the definition
+	if len(trimmed) == 0 || trimmed[0] == ')' || trimmed[0] == ']' {
+		return
+	}
+	indent := indentation(line)
+	for len(f.scopes) > 0 && f.scopes[len(f.scopes)-1].indent >= indent {
+		f.scopes = f.scopes[:len(f.scopes)-1]
+	}
+	for _, p := range f.patterns {
+		if match := p.regex.FindSubmatch(line); match != nil {
+			f.scopes = append(f.scopes, scope{indent: indent, name: strings.Replace(p.format, "%s", string(match[1]), 1)})
+			return
+		}
+	}
+}
+
+// enclosing returns the innermost definition enclosing the last line read, or an
+// empty string. A definition doesn't enclose its own line.
+func (f *symbolFinder) enclosing(line []byte) string {
+	indent := indentation(line)
+	for i := len(f.scopes) - 1; i >= 0; i-- {
+		if f.scopes[i].indent < indent {
+			return f.scopes[i].name
+		}
+	}
+	return ""
+}
+
+// indentation returns the width of the leading whitespace of a line, counting tabs as 4 spaces.
+func indentation(line []byte) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
@@ -0,0 +1,96 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Search phases reported by timings.
+const (
+	phaseGitignore = "gitignore loading"
+	phaseWalk      = "walk"
+	phaseScan      = "scanning"
+	phaseBlame     = "blame"
+	phaseRender    = "rendering"
+)
+
+var phases = []string{phaseGitignore, phaseWalk, phaseScan, phaseBlame, phaseRender}
+
+// number of slowest files listed in the report
+const slowestFiles = 10
+
+type fileTiming struct {
+	path     string
+	duration time.Duration
+}
+
+// timings collects the cumulative duration of each search phase and the time
+// spent on each file. Phases running on multiple workers add up, so their sum
+// may exceed the elapsed time. All methods are no-ops on a nil *timings.
+type timings struct {
+	mu     sync.Mutex
+	phases map[string]time.Duration
+	files  []fileTiming
+}
+
+func newTimings() *timings {
+	return &timings{phases: make(map[string]time.Duration, len(phases))}
+}
+
+func (t *timings) add(phase string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phases[phase] += d
+}
+
+// since adds the time elapsed since start to the phase.
+func (t *timings) since(phase string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.add(phase, time.Since(start))
+}
+
+func (t *timings) addFile(path string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files = append(t.files, fileTiming{path: path, duration: d})
+}
+
+// Render writes the per-phase durations and the slowest files to w.
+func (t *timings) Render(w io.Writer, elapsed time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprintf(w, "timings (total %s):\n", elapsed.Round(time.Microsecond))
+	for _, phase := range phases {
+		fmt.Fprintf(w, "  %-18s %s\n", phase, t.phases[phase].Round(time.Microsecond))
+	}
+
+	sort.Slice(t.files, func(i, j int) bool {
+		return t.files[i].duration > t.files[j].duration
+	})
+	n := len(t.files)
+	if n > slowestFiles {
+		n = slowestFiles
+	}
+	if n == 0 {
+		return
+	}
+	fmt.Fprintf(w, "slowest files:\n")
+	for _, f := range t.files[:n] {
+		fmt.Fprintf(w, "  %12s  %s\n", f.duration.Round(time.Microsecond), f.path)
+	}
+}
@@ -0,0 +1,27 @@
+package search
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// lockedWriter serializes writes, so each Write call reaches the
+// underlying writer as a whole, without interleaving with other goroutines.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// Writers used by the package. Reports are built in a buffer and
+// written with a single call, so a file's report is never split.
+var (
+	stdout io.Writer = &lockedWriter{w: os.Stdout}
+	stderr io.Writer = &lockedWriter{w: os.Stderr}
+)
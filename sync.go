@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/edit"
+	"github.com/mathpn/listme/pretty"
+	"github.com/mathpn/listme/search"
+	"github.com/mathpn/listme/tracker"
+)
+
+// trackers lists the accepted issue trackers of the sync command.
+var trackers = []string{"github", "gitea", "forgejo"}
+
+func runSync(args []string) {
+	parser := argparse.NewParser("listme sync", "Create an issue for each tagged comment that doesn't have one yet.")
+	backend := parser.SelectorPositional(trackers, &argparse.Options{Help: "Issue tracker: github, gitea or forgejo"})
+	flags := addSearchFlags(parser)
+	repo := parser.String("", "repo", &argparse.Options{Help: "Repository with the format owner/repo. Defaults to GITHUB_REPOSITORY for GitHub, or the origin remote"})
+	rewrite := parser.Flag("", "rewrite", &argparse.Options{Help: "Add the issue reference to the source comments, e.g. TODO(#123): text"})
+	yes := parser.Flag("y", "yes", &argparse.Options{Help: "Do not ask for confirmation"})
+	status := parser.Flag("", "status", &argparse.Options{Help: "Also set a commit status on HEAD with the number of tagged comments. It fails if tags listed in fail_on of the configuration file are found"})
+	dryRun := parser.Flag("", "dry-run", &argparse.Options{Help: "List the issues that would be created and print the diff of --rewrite without changing anything. New issues are referenced as #?"})
+	parseArgs(parser, args)
+
+	var matches []search.JSONMatch
+	opts := flags.options()
+	opts.FullPath = true
+	opts.Quiet = true
+	opts.Collect = func(m search.JSONMatch) {
+		matches = append(matches, m)
+	}
+	params, err := search.NewSearchParams(opts)
+	if err != nil {
+		fatal(err)
+	}
+	requireComplete(search.Search(params))
+
+	var t tracker.Tracker
+	switch *backend {
+	case "github":
+		t, err = tracker.NewGitHub(*repo, *flags.path)
+	case "gitea", "forgejo":
+		t, err = tracker.NewGitea(*repo, *flags.path)
+	default:
+		err = fmt.Errorf("unknown issue tracker: %s", *backend)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	if *status {
+		setStatus(t, matches, flags.config.FailOn, *flags.path, *dryRun)
+	}
+
+	existing, err := t.Issues()
+	if err != nil {
+		fatal(err)
+	}
+	byTitle := make(map[string]*tracker.Issue, len(existing))
+	for _, issue := range existing {
+		byTitle[issue.Title] = issue
+	}
+
+	var pending []search.JSONMatch
+	for _, m := range matches {
+		linked, err := edit.Linked(m.Path, m.Line, m.Column, m.Tag)
+		if err != nil {
+			fatal(err)
+		}
+		if linked {
+			continue
+		}
+		if _, ok := byTitle[issueTitle(m)]; ok && !*rewrite {
+			continue
+		}
+		pending = append(pending, m)
+	}
+	if len(pending) == 0 {
+		fmt.Fprintln(os.Stderr, "all tagged comments are already synced")
+		return
+	}
+
+	for _, m := range pending {
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", relPath(m.Path), m.Line, issueTitle(m))
+	}
+	if *dryRun {
+		if *rewrite {
+			previewLinks(pending, byTitle, opts.Style)
+		}
+		return
+	}
+	if !*yes && !confirm(fmt.Sprintf("sync %d comment(s)?", len(pending))) {
+		fmt.Fprintln(os.Stderr, "aborted")
+		return
+	}
+
+	for _, m := range pending {
+		title := issueTitle(m)
+		issue, ok := byTitle[title]
+		if !ok {
+			issue, err = t.CreateIssue(title, issueBody(m))
+			if err != nil {
+				fatal(err)
+			}
+			byTitle[title] = issue
+			fmt.Fprintf(os.Stderr, "created %s %s\n", issue.Ref(), issue.URL)
+		}
+		if !*rewrite {
+			continue
+		}
+		changed, err := edit.LinkIssue(m.Path, m.Line, m.Column, m.Tag, issue.Ref())
+		if err != nil {
+			fatal(err)
+		}
+		if changed {
+			fmt.Fprintf(os.Stderr, "linked %s:%d to %s\n", relPath(m.Path), m.Line, issue.Ref())
+		}
+	}
+}
+
+// previewLinks prints the diff of linking the comments to their issues.
+// Issues that don't exist yet are referenced as #?.
+func previewLinks(matches []search.JSONMatch, byTitle map[string]*tracker.Issue, style pretty.Style) {
+	links := make(map[string][]edit.Link)
+	var paths []string
+	for _, m := range matches {
+		ref := "#?"
+		if issue, ok := byTitle[issueTitle(m)]; ok {
+			ref = issue.Ref()
+		}
+		if _, ok := links[m.Path]; !ok {
+			paths = append(paths, m.Path)
+		}
+		links[m.Path] = append(links[m.Path], edit.Link{Line: m.Line, Column: m.Column, Tag: m.Tag, Ref: ref})
+	}
+	for _, path := range paths {
+		e, err := edit.PlanLinks(path, links[path])
+		if err != nil {
+			fatal(err)
+		}
+		printDiff(e, style)
+	}
+}
+
+// issueTitle returns the title of the issue created for the comment.
+// It's also used to find issues created by previous runs.
+func issueTitle(m search.JSONMatch) string {
+	return fmt.Sprintf("%s: %s", m.Tag, m.Text)
+}
+
+func issueBody(m search.JSONMatch) string {
+	body := fmt.Sprintf("Found by listme in `%s` line %d.", relPath(m.Path), m.Line)
+	if m.Author != "" {
+		body += fmt.Sprintf("\n\nAuthor: %s", m.Author)
+	}
+	return body
+}
+
+// setStatus sets the commit status of HEAD, failing if any of the failOn tags was found.
+func setStatus(t tracker.Tracker, matches []search.JSONMatch, failOn []string, path string, dryRun bool) {
+	setter, ok := t.(tracker.StatusSetter)
+	if !ok {
+		fatal(fmt.Errorf("the issue tracker doesn't support commit statuses"))
+	}
+
+	counts := make(map[string]int)
+	for _, m := range matches {
+		counts[m.Tag]++
+	}
+	status := tracker.Status{
+		State:       tracker.StatusSuccess,
+		Description: fmt.Sprintf("%d tagged comments", len(matches)),
+	}
+	var failed []string
+	for _, tag := range failOn {
+		if counts[tag] > 0 {
+			failed = append(failed, fmt.Sprintf("%d %s", counts[tag], tag))
+		}
+	}
+	if len(failed) > 0 {
+		status.State = tracker.StatusFailure
+		status.Description += ", including " + strings.Join(failed, ", ")
+	}
+
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		fatal(fmt.Errorf("failed to find the HEAD commit: %s", err))
+	}
+	sha := strings.TrimSpace(string(out))
+
+	fmt.Fprintf(os.Stderr, "status of %s: %s (%s)\n", sha[:7], status.State, status.Description)
+	if dryRun {
+		return
+	}
+	if err := setter.SetStatus(sha, status); err != nil {
+		fatal(err)
+	}
+}
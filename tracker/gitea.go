@@ -0,0 +1,200 @@
+package tracker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Gitea creates issues and commit statuses in a Gitea or Forgejo repository using the REST API.
+type Gitea struct {
+	repo    string
+	token   string
+	apiURL  string
+	labelID int64
+	client  *http.Client
+}
+
+// NewGitea returns a Gitea tracker for the repository with the format owner/repo.
+// The server address is read from GITEA_URL, e.g. https://codeberg.org, and the token
+// from GITEA_TOKEN. If repo or the server address are empty, they're taken from the
+// origin remote of the git repository containing path.
+func NewGitea(repo string, path string) (*Gitea, error) {
+	serverURL := os.Getenv("GITEA_URL")
+	if repo == "" || serverURL == "" {
+		remoteServer, remoteRepo, err := originServer(path)
+		if err != nil {
+			return nil, err
+		}
+		if repo == "" {
+			repo = remoteRepo
+		}
+		if serverURL == "" {
+			serverURL = remoteServer
+		}
+	}
+	if strings.Count(repo, "/") != 1 {
+		return nil, fmt.Errorf("invalid Gitea repository %q, expected owner/repo", repo)
+	}
+
+	token := os.Getenv("GITEA_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("GITEA_TOKEN must be set to use the Gitea API")
+	}
+	return &Gitea{
+		repo:   repo,
+		token:  token,
+		apiURL: strings.TrimSuffix(serverURL, "/") + "/api/v1",
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Repo returns the repository with the format owner/repo.
+func (g *Gitea) Repo() string {
+	return g.repo
+}
+
+// originServer returns the server address and owner/repo from the origin remote
+// of the git repository containing path. SSH remotes are assumed to be served over HTTPS.
+func originServer(path string) (server, repo string, err error) {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find the repository, use --repo and set GITEA_URL")
+	}
+	return parseRemote(strings.TrimSpace(string(out)))
+}
+
+// parseRemote splits an HTTPS or SSH remote URL into the server address and owner/repo.
+func parseRemote(remote string) (server, repo string, err error) {
+	invalid := fmt.Errorf("unsupported origin remote %q, use --repo and set GITEA_URL", remote)
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+		if u.Scheme == "http" || u.Scheme == "https" {
+			host = u.Scheme + "://" + u.Host
+		} else {
+			host = "https://" + host
+		}
+	} else if h, p, ok := strings.Cut(remote, ":"); ok {
+		// scp-like syntax: git@host:owner/repo.git
+		_, h, _ = strings.Cut(h, "@")
+		if h == "" {
+			return "", "", invalid
+		}
+		host, path = "https://"+h, "/"+p
+	} else {
+		return "", "", invalid
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimSuffix(path, ".git"), "/"), "/")
+	if len(segments) < 2 {
+		return "", "", invalid
+	}
+	n := len(segments)
+	// servers may be installed under a subpath, e.g. https://example.com/git/owner/repo
+	prefix := strings.Join(segments[:n-2], "/")
+	if prefix != "" {
+		host += "/" + prefix
+	}
+	return host, segments[n-2] + "/" + segments[n-1], nil
+}
+
+type giteaIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+func (i *giteaIssue) issue() *Issue {
+	return &Issue{Number: i.Number, Title: i.Title, Body: i.Body, URL: i.HTMLURL}
+}
+
+func (g *Gitea) Issues() ([]*Issue, error) {
+	var issues []*Issue
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/issues?labels=%s&state=all&type=issues&limit=50&page=%d", g.apiURL, g.repo, Label, page)
+		var batch []giteaIssue
+		if err := g.do(http.MethodGet, url, nil, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list issues: %s", err)
+		}
+		for _, i := range batch {
+			issues = append(issues, i.issue())
+		}
+		if len(batch) < 50 {
+			return issues, nil
+		}
+	}
+}
+
+func (g *Gitea) CreateIssue(title, body string) (*Issue, error) {
+	labelID, err := g.label()
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("%s/repos/%s/issues", g.apiURL, g.repo)
+	req := map[string]any{"title": title, "body": body, "labels": []int64{labelID}}
+	var created giteaIssue
+	if err := g.do(http.MethodPost, url, req, &created); err != nil {
+		return nil, fmt.Errorf("failed to create issue %q: %s", title, err)
+	}
+	return created.issue(), nil
+}
+
+// label returns the ID of the Label in the repository, creating it if needed.
+// Unlike GitHub, Gitea requires label IDs to create labeled issues.
+func (g *Gitea) label() (int64, error) {
+	if g.labelID != 0 {
+		return g.labelID, nil
+	}
+	type giteaLabel struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/labels?limit=50&page=%d", g.apiURL, g.repo, page)
+		var batch []giteaLabel
+		if err := g.do(http.MethodGet, url, nil, &batch); err != nil {
+			return 0, fmt.Errorf("failed to list labels: %s", err)
+		}
+		for _, l := range batch {
+			if l.Name == Label {
+				g.labelID = l.ID
+				return l.ID, nil
+			}
+		}
+		if len(batch) < 50 {
+			break
+		}
+	}
+	url := fmt.Sprintf("%s/repos/%s/labels", g.apiURL, g.repo)
+	var created giteaLabel
+	if err := g.do(http.MethodPost, url, map[string]any{"name": Label, "color": "#ededed"}, &created); err != nil {
+		return 0, fmt.Errorf("failed to create label %s: %s", Label, err)
+	}
+	g.labelID = created.ID
+	return created.ID, nil
+}
+
+func (g *Gitea) SetStatus(sha string, status Status) error {
+	url := fmt.Sprintf("%s/repos/%s/statuses/%s", g.apiURL, g.repo, sha)
+	var out map[string]any
+	if err := g.do(http.MethodPost, url, status.request(), &out); err != nil {
+		return fmt.Errorf("failed to set commit status: %s", err)
+	}
+	return nil
+}
+
+func (g *Gitea) do(method, url string, body any, out any) error {
+	header := http.Header{"Authorization": {"token " + g.token}}
+	return doJSON(g.client, method, url, header, body, out)
+}
@@ -0,0 +1,31 @@
+package tracker
+
+import "testing"
+
+func TestParseRemote(t *testing.T) {
+	cases := []struct {
+		remote string
+		server string
+		repo   string
+	}{
+		{"https://codeberg.org/owner/repo.git", "https://codeberg.org", "owner/repo"},
+		{"http://localhost:3000/owner/repo", "http://localhost:3000", "owner/repo"},
+		{"https://example.com/git/owner/repo.git", "https://example.com/git", "owner/repo"},
+		{"git@codeberg.org:owner/repo.git", "https://codeberg.org", "owner/repo"},
+		{"ssh://git@example.com:2222/owner/repo.git", "https://example.com", "owner/repo"},
+	}
+	for _, c := range cases {
+		server, repo, err := parseRemote(c.remote)
+		if err != nil {
+			t.Errorf("parseRemote(%q) failed: %s", c.remote, err)
+			continue
+		}
+		if server != c.server || repo != c.repo {
+			t.Errorf("parseRemote(%q) = %q, %q, want %q, %q", c.remote, server, repo, c.server, c.repo)
+		}
+	}
+
+	if _, _, err := parseRemote("/srv/git/repo"); err == nil {
+		t.Error("parseRemote accepted a local path")
+	}
+}
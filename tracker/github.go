@@ -0,0 +1,214 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const defaultGitHubAPI = "https://api.github.com"
+
+// remoteRegex extracts owner/repo from GitHub remote URLs, both HTTPS and SSH.
+var remoteRegex = regexp.MustCompile(`github\.com[:/]([^/]+/[^/]+?)(?:\.git)?/?$`)
+
+// GitHub creates issues in a GitHub repository using the REST API.
+type GitHub struct {
+	repo   string
+	token  string
+	apiURL string
+	client *http.Client
+}
+
+// NewGitHub returns a GitHub tracker for the repository with the format owner/repo.
+// If repo is empty, GITHUB_REPOSITORY is used and then the origin remote of the
+// git repository containing path. The token is read from GITHUB_TOKEN and the API
+// address can be overridden with GITHUB_API_URL.
+func NewGitHub(repo string, path string) (*GitHub, error) {
+	if repo == "" {
+		repo = os.Getenv("GITHUB_REPOSITORY")
+	}
+	if repo == "" {
+		var err error
+		repo, err = originRepo(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if strings.Count(repo, "/") != 1 {
+		return nil, fmt.Errorf("invalid GitHub repository %q, expected owner/repo", repo)
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN must be set to use the GitHub API")
+	}
+	apiURL := os.Getenv("GITHUB_API_URL")
+	if apiURL == "" {
+		apiURL = defaultGitHubAPI
+	}
+	return &GitHub{
+		repo:   repo,
+		token:  token,
+		apiURL: strings.TrimSuffix(apiURL, "/"),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Repo returns the repository with the format owner/repo.
+func (g *GitHub) Repo() string {
+	return g.repo
+}
+
+// originRepo returns owner/repo from the origin remote of the git repository containing path.
+func originRepo(path string) (string, error) {
+	dir := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+	out, err := exec.Command("git", "-C", dir, "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the GitHub repository, use --repo or set GITHUB_REPOSITORY")
+	}
+	match := remoteRegex.FindStringSubmatch(strings.TrimSpace(string(out)))
+	if match == nil {
+		return "", fmt.Errorf("origin remote is not a GitHub repository, use --repo or set GITHUB_REPOSITORY")
+	}
+	return match[1], nil
+}
+
+type githubIssue struct {
+	Number      int             `json:"number"`
+	Title       string          `json:"title"`
+	Body        string          `json:"body"`
+	HTMLURL     string          `json:"html_url"`
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
+}
+
+func (i *githubIssue) issue() *Issue {
+	return &Issue{Number: i.Number, Title: i.Title, Body: i.Body, URL: i.HTMLURL}
+}
+
+func (g *GitHub) Issues() ([]*Issue, error) {
+	var issues []*Issue
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("%s/repos/%s/issues?labels=%s&state=all&per_page=100&page=%d", g.apiURL, g.repo, Label, page)
+		var batch []githubIssue
+		if err := g.do(http.MethodGet, url, nil, &batch); err != nil {
+			return nil, fmt.Errorf("failed to list issues: %s", err)
+		}
+		for _, i := range batch {
+			if i.PullRequest != nil {
+				continue
+			}
+			issues = append(issues, i.issue())
+		}
+		if len(batch) < 100 {
+			return issues, nil
+		}
+	}
+}
+
+func (g *GitHub) CreateIssue(title, body string) (*Issue, error) {
+	url := fmt.Sprintf("%s/repos/%s/issues", g.apiURL, g.repo)
+	req := map[string]any{"title": title, "body": body, "labels": []string{Label}}
+	var created githubIssue
+	if err := g.do(http.MethodPost, url, req, &created); err != nil {
+		return nil, fmt.Errorf("failed to create issue %q: %s", title, err)
+	}
+	return created.issue(), nil
+}
+
+func (g *GitHub) SetStatus(sha string, status Status) error {
+	url := fmt.Sprintf("%s/repos/%s/statuses/%s", g.apiURL, g.repo, sha)
+	var out map[string]any
+	if err := g.do(http.MethodPost, url, status.request(), &out); err != nil {
+		return fmt.Errorf("failed to set commit status: %s", err)
+	}
+	return nil
+}
+
+func (g *GitHub) do(method, url string, body any, out any) error {
+	header := http.Header{
+		"Accept":        {"application/vnd.github+json"},
+		"Authorization": {"Bearer " + g.token},
+	}
+	return doJSON(g.client, method, url, header, body, out)
+}
+
+type githubComment struct {
+	ID      int64  `json:"id"`
+	Body    string `json:"body"`
+	HTMLURL string `json:"html_url"`
+}
+
+// UpsertComment keeps a single comment on the issue or pull request: the existing
+// comment containing marker is updated, or a new one is created. It returns the URL
+// of the comment.
+func (g *GitHub) UpsertComment(number int, marker, body string) (string, error) {
+	var existing *githubComment
+	for page := 1; existing == nil; page++ {
+		url := fmt.Sprintf("%s/repos/%s/issues/%d/comments?per_page=100&page=%d", g.apiURL, g.repo, number, page)
+		var batch []githubComment
+		if err := g.do(http.MethodGet, url, nil, &batch); err != nil {
+			return "", fmt.Errorf("failed to list comments of #%d: %s", number, err)
+		}
+		for i := range batch {
+			if strings.Contains(batch[i].Body, marker) {
+				existing = &batch[i]
+				break
+			}
+		}
+		if len(batch) < 100 {
+			break
+		}
+	}
+
+	req := map[string]any{"body": body}
+	var comment githubComment
+	if existing != nil {
+		url := fmt.Sprintf("%s/repos/%s/issues/comments/%d", g.apiURL, g.repo, existing.ID)
+		if err := g.do(http.MethodPatch, url, req, &comment); err != nil {
+			return "", fmt.Errorf("failed to update comment of #%d: %s", number, err)
+		}
+		return comment.HTMLURL, nil
+	}
+	url := fmt.Sprintf("%s/repos/%s/issues/%d/comments", g.apiURL, g.repo, number)
+	if err := g.do(http.MethodPost, url, req, &comment); err != nil {
+		return "", fmt.Errorf("failed to comment on #%d: %s", number, err)
+	}
+	return comment.HTMLURL, nil
+}
+
+// PullRequestEvent returns the number and the base commit of the pull request that
+// triggered the GitHub Actions run, read from the event payload at GITHUB_EVENT_PATH.
+func PullRequestEvent() (number int, base string, err error) {
+	path := os.Getenv("GITHUB_EVENT_PATH")
+	if path == "" {
+		return 0, "", fmt.Errorf("GITHUB_EVENT_PATH is not set, not running in GitHub Actions")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to read GitHub event: %s", err)
+	}
+	var event struct {
+		PullRequest *struct {
+			Number int `json:"number"`
+			Base   struct {
+				SHA string `json:"sha"`
+			} `json:"base"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return 0, "", fmt.Errorf("failed to parse GitHub event: %s", err)
+	}
+	if event.PullRequest == nil {
+		return 0, "", fmt.Errorf("the GitHub event is not a pull request")
+	}
+	return event.PullRequest.Number, event.PullRequest.Base.SHA, nil
+}
@@ -0,0 +1,96 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Label is added to every issue created by listme, so existing ones can be found again.
+const Label = "listme"
+
+// Issue is an issue in a tracker.
+//   - Number: issue number, used to reference it from the source code
+//   - URL: address of the issue page
+type Issue struct {
+	Number int
+	Title  string
+	Body   string
+	URL    string
+}
+
+// Ref returns the reference to the issue used in source comments, e.g. #123.
+func (i *Issue) Ref() string {
+	return "#" + strconv.Itoa(i.Number)
+}
+
+// Tracker is an issue tracker.
+//   - Issues: returns the issues created by listme, open or closed
+//   - CreateIssue: creates an issue with the Label
+type Tracker interface {
+	Issues() ([]*Issue, error)
+	CreateIssue(title, body string) (*Issue, error)
+}
+
+// Commit status states.
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// StatusContext identifies the commit statuses set by listme.
+const StatusContext = "listme"
+
+// Status is the status of a commit, shown next to it by the forge.
+//   - State: StatusSuccess or StatusFailure
+//   - Description: short summary, e.g. the number of tagged comments
+type Status struct {
+	State       string
+	Description string
+}
+
+func (s Status) request() map[string]any {
+	return map[string]any{"state": s.State, "description": s.Description, "context": StatusContext}
+}
+
+// StatusSetter is implemented by trackers that can set commit statuses.
+type StatusSetter interface {
+	SetStatus(sha string, status Status) error
+}
+
+// doJSON sends the request with a JSON body, if not nil, and decodes the JSON response into out.
+func doJSON(client *http.Client, method, url string, header http.Header, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
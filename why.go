@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/akamensky/argparse"
+
+	"github.com/mathpn/listme/search"
+)
+
+func runWhy(args []string) {
+	parser := argparse.NewParser("listme why", "Explain whether a file would be searched and, if not, why it's skipped. Accepts the search flags, e.g. --glob")
+	flags := addSearchFlags(parser)
+	root := parser.String("", "root", &argparse.Options{Default: ".", Help: "Path that would be searched, which must contain the explained path"})
+	parseArgs(parser, args)
+
+	opts := flags.options()
+	if opts.Path == "" {
+		fatal(fmt.Errorf("a path to explain is required"))
+	}
+	e, err := search.Explain(opts, *root)
+	if err != nil {
+		fatal(err)
+	}
+
+	path := relPath(opts.Path)
+	switch {
+	case e.Skipped && e.Lines > 0:
+		fmt.Printf("%s: partially scanned, %s\n", path, e.Reason)
+	case e.Skipped:
+		fmt.Printf("%s: skipped, %s\n", path, e.Reason)
+	case e.Reason != "":
+		fmt.Printf("%s: %s\n", path, e.Reason)
+	default:
+		fmt.Printf("%s: scanned, %d lines with %d tagged comments\n", path, e.Lines, e.Matches)
+	}
+}